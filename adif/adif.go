@@ -1,11 +1,13 @@
 package adif
 
 import (
-	"bufio"
-	"fmt"
+	"context"
+	"errors"
+	"io"
 	"log"
+	"math"
 	"os"
-	"regexp"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -14,40 +16,6 @@ import (
 	"github.com/bbathe/golog/models/qso"
 )
 
-var (
-	// regex's to extract data fields
-	reStationCallsign = regexp.MustCompile(`(?i)^station_callsign:(\d+)>(.+)`)
-	reBand            = regexp.MustCompile(`(?i)^band:(\d+)>(.+)`)
-	reCall            = regexp.MustCompile(`(?i)^call:(\d+)>(.+)`)
-	reMode            = regexp.MustCompile(`(?i)^mode:(\d+)>(.+)`)
-	reSubmode         = regexp.MustCompile(`(?i)^submode:(\d+)>(.+)`)
-	reDate            = regexp.MustCompile(`(?i)^qso_date:(\d+)>(.+)`)
-	reTimeOn          = regexp.MustCompile(`(?i)^time_on:(\d+)>(.+)`)
-	reTimeOff         = regexp.MustCompile(`(?i)^time_off:(\d+)>(.+)`)
-	reRSTRcvd         = regexp.MustCompile(`(?i)^rst_rcvd:(\d+)>(.+)`)
-	reRSTSent         = regexp.MustCompile(`(?i)^rst_sent:(\d+)>(.+)`)
-)
-
-// extractValue does the work to pull the value out of the ADIF field
-func extractValue(s string, r *regexp.Regexp) *string {
-	m := r.FindStringSubmatch(s)
-	if m != nil {
-		// get field length
-		i, err := strconv.Atoi(m[1])
-		if err != nil {
-			log.Printf("%+v", err)
-			return nil
-		}
-
-		// get field value
-		if i <= len(m[2]) {
-			ss := m[2][:i]
-			return &ss
-		}
-	}
-	return nil
-}
-
 func formatTime(ts string) (string, error) {
 	var err error
 	var t time.Time
@@ -66,95 +34,141 @@ func formatTime(ts string) (string, error) {
 		}
 	}
 
-	// we don't keep seconds
-	return t.Format("15:04"), nil
+	return t.Format("15:04:05"), nil
 }
 
+// parseFrequency returns the adif frequency (MHz) in Hz, zero if it isn't a number
+func parseFrequency(mhz string) int64 {
+	f, err := strconv.ParseFloat(mhz, 64)
+	if err != nil {
+		log.Printf("%+v", err)
+		return 0
+	}
+
+	return int64(math.Round(f * 1e6))
+}
+
+// formatFrequency returns the frequency in Hz as adif frequency (MHz)
+func formatFrequency(hz int64) string {
+	return strconv.FormatFloat(float64(hz)/1e6, 'f', -1, 64)
+}
+
+// QSOFromADIFRecord returns the qso from the adif record
 func QSOFromADIFRecord(record string) (*qso.QSO, error) {
-	var err error
+	r, err := ParseRecord(record)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return QSOFromRecord(r)
+}
+
+// fields that have their own qso column, everything else is kept in qso.Extra
+var mappedFields = map[string]bool{
+	"STATION_CALLSIGN": true,
+	"BAND":             true,
+	"CALL":             true,
+	"MODE":             true,
+	"SUBMODE":          true,
+	"QSO_DATE":         true,
+	"TIME_ON":          true,
+	"TIME_OFF":         true,
+	"RST_RCVD":         true,
+	"RST_SENT":         true,
+	"FREQ":             true,
+	"FREQ_RX":          true,
+	"TX_PWR":           true,
+}
+
+// extraField returns the adif field as a qso extra field
+func extraField(f Field) qso.ExtraField {
+	return qso.ExtraField{
+		Name:      f.Name,
+		Indicator: f.Indicator,
+		Value:     f.Value,
+	}
+}
+
+// QSOFromRecord maps the fields of the parsed adif record to a qso
+// mapped fields that fail data type validation are logged and ignored, unmapped fields are kept as-is in qso.Extra
+func QSOFromRecord(r Record) (*qso.QSO, error) {
 	var qso qso.QSO
 	var timeOn, timeOff string
 	submode := ""
 
 	// look at every field, picking out what we want
-	fields := strings.Split(record, "<")
-	for _, field := range fields {
-		m := extractValue(field, reStationCallsign)
-		if m != nil {
-			qso.StationCallsign = strings.ToUpper(*m)
-			continue
-		}
-		m = extractValue(field, reBand)
-		if m != nil {
-			qso.Band = strings.ToLower(*m)
-			continue
-		}
-		m = extractValue(field, reCall)
-		if m != nil {
-			qso.Call = strings.ToUpper(*m)
+	for _, f := range r {
+		if !mappedFields[f.Name] {
+			qso.Extra = append(qso.Extra, extraField(f))
 			continue
 		}
-		m = extractValue(field, reMode)
-		if m != nil {
-			qso.Mode = strings.ToUpper(*m)
-			continue
-		}
-		m = extractValue(field, reSubmode)
-		if m != nil {
-			submode = strings.ToUpper(*m)
+
+		err := f.Validate()
+		if err != nil {
+			log.Printf("%+v", err)
 			continue
 		}
-		m = extractValue(field, reDate)
-		if m != nil {
-			t, err := time.Parse("20060102", *m)
+
+		switch f.Name {
+		case "STATION_CALLSIGN":
+			qso.StationCallsign = strings.ToUpper(f.Value)
+		case "BAND":
+			qso.Band = strings.ToLower(f.Value)
+		case "CALL":
+			qso.Call = strings.ToUpper(f.Value)
+		case "MODE":
+			qso.Mode = strings.ToUpper(f.Value)
+		case "SUBMODE":
+			submode = strings.ToUpper(f.Value)
+		case "QSO_DATE":
+			t, err := time.Parse("20060102", f.Value)
 			if err != nil {
 				log.Printf("%+v", err)
 				continue
 			}
-
 			qso.Date = t.Format("2006-01-02")
-			continue
-		}
-		m = extractValue(field, reTimeOn)
-		if m != nil {
-			timeOn, err = formatTime(*m)
+		case "TIME_ON":
+			timeOn, err = formatTime(f.Value)
 			if err != nil {
 				log.Printf("%+v", err)
 				continue
 			}
-
-			continue
-		}
-		m = extractValue(field, reTimeOff)
-		if m != nil {
-			timeOff, err = formatTime(*m)
+		case "TIME_OFF":
+			timeOff, err = formatTime(f.Value)
 			if err != nil {
 				log.Printf("%+v", err)
 				continue
 			}
-
-			continue
-		}
-		m = extractValue(field, reRSTRcvd)
-		if m != nil {
-			qso.RSTRcvd = strings.ToUpper(*m)
-			continue
-		}
-		m = extractValue(field, reRSTSent)
-		if m != nil {
-			qso.RSTSent = strings.ToUpper(*m)
-			continue
+		case "RST_RCVD":
+			qso.RSTRcvd = strings.ToUpper(f.Value)
+		case "RST_SENT":
+			qso.RSTSent = strings.ToUpper(f.Value)
+		case "FREQ":
+			qso.Frequency = parseFrequency(f.Value)
+		case "FREQ_RX":
+			qso.FrequencyRx = parseFrequency(f.Value)
+		case "TX_PWR":
+			qso.TxPower, _ = strconv.ParseFloat(f.Value, 64)
+		default:
+			setTableField(&qso, f)
 		}
 	}
 
+	// band can come from the frequency
+	if qso.Band == "" && qso.Frequency > 0 {
+		qso.Band = config.LookupBand(int(qso.Frequency / 1000))
+	}
+
 	// fixup mode/submode
 	qso.Mode = config.LookupMode(qso.Mode, submode)
 
 	// figure out qso time
 	// jtdx will sometimes generate zero time_on
 	qso.Time = timeOn
+	qso.TimeOff = timeOff
 	if qso.Mode == "FT8" || qso.Mode == "FT4" {
-		if timeOn == "00:00" && timeOff != "" {
+		if timeOn == "00:00:00" && timeOff != "" {
 			qso.Time = timeOff
 		}
 	}
@@ -162,166 +176,373 @@ func QSOFromADIFRecord(record string) (*qso.QSO, error) {
 	return &qso, nil
 }
 
-// ReadFromFile reads QSOs from the ADIF file fname
-func ReadFromFile(fname string, qsllotw, qslqrz, qslclublog qso.QSLSent) ([]qso.QSO, error) {
+// RecordReader reads ADIF records, implemented by Reader and ADXReader
+type RecordReader interface {
+	Read() (Record, error)
+	Header() Header
+	Offset() int64
+}
+
+// RecordWriter writes ADIF records, implemented by Writer and ADXWriter
+type RecordWriter interface {
+	WriteHeader(h Header) error
+	WriteRecord(r Record) error
+	WriteQSO(q qso.QSO) error
+	Close() error
+}
+
+// IsADX returns true if fname should be read/written as ADX (ADIF XML) based on its extension
+func IsADX(fname string) bool {
+	return strings.EqualFold(filepath.Ext(fname), ".adx")
+}
+
+// NewRecordReader returns a RecordReader for the format fname is in, based on its extension
+func NewRecordReader(fname string, r io.Reader) RecordReader {
+	if IsADX(fname) {
+		return NewADXReader(r)
+	}
+	return NewReader(r)
+}
+
+// NewRecordWriter returns a RecordWriter for the format fname should be in, based on its extension
+func NewRecordWriter(fname string, w io.Writer) RecordWriter {
+	if IsADX(fname) {
+		return NewADXWriter(w)
+	}
+	return NewWriter(w)
+}
+
+// ForEachQSO reads all records from rr and calls fn with each QSO that is valid
+// records that are malformed or fail validation are logged, skipped and included in the report
+func ForEachQSO(rr RecordReader, qsllotw, qslqrz, qslclublog, qsleqsl qso.QSLSent, fn func(qso.QSO) error) (Report, error) {
+	var report Report
 	loadedAt := time.Now().Unix()
 
+	for {
+		start := rr.Offset()
+		record, err := rr.Read()
+		if err == io.EOF {
+			return report, nil
+		}
+
+		offset, line := recordPosition(rr, start)
+		if err != nil {
+			rep, ok := readProblem(err, offset, line)
+			if !ok {
+				log.Printf("%+v", err)
+				return report, err
+			}
+
+			log.Printf("%+v", err)
+			report.Total++
+			report.add(rep)
+
+			// data ended in the middle of a record, nothing more to read
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return report, nil
+			}
+			continue
+		}
+		report.Total++
+
+		q, rep := recordToQSO(record, offset, line, loadedAt)
+		report.add(rep)
+		if rep.Rejected {
+			log.Printf("%+v", record)
+			log.Printf("%+v", rep.Problems)
+			continue
+		}
+
+		// records with a sent status of their own keep it
+		defaultSent(record, "LOTW_QSL_SENT", &q.QSLLotw, &q.QSLLotwSentDate, qsllotw)
+		defaultSent(record, "QRZCOM_QSO_UPLOAD_STATUS", &q.QSLQrz, &q.QSLQrzSentDate, qslqrz)
+		defaultSent(record, "CLUBLOG_QSO_UPLOAD_STATUS", &q.QSLClublog, &q.QSLClublogSentDate, qslclublog)
+		defaultSent(record, "EQSL_QSL_SENT", &q.QSLEqsl, &q.QSLEqslSentDate, qsleqsl)
+
+		err = fn(q)
+		if err != nil {
+			log.Printf("%+v", err)
+			return report, err
+		}
+	}
+}
+
+// defaultSent sets the sent status to sent when the record doesn't have the status field name
+// a sent date without a status doesn't mean much, so it's cleared when the status is not sent
+func defaultSent(record Record, name string, status *qso.QSLSent, date *string, sent qso.QSLSent) {
+	if _, ok := record.Get(name); ok {
+		return
+	}
+
+	*status = sent
+	if sent == qso.NotSent {
+		*date = ""
+	}
+}
+
+// recordToQSO returns the qso from the record along with the report of any problems with it
+func recordToQSO(record Record, offset int64, line int, loadedAt int64) (qso.QSO, RecordReport) {
+	rep := RecordReport{
+		Offset: offset,
+		Line:   line,
+	}
+
+	rep.Problems, rep.Rejected = checkRecord(record)
+	if rep.Rejected {
+		return qso.QSO{}, rep
+	}
+
+	// get qso from record
+	q, err := QSOFromRecord(record)
+	if err != nil {
+		rep.Rejected = true
+		rep.Problems = append(rep.Problems, Problem{Kind: ProblemMalformed, Detail: err.Error()})
+		return qso.QSO{}, rep
+	}
+
+	// default some fields
+	q.LoadedAt = loadedAt
+	if q.StationCallsign == "" {
+		q.StationCallsign = config.Station.Callsign
+	}
+
+	// make sure all is good
+	err = q.Validate(false)
+	if err != nil {
+		rep.Rejected = true
+		rep.Problems = append(rep.Problems, Problem{Kind: ProblemMissingField, Detail: err.Error()})
+		return qso.QSO{}, rep
+	}
+
+	return *q, rep
+}
+
+// ReadFromFile reads QSOs and the user-defined field declarations from the ADIF (or ADX) file fname
+// along with the report of the records that were skipped
+func ReadFromFile(fname string, qsllotw, qslqrz, qslclublog, qsleqsl qso.QSLSent) ([]qso.QSO, []qso.UserDef, Report, error) {
 	file, err := os.Open(fname)
 	if err != nil {
 		log.Printf("%+v", err)
-		return nil, err
+		return nil, nil, Report{}, err
 	}
 	defer file.Close()
 
-	// we split on words, reconstruct the record and process record-by-record
-	// this is to handle multiline records, etc.
-	// for the fields we care about there are no embedded spaces so good enough for our purposes
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanWords)
+	rr := NewRecordReader(fname, file)
 
-	var record string
 	qsos := make([]qso.QSO, 0, 128)
-	for scanner.Scan() {
-		t := scanner.Text()
-		record += t
+	report, err := ForEachQSO(rr, qsllotw, qslqrz, qslclublog, qsleqsl, func(q qso.QSO) error {
+		qsos = append(qsos, q)
+		return nil
+	})
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, report, err
+	}
 
-		if strings.HasSuffix(strings.ToLower(t), "<eoh>") {
-			record = ""
-			continue
-		}
+	return qsos, rr.Header().UserDefs, report, nil
+}
 
-		if strings.HasSuffix(strings.ToLower(t), "<eor>") {
-			// get qso from record
-			var qso *qso.QSO
-			qso, err = QSOFromADIFRecord(record)
-			if err != nil {
-				log.Println(record)
-				log.Printf("%+v", err)
-				record = ""
-				continue
-			}
+// how many qsos are inserted per transaction when importing
+const importBatchSize = 1000
 
-			// override/default some fields
-			qso.LoadedAt = loadedAt
-			qso.QSLLotw = qsllotw
-			qso.QSLQrz = qslqrz
-			qso.QSLClublog = qslclublog
-			if qso.StationCallsign == "" {
-				qso.StationCallsign = config.Station.Callsign
-			}
+// Import streams the QSOs read from rr into the store s, along with the user-defined field declarations
+func Import(s qso.QSOStore, rr RecordReader, qsllotw, qslqrz, qslclublog, qsleqsl qso.QSLSent) (ImportResult, error) {
+	var result ImportResult
+	batch := make([]qso.QSO, 0, importBatchSize)
 
-			// make sure all is good
-			err = qso.Validate(false)
-			if err != nil {
-				log.Println(record)
-				log.Printf("%+v", err)
-			} else {
-				qsos = append(qsos, *qso)
-			}
+	// add the batch, anything not inserted was already in the database
+	addBatch := func() error {
+		n, err := s.BulkAdd(qso.WithSource(context.Background(), qso.SourceImport), batch)
+		if err != nil {
+			return err
+		}
 
-			record = ""
+		result.Imported += n
+		result.Duplicates += len(batch) - n
+		batch = batch[:0]
+		return nil
+	}
+
+	report, err := ForEachQSO(rr, qsllotw, qslqrz, qslclublog, qsleqsl, func(q qso.QSO) error {
+		batch = append(batch, q)
+		if len(batch) < importBatchSize {
+			return nil
 		}
+		return addBatch()
+	})
+	result.Report = report
+	if err != nil {
+		log.Printf("%+v", err)
+		return result, err
 	}
-	if err := scanner.Err(); err != nil {
+
+	if len(batch) > 0 {
+		err = addBatch()
+		if err != nil {
+			log.Printf("%+v", err)
+			return result, err
+		}
+	}
+
+	err = s.AddUserDefs(context.Background(), rr.Header().UserDefs)
+	if err != nil {
 		log.Printf("%+v", err)
-		return nil, err
+		return result, err
 	}
 
-	return qsos, nil
+	return result, nil
 }
 
-// QSOToADIFRecord returns the adif record from the qso
-func QSOToADIFRecord(qso qso.QSO) (string, error) {
+// ImportFromFile streams the QSOs from the ADIF (or ADX) file fname into the store s
+func ImportFromFile(s qso.QSOStore, fname string, qsllotw, qslqrz, qslclublog, qsleqsl qso.QSLSent) (ImportResult, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		log.Printf("%+v", err)
+		return ImportResult{}, err
+	}
+	defer file.Close()
+
+	result, err := Import(s, NewRecordReader(fname, file), qsllotw, qslqrz, qslclublog, qsleqsl)
+	if err != nil {
+		log.Printf("%+v", err)
+		return result, err
+	}
+
+	return result, nil
+}
+
+// QSOToRecord returns the adif fields for the qso
+func QSOToRecord(qso qso.QSO) (Record, error) {
 	// format date
 	t, err := time.Parse("2006-01-02", qso.Date)
 	if err != nil {
 		log.Printf("%+v", err)
-		return "", err
+		return nil, err
 	}
 	qsodate := t.Format("20060102")
 
-	// format time
-	t, err = time.Parse("15:04", qso.Time)
+	// format times
+	t, err = time.Parse("15:04:05", qso.Time)
 	if err != nil {
 		log.Printf("%+v", err)
-		return "", err
+		return nil, err
+	}
+	qsotime := t.Format("150405")
+
+	var qsotimeoff string
+	if qso.TimeOff != "" {
+		t, err = time.Parse("15:04:05", qso.TimeOff)
+		if err != nil {
+			log.Printf("%+v", err)
+			return nil, err
+		}
+		qsotimeoff = t.Format("150405")
+	}
+
+	mode, submode := config.LookupModeSubmode(qso.Band, qso.Mode)
+
+	r := Record{
+		{Name: "STATION_CALLSIGN", Value: qso.StationCallsign},
+		{Name: "CALL", Value: qso.Call},
+		{Name: "BAND", Value: qso.Band},
+		{Name: "MODE", Value: mode},
 	}
-	qsotime := t.Format("1504")
 
 	// handle optional fields
-	var rstrcvd string
-	if qso.RSTRcvd != "" {
-		rstrcvd = fmt.Sprintf("<rst_rcvd:%d>%s", len(qso.RSTRcvd), qso.RSTRcvd)
+	if submode != "" {
+		r = append(r, Field{Name: "SUBMODE", Value: submode})
+	}
+
+	r = append(r,
+		Field{Name: "QSO_DATE", Value: qsodate},
+		Field{Name: "TIME_ON", Value: qsotime},
+	)
+
+	if qsotimeoff != "" {
+		r = append(r, Field{Name: "TIME_OFF", Value: qsotimeoff})
 	}
 
-	var rstsent string
+	if qso.RSTRcvd != "" {
+		r = append(r, Field{Name: "RST_RCVD", Value: qso.RSTRcvd})
+	}
 	if qso.RSTSent != "" {
-		rstsent = fmt.Sprintf("<rst_sent:%d>%s", len(qso.RSTSent), qso.RSTSent)
-	}
-
-	var submode string
-	mode, s := config.LookupModeSubmode(qso.Band, qso.Mode)
-	if s != "" {
-		submode = fmt.Sprintf("<submode:%d>%s", len(s), s)
-	}
-
-	return fmt.Sprintf(
-		"<station_callsign:%d>%s<call:%d>%s<band:%d>%s<mode:%d>%s%s<qso_date:%d>%s<time_on:%d>%s%s%s<eor>\n",
-		len(qso.StationCallsign),
-		qso.StationCallsign,
-		len(qso.Call),
-		qso.Call,
-		len(qso.Band),
-		qso.Band,
-		len(mode),
-		mode,
-		submode,
-		len(qsodate),
-		qsodate,
-		len(qsotime),
-		qsotime,
-		rstrcvd,
-		rstsent,
-	), nil
+		r = append(r, Field{Name: "RST_SENT", Value: qso.RSTSent})
+	}
+	if qso.Frequency > 0 {
+		r = append(r, Field{Name: "FREQ", Value: formatFrequency(qso.Frequency)})
+	}
+	if qso.FrequencyRx > 0 {
+		r = append(r, Field{Name: "FREQ_RX", Value: formatFrequency(qso.FrequencyRx)})
+	}
+	if qso.TxPower > 0 {
+		r = append(r, Field{Name: "TX_PWR", Value: strconv.FormatFloat(qso.TxPower, 'f', -1, 64)})
+	}
+
+	r = append(r, tableRecordFields(qso)...)
+
+	// fields we have no column for
+	for _, f := range qso.Extra {
+		r = append(r, Field{Name: f.Name, Indicator: f.Indicator, Value: f.Value})
+	}
+
+	return r, nil
 }
 
-// WriteToFile creates an ADIF file with all qsos
-func WriteToFile(qsos []qso.QSO, fname string) error {
-	// create file
-	f, err := os.Create(fname)
+// QSOToADIFRecord returns the adif record from the qso
+func QSOToADIFRecord(qso qso.QSO) (string, error) {
+	r, err := QSOToRecord(qso)
 	if err != nil {
 		log.Printf("%+v", err)
-		return err
+		return "", err
 	}
-	defer f.Close()
 
-	w := bufio.NewWriter(f)
+	return formatRecord(r), nil
+}
 
-	// minimal header
-	_, err = w.WriteString("<adif_ver:4>1.00<eoh>\n")
+// WriteQSOs writes the header and all qsos to rw, then closes rw
+func WriteQSOs(rw RecordWriter, h Header, qsos []qso.QSO) error {
+	err := rw.WriteHeader(h)
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
 	}
 
-	// adif records
-	for _, qso := range qsos {
-		// qso to adif record
-		s, err := QSOToADIFRecord(qso)
+	for _, q := range qsos {
+		err = rw.WriteQSO(q)
 		if err != nil {
 			log.Printf("%+v", err)
 			return err
 		}
+	}
 
-		// write record
-		_, err = w.WriteString(s)
-		if err != nil {
-			log.Printf("%+v", err)
-			return err
-		}
+	err = rw.Close()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// WriteToFile creates an ADIF (or ADX) file with all qsos, opts controls the header written
+// the declarations of user-defined fields the qsos use come from the store s
+func WriteToFile(s qso.QSOStore, qsos []qso.QSO, fname string, opts WriteOptions) error {
+	// declarations for any user-defined fields the qsos use
+	userdefs, err := userDefsFor(s, qsos)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
 	}
 
-	err = w.Flush()
+	// create file
+	f, err := os.Create(fname)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer f.Close()
+
+	err = WriteQSOs(NewRecordWriter(fname, f), NewHeader(opts, userdefs), qsos)
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
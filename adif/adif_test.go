@@ -0,0 +1,149 @@
+package adif
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/models/qso"
+)
+
+func TestMain(m *testing.M) {
+	// the lookups records are checked against, normally loaded from the tqsl config
+	config.Bands = []config.Band{
+		{Band: "40m", FreqLow: 7000, FreqHigh: 7300},
+		{Band: "20m", FreqLow: 14000, FreqHigh: 14350},
+		{Band: "2m", FreqLow: 144, FreqHigh: 148},
+	}
+	config.Modes = []config.Mode{
+		{Mode: "CW"},
+		{Mode: "SSB", Submode: "USB"},
+		{Mode: "SSB", Submode: "LSB"},
+		{Mode: "MFSK", Submode: "FT4"},
+		{Mode: "FT8"},
+	}
+
+	os.Exit(m.Run())
+}
+
+const userDefADIF = `exported by another logger
+<adif_ver:5>3.1.4<userdef1:14:N>EPC,{0:999999}<userdef2:19:E>SweaterSize,{S,M,L}<eoh>
+<station_callsign:5>K0ABC<call:4>W1AW<qso_date:8>20240301<time_on:4>1234<band:3>20m<mode:2>CW<epc:4>1234<sweatersize:1>M<app_foo_bar:3>baz<eor>
+<station_callsign:5>K0ABC<call:5>VE3XX<qso_date:8>20240301<time_on:6>130000<band:3>40m<mode:3>SSB<eor>
+`
+
+func TestImportUserDefs(t *testing.T) {
+	s := qso.NewMemoryStore()
+
+	result, err := Import(s, NewReader(strings.NewReader(userDefADIF)), qso.NotSent, qso.NotSent, qso.NotSent, qso.NotSent)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if result.Imported != 2 || result.Duplicates != 0 {
+		t.Errorf("Import() = %+v, want 2 imported", result)
+	}
+
+	userdefs, err := s.UserDefs(context.Background())
+	if err != nil {
+		t.Fatalf("UserDefs() error = %v", err)
+	}
+	want := []qso.UserDef{
+		{Name: "EPC", Indicator: "N", Values: "{0:999999}"},
+		{Name: "SWEATERSIZE", Indicator: "E", Values: "{S,M,L}"},
+	}
+	if len(userdefs) != len(want) {
+		t.Fatalf("UserDefs() = %+v, want %+v", userdefs, want)
+	}
+	for i := range want {
+		u := userdefs[i]
+		if u.Name != want[i].Name || u.Indicator != want[i].Indicator || u.Values != want[i].Values {
+			t.Errorf("UserDefs()[%d] = %+v, want %+v", i, u, want[i])
+		}
+	}
+
+	// exporting only declares the user-defined fields the qsos use
+	qsos, err := s.All(context.Background())
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+	fname := filepath.Join(t.TempDir(), "export.adi")
+	err = WriteToFile(s, qsos, fname, WriteOptions{})
+	if err != nil {
+		t.Fatalf("WriteToFile() error = %v", err)
+	}
+
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"<userdef1:14:N>EPC,{0:999999}", "<userdef2:19:E>SweaterSize,{S,M,L}", "<EPC:4>1234", "<SWEATERSIZE:1>M", "<APP_FOO_BAR:3>baz"} {
+		if !strings.Contains(strings.ToUpper(string(b)), strings.ToUpper(f)) {
+			t.Errorf("export is missing %s\n%s", f, b)
+		}
+	}
+
+	qsos, userdefs, _, err = ReadFromFile(fname, qso.NotSent, qso.NotSent, qso.NotSent, qso.NotSent)
+	if err != nil {
+		t.Fatalf("ReadFromFile() error = %v", err)
+	}
+	if len(qsos) != 2 || len(userdefs) != 2 {
+		t.Errorf("ReadFromFile() = %d qsos & %d userdefs, want 2 & 2", len(qsos), len(userdefs))
+	}
+}
+
+func TestForEachQSOSentStatus(t *testing.T) {
+	const qsoFields = "<station_callsign:5>K0ABC<call:4>W1AW<qso_date:8>20240301<time_on:4>1234<band:3>20m<mode:2>CW"
+
+	tests := []struct {
+		name     string
+		fields   string
+		upload   qso.QSLSent
+		wantLotw qso.QSLSent
+		wantDate string
+	}{
+		{name: "upload", upload: qso.NotSent, wantLotw: qso.NotSent},
+		{name: "don't upload", upload: qso.Sent, wantLotw: qso.Sent},
+		{name: "record sent", fields: "<lotw_qsl_sent:1>Y<lotw_qslsdate:8>20240302", upload: qso.NotSent, wantLotw: qso.Sent, wantDate: "2024-03-02"},
+		{name: "record not sent", fields: "<lotw_qsl_sent:1>N", upload: qso.Sent, wantLotw: qso.NotSent},
+		{name: "date without status uploaded", fields: "<lotw_qslsdate:8>20240302", upload: qso.NotSent, wantLotw: qso.NotSent},
+		{name: "date without status not uploaded", fields: "<lotw_qslsdate:8>20240302", upload: qso.Sent, wantLotw: qso.Sent, wantDate: "2024-03-02"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []qso.QSO
+			rr := NewReader(strings.NewReader("<eoh>" + qsoFields + tt.fields + "<eor>"))
+			_, err := ForEachQSO(rr, tt.upload, qso.Sent, qso.Sent, qso.Sent, func(q qso.QSO) error {
+				got = append(got, q)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("ForEachQSO() error = %v", err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("ForEachQSO() read %d QSOs, want 1", len(got))
+			}
+
+			q := got[0]
+			if q.QSLLotw != tt.wantLotw || q.QSLLotwSentDate != tt.wantDate {
+				t.Errorf("LoTW sent = %v %q, want %v %q", q.QSLLotw, q.QSLLotwSentDate, tt.wantLotw, tt.wantDate)
+			}
+			if q.QSLQrz != qso.Sent || q.QSLClublog != qso.Sent || q.QSLEqsl != qso.Sent {
+				t.Errorf("other services sent = %v %v %v, want all sent", q.QSLQrz, q.QSLClublog, q.QSLEqsl)
+			}
+
+			// the status exported agrees with the date
+			r, err := QSOToRecord(q)
+			if err != nil {
+				t.Fatalf("QSOToRecord() error = %v", err)
+			}
+			status, _ := r.Get("LOTW_QSL_SENT")
+			if _, ok := r.Get("LOTW_QSLSDATE"); ok && status != "Y" {
+				t.Errorf("exported LOTW_QSL_SENT %q with a sent date", status)
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package adif
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bbathe/golog/models/qso"
+)
+
+// attrValue returns the value of the attribute name from the element, attribute names are case-insensitive
+func attrValue(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if strings.EqualFold(a.Name.Local, name) {
+			return a.Value
+		}
+	}
+
+	return ""
+}
+
+// adxUserDef returns the user-defined field declaration from the header USERDEF element
+func adxUserDef(e xml.StartElement, value string) qso.UserDef {
+	values := attrValue(e, "ENUM")
+	if values == "" {
+		values = attrValue(e, "RANGE")
+	}
+
+	return qso.UserDef{
+		Name:      strings.ToUpper(strings.TrimSpace(value)),
+		Indicator: strings.ToUpper(attrValue(e, "TYPE")),
+		Values:    values,
+	}
+}
+
+// adxField returns the field from the record element
+// APP and USERDEF elements are named the same way they would be in the ADIF tagged text format
+func adxField(e xml.StartElement, value string) Field {
+	name := strings.ToUpper(e.Name.Local)
+
+	switch name {
+	case "APP":
+		return Field{
+			Name:      "APP_" + strings.ToUpper(attrValue(e, "PROGRAMID")) + "_" + strings.ToUpper(attrValue(e, "FIELDNAME")),
+			Indicator: strings.ToUpper(attrValue(e, "TYPE")),
+			Value:     value,
+		}
+	case "USERDEF":
+		return Field{
+			Name:  strings.ToUpper(attrValue(e, "FIELDNAME")),
+			Value: value,
+		}
+	}
+
+	return Field{
+		Name:  name,
+		Value: value,
+	}
+}
+
+// ADXReader reads records from an ADX (ADIF XML) document
+type ADXReader struct {
+	d        *xml.Decoder
+	header   Header
+	inHeader bool
+
+	// the document can't be read past a syntax error
+	failed bool
+}
+
+// NewADXReader returns an ADXReader that reads from r
+func NewADXReader(r io.Reader) *ADXReader {
+	return &ADXReader{
+		d: xml.NewDecoder(r),
+	}
+}
+
+// Header returns the header, only valid after the first call to Read
+func (r *ADXReader) Header() Header {
+	return r.header
+}
+
+// Offset returns the number of bytes consumed so far
+func (r *ADXReader) Offset() int64 {
+	return r.d.InputOffset()
+}
+
+// syntaxError returns err as a *ParseError if it is an XML syntax error, nothing more is read after one
+func (r *ADXReader) syntaxError(err error) error {
+	var se *xml.SyntaxError
+	if !errors.As(err, &se) {
+		return err
+	}
+
+	r.failed = true
+	return &ParseError{Offset: r.d.InputOffset(), Line: se.Line, Err: se}
+}
+
+// Read returns the next record, returns io.EOF when there are no more records
+// a *ParseError is returned for malformed XML, the rest of the document is skipped so the next Read returns io.EOF
+func (r *ADXReader) Read() (Record, error) {
+	if r.failed {
+		return nil, io.EOF
+	}
+
+	var record Record
+	inRecord := false
+
+	for {
+		tok, err := r.d.Token()
+		if err != nil {
+			if err == io.EOF && inRecord {
+				return record, io.ErrUnexpectedEOF
+			}
+			return nil, r.syntaxError(err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToUpper(t.Name.Local)
+
+			switch {
+			case name == "HEADER":
+				r.inHeader = true
+			case name == "RECORD":
+				inRecord = true
+				record = Record{}
+			case r.inHeader || inRecord:
+				// everything inside the header or a record is a field
+				var value string
+				err = r.d.DecodeElement(&value, &t)
+				if err != nil {
+					return nil, r.syntaxError(err)
+				}
+
+				switch {
+				case r.inHeader && name == "USERDEF":
+					r.header.UserDefs = append(r.header.UserDefs, adxUserDef(t, value))
+				case r.inHeader:
+					r.header.Fields = append(r.header.Fields, adxField(t, value))
+				case value != "":
+					record = append(record, adxField(t, value))
+				}
+			}
+
+		case xml.EndElement:
+			switch strings.ToUpper(t.Name.Local) {
+			case "HEADER":
+				r.inHeader = false
+			case "RECORD":
+				return record, nil
+			}
+		}
+	}
+}
+
+// adxElement returns the element to write the field as
+func adxElement(f Field, userdefs map[string]bool) xml.StartElement {
+	name := strings.ToUpper(f.Name)
+
+	// application-defined fields are APP_{PROGRAMID}_{FIELDNAME}
+	if strings.HasPrefix(name, "APP_") {
+		programid, fieldname, ok := strings.Cut(f.Name[4:], "_")
+		if ok {
+			e := xml.StartElement{
+				Name: xml.Name{Local: "APP"},
+				Attr: []xml.Attr{
+					{Name: xml.Name{Local: "PROGRAMID"}, Value: programid},
+					{Name: xml.Name{Local: "FIELDNAME"}, Value: fieldname},
+				},
+			}
+			if f.Indicator != "" {
+				e.Attr = append(e.Attr, xml.Attr{Name: xml.Name{Local: "TYPE"}, Value: f.Indicator})
+			}
+			return e
+		}
+	}
+
+	if userdefs[name] {
+		return xml.StartElement{
+			Name: xml.Name{Local: "USERDEF"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: "FIELDNAME"}, Value: f.Name},
+			},
+		}
+	}
+
+	return xml.StartElement{Name: xml.Name{Local: name}}
+}
+
+// adxUserDefElement returns the header USERDEF element for the user-defined field declaration
+func adxUserDefElement(n int, u qso.UserDef) xml.StartElement {
+	e := xml.StartElement{
+		Name: xml.Name{Local: "USERDEF"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "FIELDID"}, Value: strconv.Itoa(n)},
+		},
+	}
+	if u.Indicator != "" {
+		e.Attr = append(e.Attr, xml.Attr{Name: xml.Name{Local: "TYPE"}, Value: u.Indicator})
+	}
+
+	// enumerations are a list of values, ranges are min:max
+	if u.Values != "" {
+		attr := "ENUM"
+		if strings.Contains(u.Values, ":") {
+			attr = "RANGE"
+		}
+		e.Attr = append(e.Attr, xml.Attr{Name: xml.Name{Local: attr}, Value: u.Values})
+	}
+
+	return e
+}
+
+// ADXWriter writes records as an ADX (ADIF XML) document
+type ADXWriter struct {
+	w        *bufio.Writer
+	enc      *xml.Encoder
+	userdefs map[string]bool
+	started  bool
+}
+
+// NewADXWriter returns an ADXWriter that writes to w
+func NewADXWriter(w io.Writer) *ADXWriter {
+	bw := bufio.NewWriter(w)
+
+	enc := xml.NewEncoder(bw)
+	enc.Indent("", "  ")
+
+	return &ADXWriter{
+		w:        bw,
+		enc:      enc,
+		userdefs: make(map[string]bool),
+	}
+}
+
+// WriteHeader starts the document and writes the header fields and user-defined field declarations
+func (w *ADXWriter) WriteHeader(h Header) error {
+	_, err := w.w.WriteString(xml.Header)
+	if err != nil {
+		return err
+	}
+
+	err = w.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "ADX"}})
+	if err != nil {
+		return err
+	}
+
+	if h.Comment != "" {
+		// comments can't contain --
+		err = w.enc.EncodeToken(xml.Comment(" " + strings.ReplaceAll(h.Comment, "--", "- -") + " "))
+		if err != nil {
+			return err
+		}
+	}
+
+	hdr := xml.StartElement{Name: xml.Name{Local: "HEADER"}}
+	err = w.enc.EncodeToken(hdr)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := h.Fields.Get("ADIF_VER"); !ok {
+		err = w.enc.EncodeElement(Version, xml.StartElement{Name: xml.Name{Local: "ADIF_VER"}})
+		if err != nil {
+			return err
+		}
+	}
+	for _, f := range h.Fields {
+		err = w.enc.EncodeElement(f.Value, xml.StartElement{Name: xml.Name{Local: strings.ToUpper(f.Name)}})
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, u := range h.UserDefs {
+		w.userdefs[strings.ToUpper(u.Name)] = true
+
+		err = w.enc.EncodeElement(u.Name, adxUserDefElement(i+1, u))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = w.enc.EncodeToken(hdr.End())
+	if err != nil {
+		return err
+	}
+
+	w.started = true
+	return w.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "RECORDS"}})
+}
+
+// WriteRecord writes the record, a minimal header is written first if WriteHeader was not called
+func (w *ADXWriter) WriteRecord(r Record) error {
+	if !w.started {
+		err := w.WriteHeader(Header{})
+		if err != nil {
+			return err
+		}
+	}
+
+	rec := xml.StartElement{Name: xml.Name{Local: "RECORD"}}
+	err := w.enc.EncodeToken(rec)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range r {
+		err = w.enc.EncodeElement(f.Value, adxElement(f, w.userdefs))
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.enc.EncodeToken(rec.End())
+}
+
+// WriteQSO writes the qso as a record
+func (w *ADXWriter) WriteQSO(q qso.QSO) error {
+	r, err := QSOToRecord(q)
+	if err != nil {
+		return err
+	}
+
+	return w.WriteRecord(r)
+}
+
+// Close ends the document and flushes, it does not close the underlying io.Writer
+func (w *ADXWriter) Close() error {
+	if !w.started {
+		err := w.WriteHeader(Header{})
+		if err != nil {
+			return err
+		}
+	}
+
+	err := w.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "RECORDS"}})
+	if err != nil {
+		return err
+	}
+
+	err = w.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "ADX"}})
+	if err != nil {
+		return err
+	}
+
+	err = w.enc.Flush()
+	if err != nil {
+		return err
+	}
+
+	return w.w.Flush()
+}
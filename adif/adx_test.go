@@ -0,0 +1,173 @@
+package adif
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bbathe/golog/models/qso"
+)
+
+func TestADXReader(t *testing.T) {
+	tests := []struct {
+		name         string
+		in           string
+		wantHeader   Header
+		want         []Record
+		wantProblems int
+	}{
+		{
+			name: "spec example",
+			in: `<?xml version="1.0" encoding="UTF-8"?>
+<ADX>
+  <HEADER>
+    <ADIF_VER>3.1.4</ADIF_VER>
+    <PROGRAMID>monolog</PROGRAMID>
+    <USERDEF FIELDID="1" TYPE="N">EPC</USERDEF>
+    <USERDEF FIELDID="2" TYPE="E" ENUM="{S,M,L}">SWEATERSIZE</USERDEF>
+    <USERDEF FIELDID="3" TYPE="N" RANGE="{5:20}">SHOESIZE</USERDEF>
+  </HEADER>
+  <RECORDS>
+    <RECORD>
+      <QSO_DATE>19900620</QSO_DATE>
+      <TIME_ON>1523</TIME_ON>
+      <CALL>VK9NS</CALL>
+      <BAND>20M</BAND>
+      <MODE>RTTY</MODE>
+      <USERDEF FIELDNAME="SWEATERSIZE">M</USERDEF>
+      <USERDEF FIELDNAME="SHOESIZE">11</USERDEF>
+      <APP PROGRAMID="MONOLOG" FIELDNAME="Compression" TYPE="s">off</APP>
+    </RECORD>
+    <RECORD>
+      <QSO_DATE>20101022</QSO_DATE>
+      <TIME_ON>0111</TIME_ON>
+      <CALL>ON4UN</CALL>
+      <BAND>40M</BAND>
+      <MODE>PSK</MODE>
+      <SUBMODE>PSK63</SUBMODE>
+      <NAME_INTL>Jürgen &amp; co</NAME_INTL>
+    </RECORD>
+  </RECORDS>
+</ADX>`,
+			wantHeader: Header{
+				Fields: Record{{Name: "ADIF_VER", Value: "3.1.4"}, {Name: "PROGRAMID", Value: "monolog"}},
+				UserDefs: []qso.UserDef{
+					{Name: "EPC", Indicator: "N"},
+					{Name: "SWEATERSIZE", Indicator: "E", Values: "{S,M,L}"},
+					{Name: "SHOESIZE", Indicator: "N", Values: "{5:20}"},
+				},
+			},
+			want: []Record{
+				{
+					{Name: "QSO_DATE", Value: "19900620"},
+					{Name: "TIME_ON", Value: "1523"},
+					{Name: "CALL", Value: "VK9NS"},
+					{Name: "BAND", Value: "20M"},
+					{Name: "MODE", Value: "RTTY"},
+					{Name: "SWEATERSIZE", Value: "M"},
+					{Name: "SHOESIZE", Value: "11"},
+					{Name: "APP_MONOLOG_COMPRESSION", Indicator: "S", Value: "off"},
+				},
+				{
+					{Name: "QSO_DATE", Value: "20101022"},
+					{Name: "TIME_ON", Value: "0111"},
+					{Name: "CALL", Value: "ON4UN"},
+					{Name: "BAND", Value: "40M"},
+					{Name: "MODE", Value: "PSK"},
+					{Name: "SUBMODE", Value: "PSK63"},
+					{Name: "NAME_INTL", Value: "Jürgen & co"},
+				},
+			},
+		},
+		{
+			name: "lower case and empty elements",
+			in:   `<adx><records><record><call>W1AW</call><name></name><comment/></record></records></adx>`,
+			want: []Record{{{Name: "CALL", Value: "W1AW"}}},
+		},
+		{
+			name:         "truncated",
+			in:           `<ADX><RECORDS><RECORD><CALL>W1AW</CALL></RECORD><RECORD><CALL>K0A`,
+			want:         []Record{{{Name: "CALL", Value: "W1AW"}}},
+			wantProblems: 1,
+		},
+		{
+			name:         "mismatched element",
+			in:           `<ADX><RECORDS><RECORD><CALL>W1AW</BAND></RECORD><RECORD><CALL>K0ABC</CALL></RECORD></RECORDS></ADX>`,
+			wantProblems: 1,
+		},
+		{
+			name:         "not xml",
+			in:           "<call:4>W1AW<eor>",
+			wantProblems: 1,
+		},
+		{name: "empty", in: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewADXReader(strings.NewReader(tt.in))
+
+			got, problems, err := readAll(r)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() = %+v\nwant %+v", got, tt.want)
+			}
+			if len(problems) != tt.wantProblems {
+				t.Errorf("Read() problems = %v, want %d", problems, tt.wantProblems)
+			}
+			if !reflect.DeepEqual(r.Header(), tt.wantHeader) {
+				t.Errorf("Header() = %+v\nwant %+v", r.Header(), tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestADXRoundTrip(t *testing.T) {
+	h := Header{
+		Comment:  "golog -- test",
+		Fields:   Record{{Name: "ADIF_VER", Value: "3.1.4"}, {Name: "PROGRAMID", Value: "golog"}},
+		UserDefs: []qso.UserDef{{Name: "SWEATERSIZE", Indicator: "E", Values: "{S,M,L}"}, {Name: "SHOESIZE", Indicator: "N", Values: "{5:20}"}},
+	}
+
+	// data type indicators are only kept for application-defined fields
+	records := append([]Record{{
+		{Name: "CALL", Value: "W1AW"},
+		{Name: "APP_GOLOG_SCORE", Indicator: "N", Value: "12"},
+		{Name: "SHOESIZE", Value: "11"},
+	}}, roundTripRecords...)
+
+	gotHeader, got := roundTrip(t, true, h, records)
+	if !reflect.DeepEqual(got, records) {
+		t.Errorf("records = %+v\nwant %+v", got, records)
+	}
+	if !reflect.DeepEqual(gotHeader.Fields, h.Fields) {
+		t.Errorf("header fields = %+v, want %+v", gotHeader.Fields, h.Fields)
+	}
+	if !reflect.DeepEqual(gotHeader.UserDefs, h.UserDefs) {
+		t.Errorf("header userdefs = %+v, want %+v", gotHeader.UserDefs, h.UserDefs)
+	}
+}
+
+func TestForEachQSOMalformedADX(t *testing.T) {
+	in := `<ADX><RECORDS>
+<RECORD><STATION_CALLSIGN>K0ABC</STATION_CALLSIGN><CALL>W1AW</CALL><QSO_DATE>20240301</QSO_DATE><TIME_ON>1234</TIME_ON><BAND>20m</BAND><MODE>CW</MODE></RECORD>
+<RECORD><STATION_CALLSIGN>K0ABC</STATION_CALLSIGN><CALL>VE3XX</CALL><QSO_DATE>2024`
+
+	n := 0
+	report, err := ForEachQSO(NewADXReader(strings.NewReader(in)), qso.Sent, qso.Sent, qso.Sent, qso.Sent, func(qso.QSO) error {
+		n++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEachQSO() error = %v", err)
+	}
+	if n != 1 || report.Total != 2 || report.Rejected != 1 {
+		t.Errorf("ForEachQSO() read %d QSOs, report %+v, want 1 QSO of 2 with 1 rejected", n, report)
+	}
+
+	if len(report.Records) != 1 || report.Records[0].Problems[0].Kind != ProblemMalformed {
+		t.Errorf("report records = %+v, want one malformed", report.Records)
+	}
+}
@@ -0,0 +1,125 @@
+package adif
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bbathe/golog/models/qso"
+)
+
+// Version is the version of the ADIF specification written by default
+const Version = "3.1.4"
+
+// ProgramID is written as the PROGRAMID header field
+const ProgramID = "golog"
+
+// ProgramVersion is written as the PROGRAMVERSION header field, set at build time with
+// -ldflags "-X github.com/bbathe/golog/adif.ProgramVersion=..."
+var ProgramVersion = "dev"
+
+// Header is the ADIF header
+type Header struct {
+	// text before the first header field, written as an XML comment in ADX
+	Comment string
+
+	Fields   Record
+	UserDefs []qso.UserDef
+}
+
+// WriteOptions controls the header written ahead of the records
+type WriteOptions struct {
+	// ADIF_VER to write, defaults to Version
+	Version string
+
+	// header comment text, defaults to a line naming the program
+	Comment string
+}
+
+// NewHeader returns a header identifying this program as the creator along with the user-defined field declarations
+func NewHeader(opts WriteOptions, userdefs []qso.UserDef) Header {
+	version := opts.Version
+	if version == "" {
+		version = Version
+	}
+
+	comment := opts.Comment
+	if comment == "" {
+		comment = "Generated by " + ProgramID + " " + ProgramVersion
+	}
+
+	return Header{
+		Comment: comment,
+		Fields: Record{
+			{Name: "ADIF_VER", Value: version},
+			{Name: "CREATED_TIMESTAMP", Value: time.Now().UTC().Format("20060102 150405")},
+			{Name: "PROGRAMID", Value: ProgramID},
+			{Name: "PROGRAMVERSION", Value: ProgramVersion},
+		},
+		UserDefs: userdefs,
+	}
+}
+
+// HeaderFromRecord splits the user-defined field declarations out of the parsed header record
+func HeaderFromRecord(r Record) Header {
+	var h Header
+
+	for _, f := range r {
+		if !strings.HasPrefix(f.Name, "USERDEF") {
+			h.Fields = append(h.Fields, f)
+			continue
+		}
+
+		// value is the field name optionally followed by an enumeration or range, ie SweaterSize,{S,M,L}
+		name, values, _ := strings.Cut(f.Value, ",")
+		h.UserDefs = append(h.UserDefs, qso.UserDef{
+			Name:      strings.ToUpper(strings.TrimSpace(name)),
+			Indicator: f.Indicator,
+			Values:    strings.TrimSpace(values),
+		})
+	}
+
+	return h
+}
+
+// userDefField returns the USERDEFn header field for the user-defined field declaration
+func userDefField(n int, u qso.UserDef) string {
+	v := u.Name
+	if u.Values != "" {
+		v += "," + u.Values
+	}
+
+	if u.Indicator != "" {
+		return fmt.Sprintf("<userdef%d:%d:%s>%s", n, len(v), u.Indicator, v)
+	}
+	return fmt.Sprintf("<userdef%d:%d>%s", n, len(v), v)
+}
+
+// userDefsFor returns the user-defined field declarations in the store s used by any of the qsos
+func userDefsFor(s qso.QSOStore, qsos []qso.QSO) ([]qso.UserDef, error) {
+	// names of all the extra fields in use
+	used := make(map[string]bool)
+	for _, q := range qsos {
+		for _, f := range q.Extra {
+			used[strings.ToUpper(f.Name)] = true
+		}
+	}
+	if len(used) == 0 {
+		return nil, nil
+	}
+
+	all, err := s.UserDefs(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	userdefs := make([]qso.UserDef, 0, len(all))
+	for _, u := range all {
+		if used[strings.ToUpper(u.Name)] {
+			userdefs = append(userdefs, u)
+		}
+	}
+
+	return userdefs, nil
+}
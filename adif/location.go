@@ -0,0 +1,45 @@
+package adif
+
+import (
+	"strconv"
+
+	"github.com/bbathe/golog/models/qso"
+)
+
+// locationFields are where the contacted station is
+var locationFields = []qsoField{
+	{
+		name: "DXCC",
+		set:  func(q *qso.QSO, v string) { q.DXCC = atoi(v) },
+		get:  func(q qso.QSO) string { return itoa(q.DXCC) },
+	},
+	{
+		name: "GRIDSQUARE",
+		set:  func(q *qso.QSO, v string) { q.GridSquare = v },
+		get:  func(q qso.QSO) string { return q.GridSquare },
+	},
+	{
+		name: "CQZ",
+		set:  func(q *qso.QSO, v string) { q.CQZone = atoi(v) },
+		get:  func(q qso.QSO) string { return itoa(q.CQZone) },
+	},
+	{
+		name: "ITUZ",
+		set:  func(q *qso.QSO, v string) { q.ITUZone = atoi(v) },
+		get:  func(q qso.QSO) string { return itoa(q.ITUZone) },
+	},
+}
+
+// atoi returns the adif integer value, zero if it isn't one
+func atoi(v string) int {
+	i, _ := strconv.Atoi(v)
+	return i
+}
+
+// itoa returns the integer as an adif value, empty for zero
+func itoa(i int) string {
+	if i == 0 {
+		return ""
+	}
+	return strconv.Itoa(i)
+}
@@ -0,0 +1,147 @@
+package adif
+
+import (
+	"github.com/bbathe/golog/models/qso"
+)
+
+// qslFields are the qsl sent & received status for each service
+var qslFields = []qsoField{
+	{
+		name: "QSL_SENT",
+		set: func(q *qso.QSO, v string) {
+			if v == "Y" {
+				q.QSLCard = qso.Sent
+			}
+		},
+		get: func(q qso.QSO) string {
+			if q.QSLCard == qso.Sent {
+				return "Y"
+			}
+			return ""
+		},
+	},
+	{
+		name: "QSLSDATE",
+		set:  func(q *qso.QSO, v string) { q.QSLCardSentDate = v },
+		get:  func(q qso.QSO) string { return q.QSLCardSentDate },
+	},
+	{
+		name: "QSL_SENT_VIA",
+		set:  func(q *qso.QSO, v string) { q.QSLCardSentVia = qso.QSLVia(v) },
+		get:  func(q qso.QSO) string { return string(q.QSLCardSentVia) },
+	},
+	{
+		name: "QSL_RCVD",
+		set:  func(q *qso.QSO, v string) { q.QSLCardRcvd = qso.QSLRcvd(v) },
+		get:  func(q qso.QSO) string { return rcvdValue(q.QSLCardRcvd) },
+	},
+	{
+		name: "QSLRDATE",
+		set:  func(q *qso.QSO, v string) { q.QSLCardRcvdDate = v },
+		get:  func(q qso.QSO) string { return q.QSLCardRcvdDate },
+	},
+	{
+		name: "QSL_RCVD_VIA",
+		set:  func(q *qso.QSO, v string) { q.QSLCardRcvdVia = qso.QSLVia(v) },
+		get:  func(q qso.QSO) string { return string(q.QSLCardRcvdVia) },
+	},
+	{
+		name: "LOTW_QSL_SENT",
+		set:  func(q *qso.QSO, v string) { q.QSLLotw = sentStatus(v) },
+		get:  func(q qso.QSO) string { return sentValue(q.QSLLotw, q.QSLLotwSentDate) },
+	},
+	{
+		name: "LOTW_QSLSDATE",
+		set:  func(q *qso.QSO, v string) { q.QSLLotwSentDate = v },
+		get:  func(q qso.QSO) string { return q.QSLLotwSentDate },
+	},
+	{
+		name: "LOTW_QSL_RCVD",
+		set:  func(q *qso.QSO, v string) { q.QSLLotwRcvd = qso.QSLRcvd(v) },
+		get:  func(q qso.QSO) string { return rcvdValue(q.QSLLotwRcvd) },
+	},
+	{
+		name: "LOTW_QSLRDATE",
+		set:  func(q *qso.QSO, v string) { q.QSLLotwRcvdDate = v },
+		get:  func(q qso.QSO) string { return q.QSLLotwRcvdDate },
+	},
+	{
+		name: "QRZCOM_QSO_UPLOAD_STATUS",
+		set:  func(q *qso.QSO, v string) { q.QSLQrz = sentStatus(v) },
+		get:  func(q qso.QSO) string { return sentValue(q.QSLQrz, q.QSLQrzSentDate) },
+	},
+	{
+		name: "QRZCOM_QSO_UPLOAD_DATE",
+		set:  func(q *qso.QSO, v string) { q.QSLQrzSentDate = v },
+		get:  func(q qso.QSO) string { return q.QSLQrzSentDate },
+	},
+	{
+		name: "QRZCOM_QSO_DOWNLOAD_STATUS",
+		set:  func(q *qso.QSO, v string) { q.QSLQrzRcvd = qso.QSLRcvd(v) },
+		get:  func(q qso.QSO) string { return rcvdValue(q.QSLQrzRcvd) },
+	},
+	{
+		name: "QRZCOM_QSO_DOWNLOAD_DATE",
+		set:  func(q *qso.QSO, v string) { q.QSLQrzRcvdDate = v },
+		get:  func(q qso.QSO) string { return q.QSLQrzRcvdDate },
+	},
+	{
+		name: "CLUBLOG_QSO_UPLOAD_STATUS",
+		set:  func(q *qso.QSO, v string) { q.QSLClublog = sentStatus(v) },
+		get:  func(q qso.QSO) string { return sentValue(q.QSLClublog, q.QSLClublogSentDate) },
+	},
+	{
+		name: "CLUBLOG_QSO_UPLOAD_DATE",
+		set:  func(q *qso.QSO, v string) { q.QSLClublogSentDate = v },
+		get:  func(q qso.QSO) string { return q.QSLClublogSentDate },
+	},
+	{
+		name: "EQSL_QSL_SENT",
+		set:  func(q *qso.QSO, v string) { q.QSLEqsl = sentStatus(v) },
+		get:  func(q qso.QSO) string { return sentValue(q.QSLEqsl, q.QSLEqslSentDate) },
+	},
+	{
+		name: "EQSL_QSLSDATE",
+		set:  func(q *qso.QSO, v string) { q.QSLEqslSentDate = v },
+		get:  func(q qso.QSO) string { return q.QSLEqslSentDate },
+	},
+	{
+		name: "EQSL_QSL_RCVD",
+		set:  func(q *qso.QSO, v string) { q.QSLEqslRcvd = qso.QSLRcvd(v) },
+		get:  func(q qso.QSO) string { return rcvdValue(q.QSLEqslRcvd) },
+	},
+	{
+		name: "EQSL_QSLRDATE",
+		set:  func(q *qso.QSO, v string) { q.QSLEqslRcvdDate = v },
+		get:  func(q qso.QSO) string { return q.QSLEqslRcvdDate },
+	},
+}
+
+// sentStatus returns the status for the adif sent value, only Y means it's been sent
+// importers that decide what gets uploaded override it
+func sentStatus(v string) qso.QSLSent {
+	if v == "Y" {
+		return qso.Sent
+	}
+	return qso.NotSent
+}
+
+// sentValue returns the adif sent status for a service we upload to
+// qsos we were told not to upload have no date, so nothing is known about them
+func sentValue(sent qso.QSLSent, date string) string {
+	if sent == qso.NotSent {
+		return "N"
+	}
+	if date != "" {
+		return "Y"
+	}
+	return ""
+}
+
+// rcvdValue returns the adif received status, not received is left out
+func rcvdValue(rcvd qso.QSLRcvd) string {
+	if rcvd == qso.NotReceived {
+		return ""
+	}
+	return string(rcvd)
+}
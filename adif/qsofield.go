@@ -0,0 +1,90 @@
+package adif
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bbathe/golog/models/qso"
+)
+
+// qsoField maps an adif field to a qso field
+type qsoField struct {
+	name string
+	set  func(q *qso.QSO, v string)
+	get  func(q qso.QSO) string
+}
+
+// fields mapped with a qsoField, in the order they are written
+var fieldTables = [][]qsoField{locationFields, qslFields}
+
+func init() {
+	// these are mapped to the qso, so they aren't kept as extra fields
+	for _, t := range fieldTables {
+		for _, f := range t {
+			mappedFields[f.name] = true
+		}
+	}
+}
+
+// setTableField sets the qso field the adif field maps to
+func setTableField(q *qso.QSO, f Field) {
+	for _, t := range fieldTables {
+		for _, qf := range t {
+			if qf.name == f.Name {
+				setQSOField(q, qf, f)
+				return
+			}
+		}
+	}
+}
+
+// setQSOField sets the qso field from the adif field, dates are stored as yyyy-mm-dd
+func setQSOField(q *qso.QSO, qf qsoField, f Field) {
+	v := strings.ToUpper(f.Value)
+	if f.Type() == TypeDate {
+		t, err := time.Parse("20060102", v)
+		if err != nil {
+			log.Printf("%+v", err)
+			return
+		}
+		v = t.Format("2006-01-02")
+	}
+
+	qf.set(q, v)
+}
+
+// tableRecordFields returns the adif fields mapped with a qsoField that have values
+func tableRecordFields(q qso.QSO) Record {
+	var r Record
+
+	for _, qf := range tableFields() {
+		v := qf.get(q)
+		if v == "" {
+			continue
+		}
+
+		f := Field{Name: qf.name, Value: v}
+		if f.Type() == TypeDate {
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				log.Printf("%+v", err)
+				continue
+			}
+			f.Value = t.Format("20060102")
+		}
+
+		r = append(r, f)
+	}
+
+	return r
+}
+
+// tableFields returns all the qsoFields in the order they are written
+func tableFields() []qsoField {
+	var fields []qsoField
+	for _, t := range fieldTables {
+		fields = append(fields, t...)
+	}
+	return fields
+}
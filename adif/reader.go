@@ -0,0 +1,270 @@
+package adif
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// longest data-specifier we'll accept before deciding the data is garbage
+const maxSpecifierLen = 1024
+
+// longest field data we'll accept, a bigger data-specifier length is treated as garbage rather than allocated
+const maxFieldLen = 1024 * 1024
+
+// the data ended after a complete field but before the <eor>
+var errMissingTerminator = fmt.Errorf("%w: record is missing <eor>", io.ErrUnexpectedEOF)
+
+// the data-specifier length isn't a number or is too long
+var errBadLength = errors.New("invalid length")
+
+// ParseError is returned when the ADIF data is malformed
+type ParseError struct {
+	Offset int64
+	Line   int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d (offset %d): %v", e.Line, e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads records from ADIF tagged text, using the data-specifier lengths to read field data
+type Reader struct {
+	r      *bufio.Reader
+	header Header
+
+	offset int64
+	line   int
+
+	recordOffset int64
+	recordLine   int
+}
+
+// NewReader returns a Reader that reads from r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		r:    bufio.NewReaderSize(r, 64*1024),
+		line: 1,
+	}
+}
+
+// Header returns the header, only valid after the first call to Read
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Offset returns the number of bytes consumed so far, after a successful Read this is just past the <eor>
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+// RecordOffset returns the byte offset and line number of the start of the record last read
+func (r *Reader) RecordOffset() (int64, int) {
+	return r.recordOffset, r.recordLine
+}
+
+// Read returns the next record
+// returns io.EOF when there are no more records and an error matching io.ErrUnexpectedEOF if the data ends in the middle of a record
+// a *ParseError is returned for a malformed record, the Reader skips to the end of that record so Read can be called again
+func (r *Reader) Read() (Record, error) {
+	for {
+		record, terminator, err := r.readFields()
+		if err != nil {
+			return record, err
+		}
+
+		if terminator == "EOH" {
+			r.header = HeaderFromRecord(record)
+			continue
+		}
+
+		return record, nil
+	}
+}
+
+func (r *Reader) readByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err != nil {
+		return b, err
+	}
+
+	r.offset++
+	if b == '\n' {
+		r.line++
+	}
+
+	return b, nil
+}
+
+// readSpecifier skips to and reads the next data-specifier, returning what is between the < and >
+func (r *Reader) readSpecifier() (string, error) {
+	// anything between data-specifiers is ignored
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			return "", err
+		}
+		if b == '<' {
+			break
+		}
+	}
+
+	spec := make([]byte, 0, 32)
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			if err == io.EOF {
+				return "", io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+		if b == '>' {
+			return string(spec), nil
+		}
+
+		spec = append(spec, b)
+		if len(spec) > maxSpecifierLen {
+			return "", fmt.Errorf("unterminated data-specifier")
+		}
+	}
+}
+
+// skipRecord discards everything up to and including the next <eor>
+func (r *Reader) skipRecord() {
+	tail := make([]byte, 0, 5)
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			return
+		}
+
+		tail = append(tail, b)
+		if len(tail) > 5 {
+			tail = tail[1:]
+		}
+		if bytes.EqualFold(tail, []byte("<eor>")) {
+			return
+		}
+	}
+}
+
+// parseSpecifier splits the data-specifier name:length[:type], length is -1 for tags like eor & eoh
+func parseSpecifier(spec string) (string, int, string, error) {
+	parts := strings.Split(spec, ":")
+
+	name := strings.ToUpper(strings.TrimSpace(parts[0]))
+	if name == "" {
+		return "", 0, "", fmt.Errorf("empty field name in data-specifier <%s>", spec)
+	}
+
+	if len(parts) == 1 {
+		return name, -1, "", nil
+	}
+	if len(parts) > 3 {
+		return "", 0, "", fmt.Errorf("malformed data-specifier <%s>", spec)
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || length < 0 || length > maxFieldLen {
+		return "", 0, "", fmt.Errorf("%w in data-specifier <%s>", errBadLength, spec)
+	}
+
+	var indicator string
+	if len(parts) == 3 {
+		indicator = strings.ToUpper(strings.TrimSpace(parts[2]))
+	}
+
+	return name, length, indicator, nil
+}
+
+// readFields reads fields up to the next <eor> or <eoh>, returning which one ended the fields
+// at the end of data the terminator is empty
+func (r *Reader) readFields() (Record, string, error) {
+	record := Record{}
+	started := false
+
+	for {
+		spec, err := r.readSpecifier()
+		if err != nil {
+			if err == io.EOF && len(record) > 0 {
+				return record, "", errMissingTerminator
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, "", err
+			}
+
+			pe := &ParseError{Offset: r.offset, Line: r.line, Err: err}
+			r.skipRecord()
+			return nil, "", pe
+		}
+
+		// remember where this record started
+		if !started {
+			r.recordOffset = r.offset - int64(len(spec)) - 2
+			r.recordLine = r.line
+			started = true
+		}
+
+		name, length, indicator, err := parseSpecifier(spec)
+		if err != nil {
+			pe := &ParseError{Offset: r.offset - int64(len(spec)) - 2, Line: r.line, Err: err}
+			r.skipRecord()
+			return nil, "", pe
+		}
+
+		if length < 0 {
+			if name == "EOR" || name == "EOH" {
+				return record, name, nil
+			}
+
+			// a field without data, nothing to keep
+			continue
+		}
+
+		// read exactly length bytes of data
+		data := make([]byte, length)
+		n, err := io.ReadFull(r.r, data)
+		r.offset += int64(n)
+		r.line += bytes.Count(data[:n], []byte("\n"))
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return record, "", io.ErrUnexpectedEOF
+			}
+			return nil, "", err
+		}
+
+		// fields with no data are the same as not being present
+		if length > 0 {
+			record = append(record, Field{
+				Name:      name,
+				Indicator: indicator,
+				Value:     string(data),
+			})
+		}
+	}
+}
+
+// ParseRecord tokenizes a single ADIF record (or header) using the data-specifier lengths
+// parsing stops at the end of s or at an <eor>/<eoh> tag
+func ParseRecord(s string) (Record, error) {
+	r := NewReader(strings.NewReader(s))
+
+	record, _, err := r.readFields()
+	if err != nil && err != io.EOF && err != errMissingTerminator {
+		return record, err
+	}
+	if record == nil {
+		record = Record{}
+	}
+
+	return record, nil
+}
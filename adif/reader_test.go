@@ -0,0 +1,263 @@
+package adif
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bbathe/golog/models/qso"
+)
+
+// readAll returns the records read from rr and the errors for the ones that couldn't be
+// stops at the first error that isn't a *ParseError
+func readAll(rr RecordReader) ([]Record, []error, error) {
+	var records []Record
+	var problems []error
+
+	for {
+		r, err := rr.Read()
+		if err == io.EOF {
+			return records, problems, nil
+		}
+
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			problems = append(problems, err)
+			continue
+		}
+		if err != nil {
+			return records, problems, err
+		}
+
+		records = append(records, r)
+	}
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name         string
+		in           string
+		wantHeader   Record
+		want         []Record
+		wantProblems int
+		wantErr      error
+	}{
+		{
+			name:       "header",
+			in:         "Exported by <some> logger\n<adif_ver:5>3.1.4 <programid:6>WSJT-X\n<eoh>\n<call:4>W1AW<eor>\n<call:5>K0ABC<eor>\n",
+			wantHeader: Record{{Name: "ADIF_VER", Value: "3.1.4"}, {Name: "PROGRAMID", Value: "WSJT-X"}},
+			want:       []Record{{{Name: "CALL", Value: "W1AW"}}, {{Name: "CALL", Value: "K0ABC"}}},
+		},
+		{
+			name: "no header",
+			in:   "<call:4>W1AW<eor><call:5>K0ABC<eor>",
+			want: []Record{{{Name: "CALL", Value: "W1AW"}}, {{Name: "CALL", Value: "K0ABC"}}},
+		},
+		{
+			name: "empty records",
+			in:   "<eoh><eor><call:4>W1AW<eor>",
+			want: []Record{{}, {{Name: "CALL", Value: "W1AW"}}},
+		},
+		{
+			name:         "bad length skips the record",
+			in:           "<eoh><call:4>W1AW<eor><call:x>BAD<band:3>20m<eor><call:5>K0ABC<eor>",
+			want:         []Record{{{Name: "CALL", Value: "W1AW"}}, {{Name: "CALL", Value: "K0ABC"}}},
+			wantProblems: 1,
+		},
+		{
+			name:         "huge length skips the record",
+			in:           "<eoh><call:4>W1AW<eor><comment:2000000>x<eor><call:5>K0ABC<eor>",
+			want:         []Record{{{Name: "CALL", Value: "W1AW"}}, {{Name: "CALL", Value: "K0ABC"}}},
+			wantProblems: 1,
+		},
+		{
+			name:         "unterminated specifier skips the record",
+			in:           "<eoh><call:4>W1AW<eor><" + strings.Repeat("x", maxSpecifierLen+1) + "<eor><call:5>K0ABC<eor>",
+			want:         []Record{{{Name: "CALL", Value: "W1AW"}}, {{Name: "CALL", Value: "K0ABC"}}},
+			wantProblems: 1,
+		},
+		{
+			name:    "missing eor",
+			in:      "<eoh><call:4>W1AW<eor><call:5>K0ABC",
+			want:    []Record{{{Name: "CALL", Value: "W1AW"}}},
+			wantErr: errMissingTerminator,
+		},
+		{
+			name:    "data ends in a field",
+			in:      "<eoh><call:4>W1AW<eor><call:5>K0A",
+			want:    []Record{{{Name: "CALL", Value: "W1AW"}}},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "data ends in a specifier",
+			in:      "<eoh><call:4>W1AW<eor><call:",
+			want:    []Record{{{Name: "CALL", Value: "W1AW"}}},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{name: "empty", in: ""},
+		{name: "no records", in: "<adif_ver:5>3.1.4<eoh>\n", wantHeader: Record{{Name: "ADIF_VER", Value: "3.1.4"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.in))
+
+			got, problems, err := readAll(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Read() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() = %+v, want %+v", got, tt.want)
+			}
+			if len(problems) != tt.wantProblems {
+				t.Errorf("Read() problems = %v, want %d", problems, tt.wantProblems)
+			}
+			if !reflect.DeepEqual(r.Header().Fields, tt.wantHeader) {
+				t.Errorf("Header() = %+v, want %+v", r.Header().Fields, tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestReaderOffsets(t *testing.T) {
+	in := "header\n<eoh>\n<call:4>W1AW<eor>\n<notes:5>a\nb\nc<eor>\n<call:x>BAD<eor>\n<call:5>K0ABC<eor>\n"
+	r := NewReader(strings.NewReader(in))
+
+	tests := []struct {
+		wantOffset int64
+		wantLine   int
+		wantErr    bool
+	}{
+		{wantOffset: int64(strings.Index(in, "<call:4>")), wantLine: 3},
+		{wantOffset: int64(strings.Index(in, "<notes")), wantLine: 4},
+		{wantOffset: int64(strings.Index(in, "<call:x>")), wantLine: 7, wantErr: true},
+		{wantOffset: int64(strings.Index(in, "<call:5>")), wantLine: 8},
+	}
+
+	for i, tt := range tests {
+		_, err := r.Read()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("record %d: Read() error = %v, wantErr %v", i, err, tt.wantErr)
+		}
+
+		offset, line := r.RecordOffset()
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			offset, line = pe.Offset, pe.Line
+		}
+		if offset != tt.wantOffset || line != tt.wantLine {
+			t.Errorf("record %d: at offset %d line %d, want offset %d line %d", i, offset, line, tt.wantOffset, tt.wantLine)
+		}
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read() error = %v, want io.EOF", err)
+	}
+	if r.Offset() != int64(len(in)) {
+		t.Errorf("Offset() = %d, want %d", r.Offset(), len(in))
+	}
+}
+
+// roundTrip writes the header and records with rw, then reads them back with the reader for the same format
+func roundTrip(t *testing.T, adx bool, h Header, records []Record) (Header, []Record) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var rw RecordWriter = NewWriter(&buf)
+	if adx {
+		rw = NewADXWriter(&buf)
+	}
+
+	err := rw.WriteHeader(h)
+	if err != nil {
+		t.Fatalf("WriteHeader() error = %v", err)
+	}
+	for _, r := range records {
+		err = rw.WriteRecord(r)
+		if err != nil {
+			t.Fatalf("WriteRecord() error = %v", err)
+		}
+	}
+	err = rw.Close()
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var rr RecordReader = NewReader(&buf)
+	if adx {
+		rr = NewADXReader(&buf)
+	}
+
+	got, problems, err := readAll(rr)
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("reading back got error %v and problems %v\n%s", err, problems, buf.String())
+	}
+
+	return rr.Header(), got
+}
+
+// roundTripRecords are written and read back by both formats
+var roundTripRecords = []Record{
+	{
+		{Name: "CALL", Value: "W1AW"},
+		{Name: "QSO_DATE", Value: "20240301"},
+		{Name: "COMMENT", Value: `tags <eor> & "quotes" stay <as-is>`},
+		{Name: "NOTES", Value: "one\r\ntwo"},
+		{Name: "NAME_INTL", Value: "Jürgen Müller"},
+		{Name: "QTH_INTL", Value: "東京"},
+		{Name: "APP_N1MM_EXCHANGE1", Value: "5A"},
+		{Name: "SWEATERSIZE", Value: "M"},
+	},
+	{
+		{Name: "CALL", Value: "K0ABC/P"},
+		{Name: "FREQ", Value: "14.074"},
+	},
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	h := Header{
+		Comment:  "golog test",
+		Fields:   Record{{Name: "ADIF_VER", Value: "3.1.4"}, {Name: "PROGRAMID", Value: "golog"}},
+		UserDefs: []qso.UserDef{{Name: "SWEATERSIZE", Indicator: "E", Values: "{S,M,L}"}, {Name: "SHOESIZE", Indicator: "N", Values: "{5:20}"}},
+	}
+
+	records := append([]Record{{
+		{Name: "CALL", Value: "W1AW"},
+		{Name: "FREQ", Indicator: "N", Value: "14.074"},
+		{Name: "APP_GOLOG_SCORE", Indicator: "N", Value: "12"},
+	}}, roundTripRecords...)
+
+	gotHeader, got := roundTrip(t, false, h, records)
+	if !reflect.DeepEqual(got, records) {
+		t.Errorf("records = %+v\nwant %+v", got, records)
+	}
+	if !reflect.DeepEqual(gotHeader.Fields, h.Fields) {
+		t.Errorf("header fields = %+v, want %+v", gotHeader.Fields, h.Fields)
+	}
+	if !reflect.DeepEqual(gotHeader.UserDefs, h.UserDefs) {
+		t.Errorf("header userdefs = %+v, want %+v", gotHeader.UserDefs, h.UserDefs)
+	}
+}
+
+func TestWriterHeader(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	// a header starting with < would be read as a record
+	err := w.WriteHeader(Header{Comment: "  "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := ProgramID + "\n<adif_ver:5>" + Version + "<eoh>\n"
+	if buf.String() != want {
+		t.Errorf("WriteHeader() wrote %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,54 @@
+package adif
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Field is a single ADIF data-specifier and its data
+type Field struct {
+	Name      string
+	Indicator string
+	Value     string
+}
+
+// Type returns the data type of the field
+func (f Field) Type() DataType {
+	return FieldType(f.Name, f.Indicator)
+}
+
+// Validate tests that the field data is valid for its data type
+func (f Field) Validate() error {
+	err := validateValue(f.Type(), f.Value)
+	if err != nil {
+		return fmt.Errorf("field %s: %w", f.Name, err)
+	}
+
+	return nil
+}
+
+// Record is an ADIF record, fields are kept in the order they were read
+type Record []Field
+
+// Get returns the value of the field name, field names are case-insensitive
+func (r Record) Get(name string) (string, bool) {
+	for _, f := range r {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// Set replaces the value of the field name or adds the field if not present
+func (r *Record) Set(name, value string) {
+	for i, f := range *r {
+		if strings.EqualFold(f.Name, name) {
+			(*r)[i].Value = value
+			return
+		}
+	}
+
+	*r = append(*r, Field{Name: strings.ToUpper(name), Value: value})
+}
@@ -0,0 +1,252 @@
+package adif
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bbathe/golog/models/qso"
+)
+
+func TestParseRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Record
+		wantErr bool
+	}{
+		{
+			name: "fields",
+			in:   "<call:4>W1AW<band:3>20m<eor>",
+			want: Record{{Name: "CALL", Value: "W1AW"}, {Name: "BAND", Value: "20m"}},
+		},
+		{
+			name: "type indicator",
+			in:   "<freq:6:n>14.074<eor>",
+			want: Record{{Name: "FREQ", Indicator: "N", Value: "14.074"}},
+		},
+		{
+			name: "case and whitespace",
+			in:   "  <CALL:4>W1AW \r\n <Band : 3 >20m\n<EOR>",
+			want: Record{{Name: "CALL", Value: "W1AW"}, {Name: "BAND", Value: "20m"}},
+		},
+		{
+			name: "data with tags",
+			in:   "<comment:12>a <eor> b<c><call:4>W1AW<eor>",
+			want: Record{{Name: "COMMENT", Value: "a <eor> b<c>"}, {Name: "CALL", Value: "W1AW"}},
+		},
+		{
+			name: "data with line breaks",
+			in:   "<notes:9>one\r\ntwo.<eor>",
+			want: Record{{Name: "NOTES", Value: "one\r\ntwo."}},
+		},
+		{
+			name: "length is bytes",
+			in:   "<name_intl:7>Jürgen<call:4>W1AW<eor>",
+			want: Record{{Name: "NAME_INTL", Value: "Jürgen"}, {Name: "CALL", Value: "W1AW"}},
+		},
+		{
+			name: "text between fields",
+			in:   "<call:4>W1AW is the call <band:3>20m<eor> trailing",
+			want: Record{{Name: "CALL", Value: "W1AW"}, {Name: "BAND", Value: "20m"}},
+		},
+		{
+			name: "empty and tag only fields",
+			in:   "<name:0><call:4>W1AW<flag><eor>",
+			want: Record{{Name: "CALL", Value: "W1AW"}},
+		},
+		{
+			name: "stops at eor",
+			in:   "<call:4>W1AW<eor><call:5>K0ABC<eor>",
+			want: Record{{Name: "CALL", Value: "W1AW"}},
+		},
+		{
+			name: "header",
+			in:   "<adif_ver:5>3.1.4<eoh>",
+			want: Record{{Name: "ADIF_VER", Value: "3.1.4"}},
+		},
+		{
+			name: "missing eor",
+			in:   "<call:4>W1AW",
+			want: Record{{Name: "CALL", Value: "W1AW"}},
+		},
+		{name: "empty", in: "", want: Record{}},
+		{name: "length not a number", in: "<call:x>W1AW<eor>", wantErr: true},
+		{name: "negative length", in: "<call:-1>W1AW<eor>", wantErr: true},
+		{name: "length too big", in: "<call:99999999999>W1AW<eor>", wantErr: true},
+		{name: "empty name", in: "<:4>W1AW<eor>", wantErr: true},
+		{name: "too many parts", in: "<call:4:S:X>W1AW<eor>", wantErr: true},
+		{name: "data shorter than length", in: "<call:10>W1AW<eor>", wantErr: true},
+		{name: "unterminated specifier", in: "<call:4", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRecord(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRecord() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRecord() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQSOFromADIFRecord(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want qso.QSO
+	}{
+		{
+			name: "wsjt-x",
+			in:   "<call:5>VE3XX <gridsquare:4>FN03 <mode:3>FT8 <rst_sent:3>-10 <rst_rcvd:3>-05 <qso_date:8>20240301 <time_on:6>130015 <qso_date_off:8>20240301 <time_off:6>130130 <band:3>20m <freq:9>14.075372 <station_callsign:5>K0ABC <my_gridsquare:6>EN34ab <tx_pwr:3>100 <eor>",
+			want: qso.QSO{
+				StationCallsign: "K0ABC",
+				Call:            "VE3XX",
+				Band:            "20m",
+				Mode:            "FT8",
+				Date:            "2024-03-01",
+				Time:            "13:00:15",
+				TimeOff:         "13:01:30",
+				RSTSent:         "-10",
+				RSTRcvd:         "-05",
+				Frequency:       14075372,
+				TxPower:         100,
+				GridSquare:      "FN03",
+				Extra: qso.ExtraFields{
+					{Name: "QSO_DATE_OFF", Value: "20240301"},
+					{Name: "MY_GRIDSQUARE", Value: "EN34ab"},
+				},
+			},
+		},
+		{
+			name: "submode",
+			in:   "<call:4>W1AW<mode:4>MFSK<submode:3>FT4<qso_date:8>20240301<time_on:4>1234<band:3>20m<eor>",
+			want: qso.QSO{Call: "W1AW", Band: "20m", Mode: "FT4", Date: "2024-03-01", Time: "12:34:00"},
+		},
+		{
+			name: "band from frequency",
+			in:   "<call:4>W1AW<mode:2>CW<qso_date:8>20240301<time_on:4>1234<freq:5>7.025<freq_rx:6>7.0255<eor>",
+			want: qso.QSO{Call: "W1AW", Band: "40m", Mode: "CW", Date: "2024-03-01", Time: "12:34:00", Frequency: 7025000, FrequencyRx: 7025500},
+		},
+		{
+			name: "zero ft8 time on",
+			in:   "<call:4>W1AW<mode:3>FT8<qso_date:8>20240301<time_on:6>000000<time_off:6>123415<band:3>20m<eor>",
+			want: qso.QSO{Call: "W1AW", Band: "20m", Mode: "FT8", Date: "2024-03-01", Time: "12:34:15", TimeOff: "12:34:15"},
+		},
+		{
+			name: "lower case values",
+			in:   "<call:4>w1aw<mode:2>cw<qso_date:8>20240301<time_on:4>1234<band:3>20M<station_callsign:5>k0abc<eor>",
+			want: qso.QSO{StationCallsign: "K0ABC", Call: "W1AW", Band: "20m", Mode: "CW", Date: "2024-03-01", Time: "12:34:00"},
+		},
+		{
+			name: "invalid mapped values are dropped",
+			in:   "<call:4>W1AW<mode:2>CW<qso_date:8>20241301<time_on:4>2561<band:3>20m<freq:4>fast<tx_pwr:3>lots<eor>",
+			want: qso.QSO{Call: "W1AW", Band: "20m", Mode: "CW"},
+		},
+		{
+			name: "unmapped fields are kept",
+			in:   "<call:4>W1AW<mode:2>CW<qso_date:8>20240301<time_on:4>1234<band:3>20m<app_n1mm_exchange1:2>5A<sweatersize:1:E>M<name:5>Hiram<eor>",
+			want: qso.QSO{
+				Call: "W1AW", Band: "20m", Mode: "CW", Date: "2024-03-01", Time: "12:34:00",
+				Extra: qso.ExtraFields{
+					{Name: "APP_N1MM_EXCHANGE1", Value: "5A"},
+					{Name: "SWEATERSIZE", Indicator: "E", Value: "M"},
+					{Name: "NAME", Value: "Hiram"},
+				},
+			},
+		},
+		{
+			name: "qsl fields",
+			in:   "<call:4>W1AW<mode:2>CW<qso_date:8>20240301<time_on:4>1234<band:3>20m<qsl_sent:1>Y<qslsdate:8>20240305<lotw_qsl_rcvd:1>Y<lotw_qslrdate:8>20240310<dxcc:3>291<cqz:1>5<ituz:1>8<eor>",
+			want: qso.QSO{
+				Call: "W1AW", Band: "20m", Mode: "CW", Date: "2024-03-01", Time: "12:34:00",
+				QSLCard: qso.Sent, QSLCardSentDate: "2024-03-05",
+				QSLLotwRcvd: qso.QSLRcvd("Y"), QSLLotwRcvdDate: "2024-03-10",
+				DXCC: 291, CQZone: 5, ITUZone: 8,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := QSOFromADIFRecord(tt.in)
+			if err != nil {
+				t.Fatalf("QSOFromADIFRecord() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("QSOFromADIFRecord() = %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	tests := []struct {
+		t     DataType
+		value string
+		ok    bool
+	}{
+		{TypeNumber, "14.074", true},
+		{TypeNumber, "-.5", true},
+		{TypeNumber, "1e6", false},
+		{TypeNumber, "", false},
+		{TypeIntegerNumber, "-12", true},
+		{TypeIntegerNumber, "1.0", false},
+		{TypePositiveInteger, "291", true},
+		{TypePositiveInteger, "0", false},
+		{TypeBoolean, "y", true},
+		{TypeBoolean, "yes", false},
+		{TypeDate, "20240229", true},
+		{TypeDate, "20230229", false},
+		{TypeDate, "19291231", false},
+		{TypeDate, "2024-03-01", false},
+		{TypeTime, "2359", true},
+		{TypeTime, "235959", true},
+		{TypeTime, "2400", false},
+		{TypeTime, "12345", false},
+		{TypeLocation, "N042 21.500", true},
+		{TypeLocation, "N42 21.5", false},
+		{TypeGridSquare, "FN31pr", true},
+		{TypeGridSquare, "FN31pr56", true},
+		{TypeGridSquare, "FN3", false},
+		{TypeGridSquare, "ZZ00", false},
+		{TypeString, "Hiram Percy Maxim", true},
+		{TypeString, "Jürgen", false},
+		{TypeString, "one\ntwo", false},
+		{TypeMultilineString, "one\r\ntwo", true},
+		{TypeIntlString, "Jürgen", true},
+	}
+
+	for _, tt := range tests {
+		err := validateValue(tt.t, tt.value)
+		if (err == nil) != tt.ok {
+			t.Errorf("validateValue(%s, %q) error = %v, want ok %v", tt.t, tt.value, err, tt.ok)
+		}
+	}
+}
+
+func TestHeaderFromRecord(t *testing.T) {
+	r, err := ParseRecord("<adif_ver:5>3.1.4<programid:6>WSJT-X<userdef1:7:N>EPC,{1}<userdef2:15:N>SHOESIZE,{5:20}<userdef3:19:E>SweaterSize,{S,M,L}<userdef4:7:S>Nothing<eoh>")
+	if err != nil {
+		t.Fatalf("ParseRecord() error = %v", err)
+	}
+
+	h := HeaderFromRecord(r)
+
+	wantFields := Record{{Name: "ADIF_VER", Value: "3.1.4"}, {Name: "PROGRAMID", Value: "WSJT-X"}}
+	if !reflect.DeepEqual(h.Fields, wantFields) {
+		t.Errorf("Fields = %+v, want %+v", h.Fields, wantFields)
+	}
+
+	wantUserDefs := []qso.UserDef{
+		{Name: "EPC", Indicator: "N", Values: "{1}"},
+		{Name: "SHOESIZE", Indicator: "N", Values: "{5:20}"},
+		{Name: "SWEATERSIZE", Indicator: "E", Values: "{S,M,L}"},
+		{Name: "NOTHING", Indicator: "S"},
+	}
+	if !reflect.DeepEqual(h.UserDefs, wantUserDefs) {
+		t.Errorf("UserDefs = %+v, want %+v", h.UserDefs, wantUserDefs)
+	}
+}
@@ -0,0 +1,216 @@
+package adif
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/models/qso"
+)
+
+// ProblemKind is the kind of problem found with a record
+type ProblemKind string
+
+const (
+	ProblemMalformed    ProblemKind = "malformed"
+	ProblemBadLength    ProblemKind = "bad length"
+	ProblemBadDate      ProblemKind = "bad date"
+	ProblemBadValue     ProblemKind = "bad value"
+	ProblemUnknownBand  ProblemKind = "unknown band"
+	ProblemUnknownMode  ProblemKind = "unknown mode"
+	ProblemMissingField ProblemKind = "missing required field"
+)
+
+// fields a record has to have to become a qso, station callsign defaults from the config
+var requiredFields = []string{"CALL", "QSO_DATE", "TIME_ON", "BAND", "MODE"}
+
+// Problem is something wrong with a record
+type Problem struct {
+	Field  string      `json:"field,omitempty"`
+	Kind   ProblemKind `json:"kind"`
+	Detail string      `json:"detail,omitempty"`
+}
+
+func (p Problem) String() string {
+	s := string(p.Kind)
+	if p.Field != "" {
+		s = p.Field + ": " + s
+	}
+	if p.Detail != "" {
+		s += ": " + p.Detail
+	}
+	return s
+}
+
+// RecordReport is the problems found with a single record
+type RecordReport struct {
+	Offset int64 `json:"offset"`
+	// zero when the format doesn't track lines
+	Line int `json:"line,omitempty"`
+	// the record can't be turned into a qso
+	Rejected bool      `json:"rejected"`
+	Problems []Problem `json:"problems"`
+}
+
+// Report is the result of validating all records read
+type Report struct {
+	Total    int `json:"total"`
+	Rejected int `json:"rejected"`
+	// only the records that have problems
+	Records []RecordReport `json:"records,omitempty"`
+}
+
+// add includes the record report if it has any problems
+func (r *Report) add(rr RecordReport) {
+	if len(rr.Problems) == 0 {
+		return
+	}
+
+	if rr.Rejected {
+		r.Rejected++
+	}
+	r.Records = append(r.Records, rr)
+}
+
+// ImportResult summarizes an import into the qso database
+type ImportResult struct {
+	Report
+	Imported   int `json:"imported"`
+	Duplicates int `json:"duplicates"`
+}
+
+func (r ImportResult) String() string {
+	return fmt.Sprintf("%d imported, %d duplicates, %d rejected", r.Imported, r.Duplicates, r.Rejected)
+}
+
+// recordOffsetter is implemented by readers that know where the last record read started
+type recordOffsetter interface {
+	RecordOffset() (int64, int)
+}
+
+// recordPosition returns where the record last read started, start is the offset before the read
+func recordPosition(rr RecordReader, start int64) (int64, int) {
+	if ro, ok := rr.(recordOffsetter); ok {
+		return ro.RecordOffset()
+	}
+	return start, 0
+}
+
+// readProblem returns the report for a record that couldn't be read
+// ok is false if the error isn't a problem with the data
+func readProblem(err error, offset int64, line int) (RecordReport, bool) {
+	var pe *ParseError
+	switch {
+	case errors.As(err, &pe):
+		offset, line = pe.Offset, pe.Line
+	case errors.Is(err, io.ErrUnexpectedEOF):
+	default:
+		return RecordReport{}, false
+	}
+
+	kind := ProblemMalformed
+	if errors.Is(err, errBadLength) || (errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, errMissingTerminator)) {
+		kind = ProblemBadLength
+	}
+
+	return RecordReport{
+		Offset:   offset,
+		Line:     line,
+		Rejected: true,
+		Problems: []Problem{{Kind: kind, Detail: err.Error()}},
+	}, true
+}
+
+// bandFromFrequency returns the band the record's FREQ is in, empty if it doesn't have one
+func bandFromFrequency(r Record) string {
+	freq, _ := r.Get("FREQ")
+	if freq == "" {
+		return ""
+	}
+
+	return config.LookupBand(int(parseFrequency(freq) / 1000))
+}
+
+// checkRecord returns the problems with the record's fields and if any of them mean it can't be imported
+func checkRecord(r Record) ([]Problem, bool) {
+	var problems []Problem
+	rejected := false
+
+	// data types, values for fields we don't map are kept as-is so are only reported
+	for _, f := range r {
+		err := validateValue(f.Type(), f.Value)
+		if err != nil {
+			kind := ProblemBadValue
+			if t := f.Type(); t == TypeDate || t == TypeTime {
+				kind = ProblemBadDate
+			}
+
+			problems = append(problems, Problem{Field: f.Name, Kind: kind, Detail: err.Error()})
+			rejected = rejected || mappedFields[f.Name]
+		}
+	}
+
+	// band & mode need to be ones we know about
+	if band, _ := r.Get("BAND"); band != "" {
+		if low, _ := config.LookupFrequencyRange(strings.ToLower(band)); low == 0 {
+			problems = append(problems, Problem{Field: "BAND", Kind: ProblemUnknownBand, Detail: band})
+			rejected = true
+		}
+	}
+	if mode, _ := r.Get("MODE"); mode != "" {
+		submode, _ := r.Get("SUBMODE")
+		m := config.LookupMode(strings.ToUpper(mode), strings.ToUpper(submode))
+		if mm, _ := config.LookupModeSubmode("", m); mm == "" {
+			problems = append(problems, Problem{Field: "MODE", Kind: ProblemUnknownMode, Detail: m})
+			rejected = true
+		}
+	}
+
+	for _, name := range requiredFields {
+		if name == "BAND" && bandFromFrequency(r) != "" {
+			continue
+		}
+		if v, _ := r.Get(name); v == "" {
+			problems = append(problems, Problem{Field: name, Kind: ProblemMissingField})
+			rejected = true
+		}
+	}
+
+	return problems, rejected
+}
+
+// Lint reads all records from rr and reports the problems found, nothing is imported
+func Lint(rr RecordReader) (Report, error) {
+	report, err := ForEachQSO(rr, qso.Sent, qso.Sent, qso.Sent, qso.Sent, func(qso.QSO) error {
+		return nil
+	})
+	if err != nil {
+		log.Printf("%+v", err)
+		return report, err
+	}
+
+	return report, nil
+}
+
+// LintFile reports the problems found in the ADIF (or ADX) file fname
+func LintFile(fname string) (Report, error) {
+	// #nosec G304
+	file, err := os.Open(fname)
+	if err != nil {
+		log.Printf("%+v", err)
+		return Report{}, err
+	}
+	defer file.Close()
+
+	report, err := Lint(NewRecordReader(fname, file))
+	if err != nil {
+		log.Printf("%+v", err)
+		return report, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,352 @@
+package adif
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DataType is an ADIF data type
+type DataType string
+
+const (
+	TypeAwardList                DataType = "AwardList"
+	TypeBoolean                  DataType = "Boolean"
+	TypeCreditList               DataType = "CreditList"
+	TypeDate                     DataType = "Date"
+	TypeEnumeration              DataType = "Enumeration"
+	TypeGridSquare               DataType = "GridSquare"
+	TypeGridSquareExt            DataType = "GridSquareExt"
+	TypeGridSquareList           DataType = "GridSquareList"
+	TypeIntegerNumber            DataType = "Integer"
+	TypeIntlMultilineString      DataType = "IntlMultilineString"
+	TypeIntlString               DataType = "IntlString"
+	TypeIOTARefNo                DataType = "IOTARefNo"
+	TypeLocation                 DataType = "Location"
+	TypeMultilineString          DataType = "MultilineString"
+	TypeNumber                   DataType = "Number"
+	TypePositiveInteger          DataType = "PositiveInteger"
+	TypePOTARefList              DataType = "POTARefList"
+	TypeSecondarySubdivisionList DataType = "SecondarySubdivisionList"
+	TypeSOTARef                  DataType = "SOTARef"
+	TypeSponsoredAwardList       DataType = "SponsoredAwardList"
+	TypeString                   DataType = "String"
+	TypeTime                     DataType = "Time"
+	TypeWWFFRef                  DataType = "WWFFRef"
+)
+
+// data type indicators that can appear in a data-specifier, ie <freq:6:N>
+var typeIndicators = map[string]DataType{
+	"A": TypeAwardList,
+	"B": TypeBoolean,
+	"D": TypeDate,
+	"E": TypeEnumeration,
+	"G": TypeIntlMultilineString,
+	"I": TypeIntlString,
+	"L": TypeLocation,
+	"M": TypeMultilineString,
+	"N": TypeNumber,
+	"S": TypeString,
+	"T": TypeTime,
+}
+
+// fields defined by the ADIF 3.1 specification and their data types
+var fieldTypes = map[string]DataType{
+	// header fields
+	"ADIF_VER":          TypeString,
+	"CREATED_TIMESTAMP": TypeString,
+	"PROGRAMID":         TypeString,
+	"PROGRAMVERSION":    TypeString,
+
+	// qso fields
+	"ADDRESS":                    TypeMultilineString,
+	"ADDRESS_INTL":               TypeIntlMultilineString,
+	"AGE":                        TypeNumber,
+	"ALTITUDE":                   TypeNumber,
+	"ANT_AZ":                     TypeNumber,
+	"ANT_EL":                     TypeNumber,
+	"ANT_PATH":                   TypeEnumeration,
+	"ARRL_SECT":                  TypeEnumeration,
+	"AWARD_GRANTED":              TypeSponsoredAwardList,
+	"AWARD_SUBMITTED":            TypeSponsoredAwardList,
+	"A_INDEX":                    TypeNumber,
+	"BAND":                       TypeEnumeration,
+	"BAND_RX":                    TypeEnumeration,
+	"CALL":                       TypeString,
+	"CHECK":                      TypeString,
+	"CLASS":                      TypeString,
+	"CLUBLOG_QSO_UPLOAD_DATE":    TypeDate,
+	"CLUBLOG_QSO_UPLOAD_STATUS":  TypeEnumeration,
+	"CNTY":                       TypeEnumeration,
+	"CNTY_ALT":                   TypeSecondarySubdivisionList,
+	"COMMENT":                    TypeString,
+	"COMMENT_INTL":               TypeIntlString,
+	"CONT":                       TypeEnumeration,
+	"CONTACTED_OP":               TypeString,
+	"CONTEST_ID":                 TypeString,
+	"COUNTRY":                    TypeString,
+	"COUNTRY_INTL":               TypeIntlString,
+	"CQZ":                        TypePositiveInteger,
+	"CREDIT_GRANTED":             TypeCreditList,
+	"CREDIT_SUBMITTED":           TypeCreditList,
+	"DARC_DOK":                   TypeEnumeration,
+	"DCL_QSLRDATE":               TypeDate,
+	"DCL_QSLSDATE":               TypeDate,
+	"DCL_QSL_RCVD":               TypeEnumeration,
+	"DCL_QSL_SENT":               TypeEnumeration,
+	"DISTANCE":                   TypeNumber,
+	"DXCC":                       TypeEnumeration,
+	"EMAIL":                      TypeString,
+	"EQ_CALL":                    TypeString,
+	"EQSL_AG":                    TypeEnumeration,
+	"EQSL_QSLRDATE":              TypeDate,
+	"EQSL_QSLSDATE":              TypeDate,
+	"EQSL_QSL_RCVD":              TypeEnumeration,
+	"EQSL_QSL_SENT":              TypeEnumeration,
+	"FISTS":                      TypePositiveInteger,
+	"FISTS_CC":                   TypePositiveInteger,
+	"FORCE_INIT":                 TypeBoolean,
+	"FREQ":                       TypeNumber,
+	"FREQ_RX":                    TypeNumber,
+	"GRIDSQUARE":                 TypeGridSquare,
+	"GRIDSQUARE_EXT":             TypeGridSquareExt,
+	"GUEST_OP":                   TypeString,
+	"HAMLOGEU_QSO_UPLOAD_DATE":   TypeDate,
+	"HAMLOGEU_QSO_UPLOAD_STATUS": TypeEnumeration,
+	"HAMQTH_QSO_UPLOAD_DATE":     TypeDate,
+	"HAMQTH_QSO_UPLOAD_STATUS":   TypeEnumeration,
+	"HRDLOG_QSO_UPLOAD_DATE":     TypeDate,
+	"HRDLOG_QSO_UPLOAD_STATUS":   TypeEnumeration,
+	"IOTA":                       TypeIOTARefNo,
+	"IOTA_ISLAND_ID":             TypePositiveInteger,
+	"ITUZ":                       TypePositiveInteger,
+	"K_INDEX":                    TypeIntegerNumber,
+	"LAT":                        TypeLocation,
+	"LON":                        TypeLocation,
+	"LOTW_QSLRDATE":              TypeDate,
+	"LOTW_QSLSDATE":              TypeDate,
+	"LOTW_QSL_RCVD":              TypeEnumeration,
+	"LOTW_QSL_SENT":              TypeEnumeration,
+	"MAX_BURSTS":                 TypeNumber,
+	"MODE":                       TypeEnumeration,
+	"MORSE_KEY_INFO":             TypeString,
+	"MORSE_KEY_TYPE":             TypeEnumeration,
+	"MS_SHOWER":                  TypeString,
+	"MY_ALTITUDE":                TypeNumber,
+	"MY_ANTENNA":                 TypeString,
+	"MY_ANTENNA_INTL":            TypeIntlString,
+	"MY_ARRL_SECT":               TypeEnumeration,
+	"MY_CITY":                    TypeString,
+	"MY_CITY_INTL":               TypeIntlString,
+	"MY_CNTY":                    TypeEnumeration,
+	"MY_CNTY_ALT":                TypeSecondarySubdivisionList,
+	"MY_COUNTRY":                 TypeString,
+	"MY_COUNTRY_INTL":            TypeIntlString,
+	"MY_CQ_ZONE":                 TypePositiveInteger,
+	"MY_DARC_DOK":                TypeEnumeration,
+	"MY_DXCC":                    TypeEnumeration,
+	"MY_FISTS":                   TypePositiveInteger,
+	"MY_GRIDSQUARE":              TypeGridSquare,
+	"MY_GRIDSQUARE_EXT":          TypeGridSquareExt,
+	"MY_IOTA":                    TypeIOTARefNo,
+	"MY_IOTA_ISLAND_ID":          TypePositiveInteger,
+	"MY_ITU_ZONE":                TypePositiveInteger,
+	"MY_LAT":                     TypeLocation,
+	"MY_LON":                     TypeLocation,
+	"MY_MORSE_KEY_INFO":          TypeString,
+	"MY_MORSE_KEY_TYPE":          TypeEnumeration,
+	"MY_NAME":                    TypeString,
+	"MY_NAME_INTL":               TypeIntlString,
+	"MY_POSTAL_CODE":             TypeString,
+	"MY_POSTAL_CODE_INTL":        TypeIntlString,
+	"MY_POTA_REF":                TypePOTARefList,
+	"MY_RIG":                     TypeString,
+	"MY_RIG_INTL":                TypeIntlString,
+	"MY_SIG":                     TypeString,
+	"MY_SIG_INTL":                TypeIntlString,
+	"MY_SIG_INFO":                TypeString,
+	"MY_SIG_INFO_INTL":           TypeIntlString,
+	"MY_SOTA_REF":                TypeSOTARef,
+	"MY_STATE":                   TypeEnumeration,
+	"MY_STREET":                  TypeString,
+	"MY_STREET_INTL":             TypeIntlString,
+	"MY_USACA_COUNTIES":          TypeSecondarySubdivisionList,
+	"MY_VUCC_GRIDS":              TypeGridSquareList,
+	"MY_WWFF_REF":                TypeWWFFRef,
+	"NAME":                       TypeString,
+	"NAME_INTL":                  TypeIntlString,
+	"NOTES":                      TypeMultilineString,
+	"NOTES_INTL":                 TypeIntlMultilineString,
+	"NR_BURSTS":                  TypeIntegerNumber,
+	"NR_PINGS":                   TypeIntegerNumber,
+	"OPERATOR":                   TypeString,
+	"OWNER_CALLSIGN":             TypeString,
+	"PFX":                        TypeString,
+	"POTA_REF":                   TypePOTARefList,
+	"PRECEDENCE":                 TypeString,
+	"PROP_MODE":                  TypeEnumeration,
+	"PUBLIC_KEY":                 TypeString,
+	"QRZCOM_QSO_DOWNLOAD_DATE":   TypeDate,
+	"QRZCOM_QSO_DOWNLOAD_STATUS": TypeEnumeration,
+	"QRZCOM_QSO_UPLOAD_DATE":     TypeDate,
+	"QRZCOM_QSO_UPLOAD_STATUS":   TypeEnumeration,
+	"QSLMSG":                     TypeMultilineString,
+	"QSLMSG_INTL":                TypeIntlMultilineString,
+	"QSLMSG_RCVD":                TypeMultilineString,
+	"QSLRDATE":                   TypeDate,
+	"QSLSDATE":                   TypeDate,
+	"QSL_RCVD":                   TypeEnumeration,
+	"QSL_RCVD_VIA":               TypeEnumeration,
+	"QSL_SENT":                   TypeEnumeration,
+	"QSL_SENT_VIA":               TypeEnumeration,
+	"QSL_VIA":                    TypeString,
+	"QSO_COMPLETE":               TypeEnumeration,
+	"QSO_DATE":                   TypeDate,
+	"QSO_DATE_OFF":               TypeDate,
+	"QSO_RANDOM":                 TypeBoolean,
+	"QTH":                        TypeString,
+	"QTH_INTL":                   TypeIntlString,
+	"REGION":                     TypeEnumeration,
+	"RIG":                        TypeMultilineString,
+	"RIG_INTL":                   TypeIntlMultilineString,
+	"RST_RCVD":                   TypeString,
+	"RST_SENT":                   TypeString,
+	"RX_PWR":                     TypeNumber,
+	"SAT_MODE":                   TypeString,
+	"SAT_NAME":                   TypeString,
+	"SFI":                        TypeIntegerNumber,
+	"SIG":                        TypeString,
+	"SIG_INTL":                   TypeIntlString,
+	"SIG_INFO":                   TypeString,
+	"SIG_INFO_INTL":              TypeIntlString,
+	"SILENT_KEY":                 TypeBoolean,
+	"SKCC":                       TypeString,
+	"SOTA_REF":                   TypeSOTARef,
+	"SRX":                        TypeIntegerNumber,
+	"SRX_STRING":                 TypeString,
+	"STATE":                      TypeEnumeration,
+	"STATION_CALLSIGN":           TypeString,
+	"STX":                        TypeIntegerNumber,
+	"STX_STRING":                 TypeString,
+	"SUBMODE":                    TypeString,
+	"SWL":                        TypeBoolean,
+	"TEN_TEN":                    TypePositiveInteger,
+	"TIME_OFF":                   TypeTime,
+	"TIME_ON":                    TypeTime,
+	"TX_PWR":                     TypeNumber,
+	"UKSMG":                      TypePositiveInteger,
+	"USACA_COUNTIES":             TypeSecondarySubdivisionList,
+	"VE_PROV":                    TypeString,
+	"VUCC_GRIDS":                 TypeGridSquareList,
+	"WEB":                        TypeString,
+	"WWFF_REF":                   TypeWWFFRef,
+}
+
+var (
+	reNumber     = regexp.MustCompile(`^-?(\d+\.?\d*|\.\d+)$`)
+	reInteger    = regexp.MustCompile(`^-?\d+$`)
+	reLocation   = regexp.MustCompile(`^[NSEWnsew]\d{3} \d{2}\.\d{3}$`)
+	reGridSquare = regexp.MustCompile(`^[A-Ra-r]{2}(\d{2}([A-Xa-x]{2}(\d{2})?)?)?$`)
+)
+
+// IsDefinedField returns true if name is a field defined by the ADIF specification
+func IsDefinedField(name string) bool {
+	_, ok := fieldTypes[strings.ToUpper(name)]
+	return ok
+}
+
+// FieldType returns the data type of the field name
+// if the field is not defined by the ADIF specification, the data type indicator is used
+func FieldType(name, indicator string) DataType {
+	if t, ok := fieldTypes[strings.ToUpper(name)]; ok {
+		return t
+	}
+	if t, ok := typeIndicators[strings.ToUpper(indicator)]; ok {
+		return t
+	}
+
+	// no type information, treat as a string
+	return TypeString
+}
+
+// validateValue tests that value is valid for the data type t
+func validateValue(t DataType, value string) error {
+	var ok bool
+
+	switch t {
+	case TypeBoolean:
+		v := strings.ToUpper(value)
+		ok = v == "Y" || v == "N"
+
+	case TypeNumber:
+		ok = reNumber.MatchString(value)
+
+	case TypeIntegerNumber:
+		ok = reInteger.MatchString(value)
+
+	case TypePositiveInteger:
+		i, err := strconv.Atoi(value)
+		ok = err == nil && i > 0
+
+	case TypeDate:
+		d, err := time.Parse("20060102", value)
+		ok = err == nil && d.Year() >= 1930
+
+	case TypeTime:
+		ok = validTime(value)
+
+	case TypeLocation:
+		ok = reLocation.MatchString(value)
+
+	case TypeGridSquare:
+		ok = reGridSquare.MatchString(value)
+
+	case TypeString:
+		ok = isASCII(value, false)
+
+	case TypeMultilineString:
+		ok = isASCII(value, true)
+
+	default:
+		// no further checks on the remaining types
+		ok = true
+	}
+
+	if !ok {
+		return fmt.Errorf("invalid %s value '%s'", t, value)
+	}
+
+	return nil
+}
+
+// validTime tests for time in HHMM or HHMMSS format
+func validTime(value string) bool {
+	var err error
+
+	switch len(value) {
+	case 4:
+		_, err = time.Parse("1504", value)
+	case 6:
+		_, err = time.Parse("150405", value)
+	default:
+		return false
+	}
+
+	return err == nil
+}
+
+// isASCII tests that all characters in s are printable ASCII, optionally allowing line breaks
+func isASCII(s string, multiline bool) bool {
+	for _, c := range s {
+		if multiline && (c == '\r' || c == '\n') {
+			continue
+		}
+		if c < 32 || c > 126 {
+			return false
+		}
+	}
+
+	return true
+}
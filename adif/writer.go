@@ -0,0 +1,94 @@
+package adif
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bbathe/golog/models/qso"
+)
+
+// formatField returns the field as an adif data-specifier followed by its data
+func formatField(f Field) string {
+	if f.Indicator != "" {
+		return fmt.Sprintf("<%s:%d:%s>%s", strings.ToLower(f.Name), len(f.Value), f.Indicator, f.Value)
+	}
+	return fmt.Sprintf("<%s:%d>%s", strings.ToLower(f.Name), len(f.Value), f.Value)
+}
+
+// formatRecord returns the record as adif tagged text, including the <eor>
+func formatRecord(r Record) string {
+	var sb strings.Builder
+
+	for _, f := range r {
+		sb.WriteString(formatField(f))
+	}
+	sb.WriteString("<eor>\n")
+
+	return sb.String()
+}
+
+// Writer writes records as ADIF tagged text
+type Writer struct {
+	w *bufio.Writer
+}
+
+// NewWriter returns a Writer that writes to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w: bufio.NewWriter(w),
+	}
+}
+
+// WriteHeader writes the header fields and user-defined field declarations
+func (w *Writer) WriteHeader(h Header) error {
+	var sb strings.Builder
+
+	// the header can't start with a <, otherwise it's read as having no header
+	comment := strings.TrimSpace(h.Comment)
+	if comment == "" {
+		comment = ProgramID
+	}
+	sb.WriteString(comment + "\n")
+
+	if _, ok := h.Fields.Get("ADIF_VER"); !ok {
+		sb.WriteString(formatField(Field{Name: "ADIF_VER", Value: Version}))
+	}
+	for _, f := range h.Fields {
+		sb.WriteString(formatField(f))
+	}
+	for i, u := range h.UserDefs {
+		sb.WriteString(userDefField(i+1, u))
+	}
+	sb.WriteString("<eoh>\n")
+
+	_, err := w.w.WriteString(sb.String())
+	return err
+}
+
+// WriteRecord writes the record
+func (w *Writer) WriteRecord(r Record) error {
+	_, err := w.w.WriteString(formatRecord(r))
+	return err
+}
+
+// WriteQSO writes the qso as a record
+func (w *Writer) WriteQSO(q qso.QSO) error {
+	r, err := QSOToRecord(q)
+	if err != nil {
+		return err
+	}
+
+	return w.WriteRecord(r)
+}
+
+// Flush writes any buffered data to the underlying io.Writer
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Close flushes the Writer, it does not close the underlying io.Writer
+func (w *Writer) Close() error {
+	return w.Flush()
+}
@@ -0,0 +1,280 @@
+package cabrillo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bbathe/golog/adif"
+	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/models/qso"
+)
+
+// Cabrillo uses band designators instead of frequencies for 50 MHz and up
+var vhfBands = map[string]string{
+	"6m":     "50",
+	"4m":     "70",
+	"2m":     "144",
+	"1.25m":  "222",
+	"70cm":   "432",
+	"33cm":   "902",
+	"23cm":   "1.2G",
+	"13cm":   "2.3G",
+	"9cm":    "3.4G",
+	"6cm":    "5.7G",
+	"3cm":    "10G",
+	"1.25cm": "24G",
+	"6mm":    "47G",
+	"4mm":    "75G",
+	"2.5mm":  "122G",
+	"2mm":    "134G",
+	"1mm":    "241G",
+	"submm":  "LIGHT",
+}
+
+// Frequency returns the Cabrillo frequency for the qso
+// HF qsos are in kHz, the low edge of the band when the qso has no frequency, VHF and up are band designators
+func Frequency(q qso.QSO) (string, error) {
+	if f, ok := vhfBands[strings.ToLower(q.Band)]; ok {
+		return f, nil
+	}
+	if q.Frequency > 0 {
+		return strconv.FormatInt(q.Frequency/1000, 10), nil
+	}
+
+	low, _ := config.LookupFrequencyRange(q.Band)
+	if low == 0 {
+		err := fmt.Errorf("no frequency for band %s", q.Band)
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	return strconv.Itoa(low), nil
+}
+
+// Mode returns the Cabrillo mode for the qso, one of CW, PH, FM, RY or DG
+func Mode(q qso.QSO) string {
+	mode, _ := config.LookupModeSubmode(q.Band, q.Mode)
+	if mode == "" {
+		mode = q.Mode
+	}
+
+	switch mode {
+	case "CW":
+		return "CW"
+	case "SSB", "AM", "DIGITALVOICE":
+		return "PH"
+	case "FM":
+		return "FM"
+	case "RTTY":
+		return "RY"
+	}
+
+	return "DG"
+}
+
+// exchangeValues returns the exchange values from the qso record, padded to their column widths
+func exchangeValues(r adif.Record, exchange []Exchange) ([]string, error) {
+	values := make([]string, 0, len(exchange))
+
+	for _, e := range exchange {
+		var v string
+		if e.Field != "" {
+			v, _ = r.Get(e.Field)
+		}
+		if v == "" {
+			v = e.Value
+		}
+		if v == "" {
+			err := fmt.Errorf("missing exchange field %s", e.Field)
+			log.Printf("%+v", err)
+			return nil, err
+		}
+
+		// values are separated by whitespace so can't contain any
+		v = strings.Join(strings.Fields(strings.ToUpper(v)), "")
+		values = append(values, fmt.Sprintf("%-*s", e.Width, v))
+	}
+
+	return values, nil
+}
+
+// qsoLine returns the QSO: line for the qso
+func qsoLine(c Contest, q qso.QSO) (string, error) {
+	freq, err := Frequency(q)
+	if err != nil {
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	r, err := adif.QSOToRecord(q)
+	if err != nil {
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	// cabrillo wants the date as yyyy-mm-dd and time as hhmm
+	qsodate, _ := r.Get("QSO_DATE")
+	qsotime, _ := r.Get("TIME_ON")
+	t, err := time.Parse("20060102 1504", qsodate+" "+qsotime[:4])
+	if err != nil {
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	sent, err := exchangeValues(r, c.ExchangeSent)
+	if err != nil {
+		err = fmt.Errorf("qso with %s on %s %s: %w", q.Call, q.Date, q.Time, err)
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	rcvd, err := exchangeValues(r, c.ExchangeRcvd)
+	if err != nil {
+		err = fmt.Errorf("qso with %s on %s %s: %w", q.Call, q.Date, q.Time, err)
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	line := fmt.Sprintf("QSO: %5s %s %s %-13s %s %-13s %s",
+		freq,
+		Mode(q),
+		t.Format("2006-01-02 1504"),
+		strings.ToUpper(q.StationCallsign),
+		strings.Join(sent, " "),
+		strings.ToUpper(q.Call),
+		strings.Join(rcvd, " "),
+	)
+
+	return strings.TrimRight(line, " "), nil
+}
+
+// headerLines returns the tags ahead of the QSO: lines, empty tags are left out
+func headerLines(c Contest, qsos []qso.QSO) []string {
+	callsign := c.Callsign
+	if callsign == "" && len(qsos) > 0 {
+		callsign = qsos[0].StationCallsign
+	}
+
+	var claimedScore string
+	if c.ClaimedScore > 0 {
+		claimedScore = strconv.Itoa(c.ClaimedScore)
+	}
+
+	tags := [][2]string{
+		{"CONTEST", c.Contest},
+		{"CALLSIGN", strings.ToUpper(callsign)},
+		{"LOCATION", c.Location},
+		{"CATEGORY-ASSISTED", c.CategoryAssisted},
+		{"CATEGORY-BAND", c.CategoryBand},
+		{"CATEGORY-MODE", c.CategoryMode},
+		{"CATEGORY-OPERATOR", c.CategoryOperator},
+		{"CATEGORY-POWER", c.CategoryPower},
+		{"CATEGORY-STATION", c.CategoryStation},
+		{"CATEGORY-TIME", c.CategoryTime},
+		{"CATEGORY-TRANSMITTER", c.CategoryTransmitter},
+		{"CATEGORY-OVERLAY", c.CategoryOverlay},
+		{"CLAIMED-SCORE", claimedScore},
+		{"CLUB", c.Club},
+		{"CREATED-BY", adif.ProgramID + " " + adif.ProgramVersion},
+		{"NAME", c.Name},
+		{"EMAIL", c.Email},
+		{"GRID-LOCATOR", c.GridLocator},
+		{"OPERATORS", c.Operators},
+	}
+
+	lines := []string{"START-OF-LOG: 3.0"}
+	for _, t := range tags {
+		if t[1] != "" {
+			lines = append(lines, t[0]+": "+t[1])
+		}
+	}
+
+	// tags that can be repeated
+	for _, a := range c.Address {
+		lines = append(lines, "ADDRESS: "+a)
+	}
+	for _, s := range c.Soapbox {
+		lines = append(lines, "SOAPBOX: "+s)
+	}
+
+	return lines
+}
+
+// Write writes the Cabrillo log of qsos for contest c to w
+func Write(w io.Writer, c Contest, qsos []qso.QSO) error {
+	err := c.Validate()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	for _, l := range headerLines(c, qsos) {
+		_, err = bw.WriteString(l + "\r\n")
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	for _, q := range qsos {
+		l, err := qsoLine(c, q)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+
+		_, err = bw.WriteString(l + "\r\n")
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	_, err = bw.WriteString("END-OF-LOG:\r\n")
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// WriteToFile creates a Cabrillo file with the qsos in the store s made from from thru to for contest c
+func WriteToFile(s qso.QSOStore, c Contest, from, to time.Time, fname string) error {
+	qsos, err := s.Between(context.Background(), from, to)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	// create file
+	f, err := os.Create(fname)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer f.Close()
+
+	err = Write(f, c, qsos)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
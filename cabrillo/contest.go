@@ -0,0 +1,98 @@
+package cabrillo
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Exchange is one element of the sent or received exchange
+type Exchange struct {
+	// ADIF field the value comes from, ie RST_SENT, STX, SRX_STRING, STATE
+	Field string `yaml:"field"`
+
+	// value used when Field is empty or the qso doesn't have it, ie a zone or state that never changes
+	Value string `yaml:"value"`
+
+	// minimum column width, values are left-aligned
+	Width int `yaml:"width"`
+}
+
+// Contest describes the contest being submitted and the layout of the QSO: lines
+type Contest struct {
+	Contest  string `yaml:"contest"`
+	Callsign string `yaml:"callsign"`
+	Location string `yaml:"location"`
+
+	CategoryAssisted    string `yaml:"category-assisted"`
+	CategoryBand        string `yaml:"category-band"`
+	CategoryMode        string `yaml:"category-mode"`
+	CategoryOperator    string `yaml:"category-operator"`
+	CategoryPower       string `yaml:"category-power"`
+	CategoryStation     string `yaml:"category-station"`
+	CategoryTime        string `yaml:"category-time"`
+	CategoryTransmitter string `yaml:"category-transmitter"`
+	CategoryOverlay     string `yaml:"category-overlay"`
+
+	ClaimedScore int      `yaml:"claimed-score"`
+	Club         string   `yaml:"club"`
+	Name         string   `yaml:"name"`
+	Email        string   `yaml:"email"`
+	GridLocator  string   `yaml:"grid-locator"`
+	Operators    string   `yaml:"operators"`
+	Address      []string `yaml:"address"`
+	Soapbox      []string `yaml:"soapbox"`
+
+	ExchangeSent []Exchange `yaml:"exchange-sent"`
+	ExchangeRcvd []Exchange `yaml:"exchange-rcvd"`
+}
+
+// Validate tests the required contest fields
+func (c Contest) Validate() error {
+	missingField := "required field missing %s"
+
+	if c.Contest == "" {
+		err := fmt.Errorf(missingField, "Contest")
+		log.Printf("%+v", err)
+		return err
+	}
+	if len(c.ExchangeSent) == 0 {
+		err := fmt.Errorf(missingField, "ExchangeSent")
+		log.Printf("%+v", err)
+		return err
+	}
+	if len(c.ExchangeRcvd) == 0 {
+		err := fmt.Errorf(missingField, "ExchangeRcvd")
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// ReadContestFromFile reads a contest definition from the YAML file fname
+func ReadContestFromFile(fname string) (Contest, error) {
+	// #nosec G304
+	bs, err := os.ReadFile(fname)
+	if err != nil {
+		log.Printf("%+v", err)
+		return Contest{}, err
+	}
+
+	var c Contest
+	err = yaml.Unmarshal(bs, &c)
+	if err != nil {
+		log.Printf("%+v", err)
+		return Contest{}, err
+	}
+
+	err = c.Validate()
+	if err != nil {
+		log.Printf("%+v", err)
+		return Contest{}, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,326 @@
+package cabrillo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bbathe/golog/adif"
+	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/models/qso"
+)
+
+// adif mode for each cabrillo mode, DG is ambiguous so it is up to the caller
+var cabrilloModes = map[string]string{
+	"CW": "CW",
+	"PH": "SSB",
+	"FM": "FM",
+	"RY": "RTTY",
+}
+
+// looks like a signal report, 59 or 599
+var reRST = regexp.MustCompile(`^[1-5][1-9][1-9]?$`)
+
+// LineError is a QSO: line that couldn't be turned into a qso
+type LineError struct {
+	Line int
+	Text string
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads qsos from a Cabrillo log
+type Reader struct {
+	s    *bufio.Scanner
+	line int
+
+	// exchange layout, without it the exchange is split in half with a leading signal report kept as the RST
+	contest *Contest
+
+	// mode used for DG qsos
+	digitalMode string
+
+	// CONTEST tag from the header
+	contestID string
+}
+
+// NewReader returns a Reader that reads from r
+// c is optional and when present its exchange layout is used to map the exchange to adif fields
+// digitalMode is the mode given to DG qsos, since Cabrillo doesn't say which digital mode was used
+func NewReader(r io.Reader, c *Contest, digitalMode string) *Reader {
+	return &Reader{
+		s:           bufio.NewScanner(r),
+		contest:     c,
+		digitalMode: strings.ToUpper(digitalMode),
+	}
+}
+
+// Read returns the next qso, returns io.EOF when there are no more
+// a *LineError is returned for a QSO: line that can't be parsed, Read can be called again to continue with the next line
+func (r *Reader) Read() (qso.QSO, error) {
+	for r.s.Scan() {
+		r.line++
+		text := strings.TrimSpace(r.s.Text())
+
+		tag, value, ok := strings.Cut(text, ":")
+		if !ok {
+			continue
+		}
+		tag = strings.ToUpper(strings.TrimSpace(tag))
+		value = strings.TrimSpace(value)
+
+		switch tag {
+		case "CONTEST":
+			r.contestID = value
+		case "QSO":
+			q, err := r.parseQSO(value)
+			if err != nil {
+				return qso.QSO{}, &LineError{Line: r.line, Text: text, Err: err}
+			}
+			return q, nil
+		}
+	}
+
+	err := r.s.Err()
+	if err != nil {
+		return qso.QSO{}, err
+	}
+
+	return qso.QSO{}, io.EOF
+}
+
+// band returns the adif band and frequency (MHz) from the cabrillo frequency, frequency is empty for band designators
+func band(freq string) (string, string, error) {
+	for b, f := range vhfBands {
+		if strings.EqualFold(f, freq) {
+			return b, "", nil
+		}
+	}
+
+	khz, err := strconv.Atoi(freq)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid frequency %s", freq)
+	}
+
+	b := config.LookupBand(khz)
+	if b == "" {
+		return "", "", fmt.Errorf("no band for frequency %s", freq)
+	}
+
+	return b, strconv.FormatFloat(float64(khz)/1000, 'f', 3, 64), nil
+}
+
+// mode returns the adif mode from the cabrillo mode
+func (r *Reader) mode(mo string) (string, error) {
+	mo = strings.ToUpper(mo)
+
+	if m, ok := cabrilloModes[mo]; ok {
+		return m, nil
+	}
+	if mo == "DG" && r.digitalMode != "" {
+		return r.digitalMode, nil
+	}
+
+	return "", fmt.Errorf("unsupported mode %s", mo)
+}
+
+// splitExchange splits what follows the time into the sent call & exchange and received call & exchange
+func (r *Reader) splitExchange(fields []string) ([]string, []string, error) {
+	var nsent int
+
+	if r.contest != nil {
+		nsent = 1 + len(r.contest.ExchangeSent)
+		if len(fields) < nsent+1+len(r.contest.ExchangeRcvd) {
+			return nil, nil, fmt.Errorf("expected %d sent and %d received exchange values", len(r.contest.ExchangeSent), len(r.contest.ExchangeRcvd))
+		}
+	} else {
+		// a trailing transmitter id makes the count odd, otherwise both sides are the same size
+		n := len(fields) - len(fields)%2
+		nsent = n / 2
+		if nsent < 1 {
+			return nil, nil, fmt.Errorf("missing callsigns")
+		}
+		fields = fields[:n]
+	}
+
+	return fields[:nsent], fields[nsent:], nil
+}
+
+// exchangeFields returns the adif fields for the exchange values
+func (r *Reader) exchangeFields(values []string, exchange []Exchange, rst, str string) adif.Record {
+	var rec adif.Record
+
+	if r.contest != nil {
+		for i, e := range exchange {
+			if e.Field != "" && i < len(values) {
+				rec.Set(e.Field, values[i])
+			}
+		}
+		return rec
+	}
+
+	// no layout, keep a leading signal report as the rst and everything else as the exchange string
+	if len(values) > 0 && reRST.MatchString(values[0]) {
+		rec.Set(rst, values[0])
+		values = values[1:]
+	}
+	if len(values) > 0 {
+		rec.Set(str, strings.Join(values, " "))
+	}
+
+	return rec
+}
+
+// parseQSO returns the qso from the value of a QSO: line
+func (r *Reader) parseQSO(value string) (qso.QSO, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 6 {
+		return qso.QSO{}, fmt.Errorf("too few fields")
+	}
+
+	b, freq, err := band(fields[0])
+	if err != nil {
+		return qso.QSO{}, err
+	}
+
+	mode, err := r.mode(fields[1])
+	if err != nil {
+		return qso.QSO{}, err
+	}
+
+	t, err := time.Parse("2006-01-02 1504", fields[2]+" "+fields[3])
+	if err != nil {
+		return qso.QSO{}, fmt.Errorf("invalid date/time %s %s", fields[2], fields[3])
+	}
+
+	sent, rcvd, err := r.splitExchange(fields[4:])
+	if err != nil {
+		return qso.QSO{}, err
+	}
+
+	rec := adif.Record{
+		{Name: "STATION_CALLSIGN", Value: sent[0]},
+		{Name: "CALL", Value: rcvd[0]},
+		{Name: "BAND", Value: b},
+		{Name: "MODE", Value: mode},
+		{Name: "QSO_DATE", Value: t.Format("20060102")},
+		{Name: "TIME_ON", Value: t.Format("1504")},
+	}
+	if freq != "" {
+		rec = append(rec, adif.Field{Name: "FREQ", Value: freq})
+	}
+	if r.contestID != "" {
+		rec = append(rec, adif.Field{Name: "CONTEST_ID", Value: r.contestID})
+	}
+
+	var sentExchange, rcvdExchange []Exchange
+	if r.contest != nil {
+		sentExchange = r.contest.ExchangeSent
+		rcvdExchange = r.contest.ExchangeRcvd
+	}
+	rec = append(rec, r.exchangeFields(sent[1:], sentExchange, "RST_SENT", "STX_STRING")...)
+	rec = append(rec, r.exchangeFields(rcvd[1:], rcvdExchange, "RST_RCVD", "SRX_STRING")...)
+
+	q, err := adif.QSOFromRecord(rec)
+	if err != nil {
+		return qso.QSO{}, err
+	}
+
+	return *q, nil
+}
+
+// how many qsos are inserted per transaction when importing
+const importBatchSize = 1000
+
+// Import reads all qsos from r into the store s
+// lines that can't be parsed are returned, they don't stop the import
+func Import(s qso.QSOStore, r *Reader, qsllotw, qslqrz, qslclublog, qsleqsl qso.QSLSent) ([]*LineError, error) {
+	var lineErrors []*LineError
+
+	loadedAt := time.Now().Unix()
+	batch := make([]qso.QSO, 0, importBatchSize)
+
+	for {
+		q, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if le, ok := err.(*LineError); ok {
+				log.Printf("%+v", err)
+				lineErrors = append(lineErrors, le)
+				continue
+			}
+
+			log.Printf("%+v", err)
+			return lineErrors, err
+		}
+
+		q.LoadedAt = loadedAt
+		q.QSLLotw = qsllotw
+		q.QSLQrz = qslqrz
+		q.QSLClublog = qslclublog
+		q.QSLEqsl = qsleqsl
+
+		// make sure all is good
+		err = q.Validate(false)
+		if err != nil {
+			lineErrors = append(lineErrors, &LineError{Line: r.line, Err: err})
+			continue
+		}
+
+		batch = append(batch, q)
+		if len(batch) == importBatchSize {
+			_, err = s.BulkAdd(qso.WithSource(context.Background(), qso.SourceImport), batch)
+			if err != nil {
+				log.Printf("%+v", err)
+				return lineErrors, err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		_, err := s.BulkAdd(qso.WithSource(context.Background(), qso.SourceImport), batch)
+		if err != nil {
+			log.Printf("%+v", err)
+			return lineErrors, err
+		}
+	}
+
+	return lineErrors, nil
+}
+
+// ImportFromFile reads all qsos from the Cabrillo file fname into the store s
+// c and digitalMode are passed to NewReader
+func ImportFromFile(s qso.QSOStore, fname string, c *Contest, digitalMode string, qsllotw, qslqrz, qslclublog, qsleqsl qso.QSLSent) ([]*LineError, error) {
+	// #nosec G304
+	file, err := os.Open(fname)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+	defer file.Close()
+
+	lineErrors, err := Import(s, NewReader(file, c, digitalMode), qsllotw, qslqrz, qslclublog, qsleqsl)
+	if err != nil {
+		log.Printf("%+v", err)
+		return lineErrors, err
+	}
+
+	return lineErrors, nil
+}
@@ -0,0 +1,282 @@
+package cabrillo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/models/qso"
+)
+
+func TestMain(m *testing.M) {
+	// the lookups qsos are checked against, normally loaded from the tqsl config
+	config.Bands = []config.Band{
+		{Band: "40m", FreqLow: 7000, FreqHigh: 7300},
+		{Band: "20m", FreqLow: 14000, FreqHigh: 14350},
+		{Band: "6m", FreqLow: 50, FreqHigh: 54},
+		{Band: "2m", FreqLow: 144, FreqHigh: 148},
+	}
+	config.Modes = []config.Mode{
+		{Mode: "CW"},
+		{Mode: "SSB", Submode: "USB"},
+		{Mode: "SSB", Submode: "LSB"},
+		{Mode: "FM"},
+		{Mode: "RTTY"},
+		{Mode: "FT8"},
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		contest     *Contest
+		digitalMode string
+		want        qso.QSO
+		wantErr     bool
+	}{
+		{
+			name: "hf",
+			line: "QSO:  7025 CW 2024-03-01 1234 K0ABC         599 MN     W1AW          599 CT",
+			want: qso.QSO{
+				StationCallsign: "K0ABC", Call: "W1AW", Band: "40m", Mode: "CW", Date: "2024-03-01", Time: "12:34:00",
+				Frequency: 7025000, RSTSent: "599", RSTRcvd: "599",
+				Extra: qso.ExtraFields{{Name: "STX_STRING", Value: "MN"}, {Name: "SRX_STRING", Value: "CT"}},
+			},
+		},
+		{
+			name: "transmitter id",
+			line: "qso: 14250 PH 2024-03-01 2359 K0ABC 59 5 W1AW 59 4 1",
+			want: qso.QSO{
+				StationCallsign: "K0ABC", Call: "W1AW", Band: "20m", Mode: "SSB", Date: "2024-03-01", Time: "23:59:00",
+				Frequency: 14250000, RSTSent: "59", RSTRcvd: "59",
+				Extra: qso.ExtraFields{{Name: "STX_STRING", Value: "5"}, {Name: "SRX_STRING", Value: "4"}},
+			},
+		},
+		{
+			name: "no signal report",
+			line: "QSO: 144 FM 2024-03-01 1234 K0ABC EN34 W1AW FN31",
+			want: qso.QSO{
+				StationCallsign: "K0ABC", Call: "W1AW", Band: "2m", Mode: "FM", Date: "2024-03-01", Time: "12:34:00",
+				Extra: qso.ExtraFields{{Name: "STX_STRING", Value: "EN34"}, {Name: "SRX_STRING", Value: "FN31"}},
+			},
+		},
+		{
+			name: "multi-part exchange",
+			line: "QSO: 50 RY 2024-03-01 1234 K0ABC 599 1 MN W1AW 599 42 CT",
+			want: qso.QSO{
+				StationCallsign: "K0ABC", Call: "W1AW", Band: "6m", Mode: "RTTY", Date: "2024-03-01", Time: "12:34:00",
+				RSTSent: "599", RSTRcvd: "599",
+				Extra: qso.ExtraFields{{Name: "STX_STRING", Value: "1 MN"}, {Name: "SRX_STRING", Value: "42 CT"}},
+			},
+		},
+		{
+			name:        "digital",
+			line:        "QSO: 14074 DG 2024-03-01 1234 K0ABC EN34 W1AW FN31",
+			digitalMode: "ft8",
+			want: qso.QSO{
+				StationCallsign: "K0ABC", Call: "W1AW", Band: "20m", Mode: "FT8", Date: "2024-03-01", Time: "12:34:00",
+				Frequency: 14074000,
+				Extra:     qso.ExtraFields{{Name: "STX_STRING", Value: "EN34"}, {Name: "SRX_STRING", Value: "FN31"}},
+			},
+		},
+		{
+			name: "contest layout",
+			line: "QSO: 14025 CW 2024-03-01 1234 K0ABC 599 4 W1AW 599 5 1",
+			contest: &Contest{
+				ExchangeSent: []Exchange{{Field: "RST_SENT"}, {Field: "CQZ_SENT"}},
+				ExchangeRcvd: []Exchange{{Field: "RST_RCVD"}, {Field: "CQZ"}},
+			},
+			want: qso.QSO{
+				StationCallsign: "K0ABC", Call: "W1AW", Band: "20m", Mode: "CW", Date: "2024-03-01", Time: "12:34:00",
+				Frequency: 14025000, RSTSent: "599", RSTRcvd: "599", CQZone: 5,
+				Extra: qso.ExtraFields{{Name: "CQZ_SENT", Value: "4"}},
+			},
+		},
+		{
+			name: "contest layout without a field",
+			line: "QSO: 14025 CW 2024-03-01 1234 K0ABC 599 MN W1AW 599 CT",
+			contest: &Contest{
+				ExchangeSent: []Exchange{{Field: "RST_SENT"}, {Value: "MN"}},
+				ExchangeRcvd: []Exchange{{Field: "RST_RCVD"}, {Field: "STATE"}},
+			},
+			want: qso.QSO{
+				StationCallsign: "K0ABC", Call: "W1AW", Band: "20m", Mode: "CW", Date: "2024-03-01", Time: "12:34:00",
+				Frequency: 14025000, RSTSent: "599", RSTRcvd: "599",
+				Extra: qso.ExtraFields{{Name: "STATE", Value: "CT"}},
+			},
+		},
+		{name: "too few fields", line: "QSO: 7025 CW 2024-03-01 1234 K0ABC", wantErr: true},
+		{name: "frequency not a number", line: "QSO: 7.025 CW 2024-03-01 1234 K0ABC 599 W1AW 599", wantErr: true},
+		{name: "frequency not in a band", line: "QSO: 10125 CW 2024-03-01 1234 K0ABC 599 W1AW 599", wantErr: true},
+		{name: "unknown mode", line: "QSO: 7025 XX 2024-03-01 1234 K0ABC 599 W1AW 599", wantErr: true},
+		{name: "digital without a mode", line: "QSO: 7025 DG 2024-03-01 1234 K0ABC 599 W1AW 599", wantErr: true},
+		{name: "bad date", line: "QSO: 7025 CW 2024-13-01 1234 K0ABC 599 W1AW 599", wantErr: true},
+		{name: "bad time", line: "QSO: 7025 CW 2024-03-01 2460 K0ABC 599 W1AW 599", wantErr: true},
+		{name: "date in another format", line: "QSO: 7025 CW 20240301 1234 K0ABC 599 W1AW 599", wantErr: true},
+		{
+			name:    "exchange shorter than the layout",
+			line:    "QSO: 7025 CW 2024-03-01 1234 K0ABC 599 W1AW 599",
+			contest: &Contest{ExchangeSent: []Exchange{{Field: "RST_SENT"}, {Field: "STX"}}, ExchangeRcvd: []Exchange{{Field: "RST_RCVD"}, {Field: "SRX"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.line), tt.contest, tt.digitalMode)
+
+			got, err := r.Read()
+			if tt.wantErr {
+				var le *LineError
+				if !errors.As(err, &le) || le.Line != 1 || le.Text != tt.line {
+					t.Fatalf("Read() error = %v, want a *LineError for line 1", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() = %+v\nwant %+v", got, tt.want)
+			}
+
+			_, err = r.Read()
+			if err != io.EOF {
+				t.Errorf("Read() error = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestReaderLog(t *testing.T) {
+	log := `START-OF-LOG: 3.0
+CONTEST: ARRL-DX-CW
+CALLSIGN: K0ABC
+SOAPBOX: QSO: lines in the soapbox aren't qsos
+QSO:  7025 CW 2024-03-01 1234 K0ABC         599 MN     W1AW          599 CT
+QSO:  7025 XX 2024-03-01 1235 K0ABC         599 MN     W1AX          599 CT
+X-QSO:  7025 CW 2024-03-01 1236 K0ABC       599 MN     W1AY          599 CT
+
+QSO: 14025 CW 2024-03-01 1237 K0ABC         599 MN     W1AZ          599 CT
+END-OF-LOG:
+`
+	r := NewReader(strings.NewReader(log), nil, "")
+
+	var calls []string
+	var lines []int
+	for {
+		q, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		var le *LineError
+		if errors.As(err, &le) {
+			lines = append(lines, le.Line)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+
+		calls = append(calls, q.Call)
+		if len(q.Extra) == 0 || q.Extra[0] != (qso.ExtraField{Name: "CONTEST_ID", Value: "ARRL-DX-CW"}) {
+			t.Errorf("qso with %s has extra fields %+v, want the contest id first", q.Call, q.Extra)
+		}
+	}
+
+	if !reflect.DeepEqual(calls, []string{"W1AW", "W1AZ"}) {
+		t.Errorf("calls = %v, want W1AW & W1AZ", calls)
+	}
+	if !reflect.DeepEqual(lines, []int{6}) {
+		t.Errorf("errors on lines %v, want 6", lines)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	c := Contest{
+		Contest:      "CQ-WW-CW",
+		ExchangeSent: []Exchange{{Field: "RST_SENT", Value: "599", Width: 3}, {Value: "4", Width: 3}},
+		ExchangeRcvd: []Exchange{{Field: "RST_RCVD", Value: "599", Width: 3}, {Field: "CQZ", Width: 3}},
+	}
+
+	qsos := []qso.QSO{
+		{StationCallsign: "K0ABC", Call: "W1AW", Band: "20m", Mode: "CW", Date: "2024-03-01", Time: "12:34:00", Frequency: 14025000, RSTSent: "599", RSTRcvd: "599", CQZone: 5},
+		{StationCallsign: "K0ABC", Call: "JA1XYZ", Band: "40m", Mode: "CW", Date: "2024-03-02", Time: "00:01:00", RSTSent: "579", RSTRcvd: "559", CQZone: 25},
+		{StationCallsign: "K0ABC", Call: "VE3XX/VE2", Band: "2m", Mode: "CW", Date: "2024-03-02", Time: "23:59:00", CQZone: 4},
+	}
+
+	var buf bytes.Buffer
+	err := Write(&buf, c, qsos)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	r := NewReader(&buf, &c, "")
+	for i, want := range qsos {
+		got, err := r.Read()
+		if err != nil {
+			t.Fatalf("qso %d: Read() error = %v\n%s", i, err, buf.String())
+		}
+
+		// qsos without a frequency are written at the low edge of the band, vhf only has the band
+		if want.Frequency == 0 && want.Band == "40m" {
+			want.Frequency = 7000000
+		}
+		// unset reports are written as the default
+		if want.RSTSent == "" {
+			want.RSTSent, want.RSTRcvd = "599", "599"
+		}
+
+		if got.StationCallsign != want.StationCallsign || got.Call != want.Call || got.Band != want.Band || got.Mode != want.Mode ||
+			got.Date != want.Date || got.Time != want.Time || got.Frequency != want.Frequency ||
+			got.RSTSent != want.RSTSent || got.RSTRcvd != want.RSTRcvd || got.CQZone != want.CQZone {
+			t.Errorf("qso %d: Read() = %+v\nwant %+v", i, got, want)
+		}
+	}
+
+	_, err = r.Read()
+	if err != io.EOF {
+		t.Errorf("Read() error = %v, want io.EOF", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	log := `START-OF-LOG: 3.0
+QSO:  7025 CW 2024-03-01 1234 K0ABC 599 MN W1AW 599 CT
+QSO:  7025 CW 2024-03-01 1234 K0ABC 599 MN W1AW 599 CT
+QSO:  7025 XX 2024-03-01 1235 K0ABC 599 MN W1AX 599 CT
+QSO: 14025 CW 2024-03-01 1237 K0ABC 599 MN W1AZ 599 CT
+END-OF-LOG:
+`
+	s := qso.NewMemoryStore()
+
+	lineErrors, err := Import(s, NewReader(strings.NewReader(log), nil, ""), qso.NotSent, qso.Sent, qso.Sent, qso.Sent)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(lineErrors) != 1 || lineErrors[0].Line != 4 {
+		t.Errorf("Import() line errors = %v, want one on line 4", lineErrors)
+	}
+
+	qsos, err := s.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(qsos) != 2 {
+		t.Fatalf("imported %d QSOs, want 2 without the duplicate", len(qsos))
+	}
+	for _, q := range qsos {
+		if q.LoadedAt == 0 || q.QSLLotw != qso.NotSent || q.QSLQrz != qso.Sent {
+			t.Errorf("imported %+v, want LoadedAt set and only LoTW to upload", q)
+		}
+	}
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/bbathe/golog/config"
 	"github.com/bbathe/golog/db"
+	"github.com/bbathe/golog/dxcc"
+	"github.com/bbathe/golog/models/qso"
 	"github.com/bbathe/golog/tasks"
 	"github.com/bbathe/golog/ui"
 )
@@ -95,13 +97,22 @@ func main() {
 		log.Fatalf("%+v", err)
 	}
 
+	// country files for dxcc entities, either or both can be there
+	// qsos & spots just don't get entities filled in when they can't be read
+	err = dxcc.Load(filepath.Join(filepath.Dir(basefn), "cty.xml"), filepath.Join(filepath.Dir(basefn), "cty.dat"))
+	if err != nil {
+		log.Printf("%+v", err)
+	}
+
+	store := qso.NewSQLiteStore(db.QSODb)
+
 	// start background tasks
 	go func() {
-		tasks.Start()
+		tasks.Start(store)
 	}()
 
 	// show app, doesn't come back until main window closed
-	err = ui.GoLogWindow()
+	err = ui.GoLogWindow(store)
 	if err != nil {
 		log.Fatalf("%+v", err)
 	}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bbathe/golog/db"
+	"github.com/bbathe/golog/models/qso"
+)
+
+// printAudit writes the audit entries for people
+func printAudit(entries []qso.AuditEntry) {
+	for _, a := range entries {
+		undone := ""
+		if a.Undone {
+			undone = " (undone)"
+		}
+		fmt.Printf("%d %s qso %d %s by %s%s\n", a.ID, unixTime(a.ChangedAt), a.QSOID, a.Action, a.Source, undone)
+		if a.OldValues != "" {
+			fmt.Printf("  old %s\n", a.OldValues)
+		}
+		if a.NewValues != "" {
+			fmt.Printf("  new %s\n", a.NewValues)
+		}
+	}
+}
+
+func auditCommand(args []string) error {
+	var asJSON, deleted bool
+	var id, restore int64
+	var undo, redo int
+	flg := flag.NewFlagSet("audit", flag.ExitOnError)
+	flg.BoolVar(&asJSON, "json", false, "write the result as json")
+	flg.Int64Var(&id, "qso", 0, "list every change made to the QSO with this id")
+	flg.BoolVar(&deleted, "deleted", false, "list the deleted QSOs that can be restored")
+	flg.Int64Var(&restore, "restore", 0, "restore the deleted QSO with this id")
+	flg.IntVar(&undo, "undo", 0, "undo the last n changes made by hand")
+	flg.IntVar(&redo, "redo", 0, "redo the last n changes that were undone")
+	_ = flg.Parse(args)
+
+	store := qso.NewSQLiteStore(db.QSODb)
+	ctx := qso.WithSource(context.Background(), qso.SourceManual)
+
+	var entries []qso.AuditEntry
+	var err error
+	switch {
+	case restore != 0:
+		q, err := store.Restore(ctx, restore)
+		if err != nil {
+			return err
+		}
+
+		if asJSON {
+			return printJSON(os.Stdout, q)
+		}
+		fmt.Printf("restored %d %s %s %s %s %s\n", q.ID, q.Date, q.Time, q.Call, q.Band, q.Mode)
+		return nil
+	case undo != 0:
+		entries, err = store.Undo(ctx, undo)
+	case redo != 0:
+		entries, err = store.Redo(ctx, redo)
+	case deleted:
+		entries, err = store.Deleted(ctx)
+	case id != 0:
+		entries, err = store.AuditTrail(ctx, id)
+	default:
+		flg.Usage()
+		return fmt.Errorf("nothing to do")
+	}
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		return printJSON(os.Stdout, entries)
+	}
+
+	printAudit(entries)
+	switch {
+	case undo != 0:
+		fmt.Printf("%d changes undone\n", len(entries))
+	case redo != 0:
+		fmt.Printf("%d changes redone\n", len(entries))
+	case deleted:
+		fmt.Printf("%d deleted QSOs\n", len(entries))
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/db"
+	"github.com/bbathe/golog/dxcc"
+)
+
+// command is a gologcli subcommand, args are what follows the subcommand name
+type command struct {
+	usage  string
+	needDb bool
+	run    func(args []string) error
+}
+
+var commands = map[string]command{
+	"audit": {
+		usage:  "audit [-json] [-qso id] [-deleted] [-restore id] [-undo n] [-redo n]\n    list the changes made to a QSO or the deleted QSOs, restore a deleted QSO and undo or redo changes made by hand",
+		needDb: true,
+		run:    auditCommand,
+	},
+	"lint": {
+		usage: "lint [-json] [-adx] file|-\n    report problems with the records in an ADIF (or ADX) file without importing it",
+		run:   lintCommand,
+	},
+	"import": {
+		usage:  "import [-json] [-adx] [-lotw] [-qrz] [-clublog] [-eqsl] file|-\n    import an ADIF (or ADX) file, -lotw -qrz -clublog -eqsl queue the QSOs for upload",
+		needDb: true,
+		run:    importCommand,
+	},
+	"outbox": {
+		usage:  "outbox [-json] [-retry] [-history id]\n    list the QSOs that failed to upload, -retry uploads them again and -history lists every attempt for a QSO",
+		needDb: true,
+		run:    outboxCommand,
+	},
+	"search": {
+		usage:  "search [-json] query\n    list the QSOs matching the query, e.g. band:20m,40m mode:FT8 date>=2024-01-01 !lotw:confirmed",
+		needDb: true,
+		run:    searchCommand,
+	},
+	"stats": {
+		usage:  "stats [-json] [-csv] [-from date] [-to date] [-top n]\n    summarize the log, QSOs by band, mode, date & hour, QSL rates for each service and the most worked calls",
+		needDb: true,
+		run:    statsCommand,
+	},
+	"qrzsync": {
+		usage:  "qrzsync [-json] [-import]\n    compare the log with the QRZ.com logbook, marking confirmed QSOs and reporting QSOs missing from either",
+		needDb: true,
+		run:    qrzSyncCommand,
+	},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage of %s\n  [-config string] command [arguments]\n\nCommands\n", filepath.Base(os.Args[0]))
+
+	names := make([]string, 0, len(commands))
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[n].usage)
+	}
+}
+
+// setup reads the config & lookups golog uses and optionally opens the qso database
+func setup(configFile string, needDb bool) error {
+	// location for log file and default config are in the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	basefn := filepath.Join(wd, "golog")
+
+	// log to the same file as golog, output is for the user
+	f, err := os.OpenFile(basefn+".log", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	log.SetOutput(f)
+
+	cfn := configFile
+	if cfn == "" {
+		cfn = basefn + ".yaml"
+	}
+
+	err = config.Read(cfn)
+	if err != nil {
+		return err
+	}
+
+	err = config.ReadLookupsFromFile(filepath.Join(wd, "lookups.yaml"))
+	if err != nil {
+		return err
+	}
+
+	// country files for dxcc entities, either or both can be there
+	// qsos just don't get entities filled in when they can't be read
+	err = dxcc.Load(filepath.Join(wd, "cty.xml"), filepath.Join(wd, "cty.dat"))
+	if err != nil {
+		log.Printf("%+v", err)
+	}
+
+	if needDb {
+		err = db.OpenQSODb()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	// show file & location, date & time
+	log.SetFlags(log.Ldate | log.Ltime | log.Llongfile)
+
+	// process command line
+	var configFile string
+	flg := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flg.Usage = usage
+	flg.StringVar(&configFile, "config", "", "Configuration file")
+	_ = flg.Parse(os.Args[1:])
+
+	if flg.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flg.Arg(0)]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	err := setup(configFile, cmd.needDb)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = cmd.run(flg.Args()[1:])
+
+	if cmd.needDb {
+		cerr := db.CloseQSODb()
+		if cerr != nil {
+			log.Printf("%+v", cerr)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
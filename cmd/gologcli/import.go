@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bbathe/golog/adif"
+	"github.com/bbathe/golog/db"
+	"github.com/bbathe/golog/models/qso"
+)
+
+// openRecordReader returns a reader for the file named by the only argument, - is stdin
+func openRecordReader(flg *flag.FlagSet, adx bool) (adif.RecordReader, io.Closer, error) {
+	if flg.NArg() != 1 {
+		return nil, nil, fmt.Errorf("expected one file name, or - for stdin")
+	}
+	fname := flg.Arg(0)
+
+	if fname == "-" {
+		if adx {
+			return adif.NewADXReader(os.Stdin), io.NopCloser(nil), nil
+		}
+		return adif.NewReader(os.Stdin), io.NopCloser(nil), nil
+	}
+
+	// #nosec G304
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if adx {
+		return adif.NewADXReader(f), f, nil
+	}
+	return adif.NewRecordReader(fname, f), f, nil
+}
+
+// printReport writes the records with problems, one line per problem
+func printReport(w io.Writer, report adif.Report) {
+	for _, r := range report.Records {
+		where := fmt.Sprintf("offset %d", r.Offset)
+		if r.Line > 0 {
+			where = fmt.Sprintf("line %d", r.Line)
+		}
+
+		status := "warning"
+		if r.Rejected {
+			status = "rejected"
+		}
+
+		for _, p := range r.Problems {
+			fmt.Fprintf(w, "%s: %s: %s\n", where, status, p)
+		}
+	}
+}
+
+// printJSON writes v as indented json
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func lintCommand(args []string) error {
+	var asJSON, adx bool
+	flg := flag.NewFlagSet("lint", flag.ExitOnError)
+	flg.BoolVar(&asJSON, "json", false, "write the report as json")
+	flg.BoolVar(&adx, "adx", false, "input is ADX, only needed for stdin")
+	_ = flg.Parse(args)
+
+	rr, c, err := openRecordReader(flg, adx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	report, err := adif.Lint(rr)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		return printJSON(os.Stdout, report)
+	}
+
+	printReport(os.Stdout, report)
+	fmt.Printf("%d records, %d with problems, %d would be rejected\n", report.Total, len(report.Records), report.Rejected)
+
+	return nil
+}
+
+func importCommand(args []string) error {
+	var asJSON, adx, lotw, qrz, clublog, eqsl bool
+	flg := flag.NewFlagSet("import", flag.ExitOnError)
+	flg.BoolVar(&asJSON, "json", false, "write the result as json")
+	flg.BoolVar(&adx, "adx", false, "input is ADX, only needed for stdin")
+	flg.BoolVar(&lotw, "lotw", false, "upload the QSOs to LoTW")
+	flg.BoolVar(&qrz, "qrz", false, "upload the QSOs to QRZ.com")
+	flg.BoolVar(&clublog, "clublog", false, "upload the QSOs to Club Log")
+	flg.BoolVar(&eqsl, "eqsl", false, "upload the QSOs to eQSL.cc")
+	_ = flg.Parse(args)
+
+	rr, c, err := openRecordReader(flg, adx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// qsos flagged as already sent won't be uploaded
+	sent := func(upload bool) qso.QSLSent {
+		if upload {
+			return qso.NotSent
+		}
+		return qso.Sent
+	}
+
+	result, err := adif.Import(qso.NewSQLiteStore(db.QSODb), rr, sent(lotw), sent(qrz), sent(clublog), sent(eqsl))
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		return printJSON(os.Stdout, result)
+	}
+
+	printReport(os.Stdout, result.Report)
+	fmt.Println(result)
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bbathe/golog/db"
+	"github.com/bbathe/golog/models/qso"
+)
+
+// unixTime formats seconds since the epoch for people
+func unixTime(t int64) string {
+	return time.Unix(t, 0).UTC().Format("2006-01-02 15:04:05")
+}
+
+func outboxCommand(args []string) error {
+	var asJSON, retry bool
+	var history int64
+	flg := flag.NewFlagSet("outbox", flag.ExitOnError)
+	flg.BoolVar(&asJSON, "json", false, "write the result as json")
+	flg.BoolVar(&retry, "retry", false, "upload the failed QSOs again with the next upload")
+	flg.Int64Var(&history, "history", 0, "list every upload attempt for the QSO with this id")
+	_ = flg.Parse(args)
+
+	store := qso.NewSQLiteStore(db.QSODb)
+	ctx := context.Background()
+
+	if history != 0 {
+		attempts, err := store.UploadAttempts(ctx, history)
+		if err != nil {
+			return err
+		}
+
+		if asJSON {
+			return printJSON(os.Stdout, attempts)
+		}
+
+		for _, a := range attempts {
+			result := "ok"
+			if a.ErrorClass != "" {
+				result = string(a.ErrorClass)
+			}
+			fmt.Printf("%s %s %s %s\n", unixTime(a.AttemptedAt), a.Service, result, a.Response)
+		}
+		return nil
+	}
+
+	items, err := store.FailedUploads(ctx)
+	if err != nil {
+		return err
+	}
+
+	if retry {
+		for _, o := range items {
+			err = store.RetryUpload(ctx, o)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if asJSON {
+		return printJSON(os.Stdout, items)
+	}
+
+	for _, o := range items {
+		next := "not retried"
+		if o.State == qso.OutboxRetrying {
+			next = "next " + unixTime(o.NextAttemptAt)
+		}
+		fmt.Printf("%d %s %s %s %s %s %s: %s after %d attempts, %s\n", o.QSOID, o.Service, o.Date, o.Time, o.Call, o.Band, o.Mode, o.ErrorClass, o.Attempts, next)
+	}
+	if retry {
+		fmt.Printf("%d QSOs will be uploaded again\n", len(items))
+	} else {
+		fmt.Printf("%d failed uploads\n", len(items))
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/db"
+	"github.com/bbathe/golog/models/qso"
+	"github.com/bbathe/golog/tasks"
+)
+
+// printQSOs writes one line per qso under the title
+func printQSOs(w io.Writer, title string, qsos []qso.QSO) {
+	if len(qsos) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, q := range qsos {
+		fmt.Fprintf(w, "  %s %s %s %s %s\n", q.Date, q.Time, q.Call, q.Band, q.Mode)
+	}
+}
+
+func qrzSyncCommand(args []string) error {
+	var asJSON, importMissing bool
+	flg := flag.NewFlagSet("qrzsync", flag.ExitOnError)
+	flg.BoolVar(&asJSON, "json", false, "write the result as json")
+	flg.BoolVar(&importMissing, "import", false, "import the QSOs that are only on QRZ.com")
+	_ = flg.Parse(args)
+
+	err := config.LogbookServices.QRZ.Validate()
+	if err != nil {
+		return err
+	}
+
+	result, err := tasks.SyncQRZ(qso.NewSQLiteStore(db.QSODb), importMissing)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		return printJSON(os.Stdout, result)
+	}
+
+	printQSOs(os.Stdout, "missing locally", result.MissingLocal)
+	printQSOs(os.Stdout, "missing on QRZ.com", result.MissingRemote)
+	fmt.Println(result)
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bbathe/golog/db"
+	"github.com/bbathe/golog/models/qso"
+)
+
+func searchCommand(args []string) error {
+	var asJSON bool
+	flg := flag.NewFlagSet("search", flag.ExitOnError)
+	flg.BoolVar(&asJSON, "json", false, "write the result as json")
+	_ = flg.Parse(args)
+
+	query, err := qso.ParseQuery(strings.Join(flg.Args(), " "))
+	if err != nil {
+		return err
+	}
+
+	qsos, err := qso.NewSQLiteStore(db.QSODb).Find(context.Background(), query)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		return printJSON(os.Stdout, qsos)
+	}
+
+	for _, q := range qsos {
+		fmt.Printf("%d %s %s %s %s %s %s\n", q.ID, q.Date, q.Time, q.StationCallsign, q.Call, q.Band, q.Mode)
+	}
+	fmt.Printf("%d QSOs\n", len(qsos))
+
+	// a full page probably has more after it
+	if query.Limit > 0 && len(qsos) == query.Limit {
+		fmt.Printf("next page with after:%d\n", qsos[len(qsos)-1].ID)
+	}
+
+	return nil
+}
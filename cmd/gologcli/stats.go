@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/bbathe/golog/db"
+	"github.com/bbathe/golog/stats"
+)
+
+func statsCommand(args []string) error {
+	var asJSON, asCSV bool
+	var opts stats.Options
+	flg := flag.NewFlagSet("stats", flag.ExitOnError)
+	flg.BoolVar(&asJSON, "json", false, "write the report as json")
+	flg.BoolVar(&asCSV, "csv", false, "write the report as csv")
+	flg.StringVar(&opts.From, "from", "", "only QSOs on or after this date, yyyy-mm-dd")
+	flg.StringVar(&opts.To, "to", "", "only QSOs on or before this date, yyyy-mm-dd")
+	flg.IntVar(&opts.TopCalls, "top", 0, "how many of the most worked calls to list")
+	_ = flg.Parse(args)
+
+	r, err := stats.Summarize(context.Background(), db.QSODb, opts)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case asJSON:
+		return r.WriteJSON(os.Stdout)
+	case asCSV:
+		return r.WriteCSV(os.Stdout)
+	}
+	return r.WriteText(os.Stdout)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 
 	"github.com/lxn/walk"
@@ -54,11 +55,30 @@ type sourcefile struct {
 	Offset   int64
 }
 
+// base URLs of the logbook services, used when a service's URL isn't set
+const (
+	ClubLogURL = "https://clublog.org"
+	QRZURL     = "https://logbook.qrz.com"
+	LoTWURL    = "https://lotw.arrl.org/lotwuser"
+	EqslURL    = "https://www.eqsl.cc/qslcard"
+)
+
+// baseURL returns u, or def if it isn't set
+func baseURL(u, def string) string {
+	if u == "" {
+		return def
+	}
+	return u
+}
+
 type clublog struct {
 	Email    string
 	Password string
 	Callsign string
 	APIKey   string
+
+	// base URL, only needs to be set for testing
+	URL string `yaml:",omitempty"`
 }
 
 // Validate tests the required clublog fields
@@ -84,8 +104,16 @@ func (c *clublog) Validate() error {
 	return nil
 }
 
+// BaseURL returns the base URL of Club Log
+func (c *clublog) BaseURL() string {
+	return baseURL(c.URL, ClubLogURL)
+}
+
 type qrz struct {
 	APIKey string
+
+	// base URL, only needs to be set for testing
+	URL string `yaml:",omitempty"`
 }
 
 // Validate tests the required qrz fields
@@ -99,6 +127,11 @@ func (q *qrz) Validate() error {
 	return nil
 }
 
+// BaseURL returns the base URL of the QRZ.com logbook
+func (q *qrz) BaseURL() string {
+	return baseURL(q.URL, QRZURL)
+}
+
 type tqsl struct {
 	ExeLocation         string
 	StationLocationName string
@@ -119,11 +152,99 @@ func (t *tqsl) Validate() error {
 	return nil
 }
 
+type lotw struct {
+	Username string
+	Password string
+
+	// base URL, only needs to be set for testing
+	URL string `yaml:",omitempty"`
+}
+
+// Validate tests the required lotw fields
+// doesn't log errors because you don't have to download lotw confirmations
+func (l *lotw) Validate() error {
+	if l.Username == "" {
+		err := fmt.Errorf(msgMissingField, "LoTW Username")
+		return err
+	}
+	if l.Password == "" {
+		err := fmt.Errorf(msgMissingField, "LoTW Password")
+		return err
+	}
+
+	return nil
+}
+
+// BaseURL returns the base URL of LoTW
+func (l *lotw) BaseURL() string {
+	return baseURL(l.URL, LoTWURL)
+}
+
+type eqsl struct {
+	Username string
+	Password string
+
+	// only needed when the account has more than one QTH
+	QTHNickname string `yaml:",omitempty"`
+
+	// base URL, only needs to be set for testing
+	URL string `yaml:",omitempty"`
+}
+
+// Validate tests the required eqsl fields
+// doesn't log errors because you don't have to use eqsl
+func (e *eqsl) Validate() error {
+	if e.Username == "" {
+		err := fmt.Errorf(msgMissingField, "eQSL Username")
+		return err
+	}
+	if e.Password == "" {
+		err := fmt.Errorf(msgMissingField, "eQSL Password")
+		return err
+	}
+
+	return nil
+}
+
+// BaseURL returns the base URL of eQSL.cc
+func (e *eqsl) BaseURL() string {
+	return baseURL(e.URL, EqslURL)
+}
+
+// httpclient is how requests are made to the logbook services, the zero value uses the defaults
+type httpclient struct {
+	// proxy url, the system proxy is used when empty
+	Proxy string `yaml:",omitempty"`
+
+	// request timeout in seconds
+	Timeout int `yaml:",omitempty"`
+
+	UserAgent string `yaml:",omitempty"`
+}
+
+// Validate tests the httpclient fields
+func (h *httpclient) Validate() error {
+	if h.Proxy != "" {
+		_, err := url.Parse(h.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid HTTP proxy %s: %w", h.Proxy, err)
+		}
+	}
+	if h.Timeout < 0 {
+		return fmt.Errorf("invalid HTTP timeout %d", h.Timeout)
+	}
+
+	return nil
+}
+
 type logbookservices struct {
 	QSLDelay int
+	HTTP     httpclient `yaml:",omitempty"`
 	TQSL     tqsl
+	LoTW     lotw
 	ClubLog  clublog
 	QRZ      qrz
+	Eqsl     eqsl
 }
 
 type hamalert struct {
@@ -167,6 +288,7 @@ type Configuration struct {
 	ClusterServices  clusterservices
 	WorkingDirectory string
 	BackupDirectory  string
+	BackupFormat     string
 }
 
 func (c *Configuration) AddSourceFile(fname string) {
@@ -216,6 +338,16 @@ func (c *Configuration) Validate() error {
 		log.Printf("%+v", err)
 		return err
 	}
+	if c.BackupFormat != "" && c.BackupFormat != "adif" && c.BackupFormat != "adx" {
+		err := fmt.Errorf("unsupported backup format %s", c.BackupFormat)
+		log.Printf("%+v", err)
+		return err
+	}
+	err := c.LogbookServices.HTTP.Validate()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
 
 	return nil
 }
@@ -235,6 +367,7 @@ var (
 	ClusterServices  clusterservices
 	WorkingDirectory string
 	BackupDirectory  string
+	BackupFormat     string
 )
 
 // Read loads application configuration from file fname
@@ -272,6 +405,7 @@ func Read(fname string) error {
 	ClusterServices = c.ClusterServices
 	WorkingDirectory = c.WorkingDirectory
 	BackupDirectory = c.BackupDirectory
+	BackupFormat = c.BackupFormat
 
 	return nil
 }
@@ -293,6 +427,7 @@ func Write() error {
 		ClusterServices:  ClusterServices,
 		WorkingDirectory: WorkingDirectory,
 		BackupDirectory:  BackupDirectory,
+		BackupFormat:     BackupFormat,
 	}
 
 	// make sure valid before proceeding
@@ -336,6 +471,7 @@ func Copy(c *Configuration) error {
 		ClusterServices:  ClusterServices,
 		WorkingDirectory: WorkingDirectory,
 		BackupDirectory:  BackupDirectory,
+		BackupFormat:     BackupFormat,
 	}
 	err := ac.Validate()
 	if err != nil {
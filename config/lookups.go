@@ -105,10 +105,27 @@ func LookupMode(mode, submode string) string {
 	return mode
 }
 
-// LookupBand returns the band name for the frequency passed
+// bandScale returns what the band frequency range is multiplied by to be in kHz
+// like the tqsl config, HF bands are in kHz and 6m and up are in MHz
+func bandScale(band string) int {
+	switch {
+	case strings.HasSuffix(band, "cm"), strings.HasSuffix(band, "mm"):
+		return 1000
+	case strings.HasSuffix(band, "m"):
+		meters, err := strconv.ParseFloat(strings.TrimSuffix(band, "m"), 64)
+		if err == nil && meters < 8 {
+			return 1000
+		}
+	}
+
+	return 1
+}
+
+// LookupBand returns the band name for the frequency (kHz) passed
 func LookupBand(frequency int) string {
 	for _, b := range Bands {
-		if b.FreqHigh >= frequency && b.FreqLow <= frequency {
+		scale := bandScale(b.Band)
+		if b.FreqHigh*scale >= frequency && b.FreqLow*scale <= frequency {
 			return b.Band
 		}
 	}
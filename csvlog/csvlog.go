@@ -0,0 +1,71 @@
+package csvlog
+
+import (
+	"log"
+	"os"
+
+	"github.com/bbathe/golog/adif"
+	"github.com/bbathe/golog/models/qso"
+)
+
+// ReadFromFile reads QSOs from the CSV file fname using mapping m
+// rows are validated the same way as ADIF records, rows that fail are logged, skipped and included in the report
+func ReadFromFile(fname string, m Mapping, qsllotw, qslqrz, qslclublog, qsleqsl qso.QSLSent) ([]qso.QSO, adif.Report, error) {
+	// #nosec G304
+	file, err := os.Open(fname)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, adif.Report{}, err
+	}
+	defer file.Close()
+
+	qsos := make([]qso.QSO, 0, 128)
+	report, err := adif.ForEachQSO(NewReader(file, m), qsllotw, qslqrz, qslclublog, qsleqsl, func(q qso.QSO) error {
+		qsos = append(qsos, q)
+		return nil
+	})
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, report, err
+	}
+
+	return qsos, report, nil
+}
+
+// ImportFromFile streams the QSOs from the CSV file fname into the store s using mapping m
+func ImportFromFile(s qso.QSOStore, fname string, m Mapping, qsllotw, qslqrz, qslclublog, qsleqsl qso.QSLSent) (adif.ImportResult, error) {
+	// #nosec G304
+	file, err := os.Open(fname)
+	if err != nil {
+		log.Printf("%+v", err)
+		return adif.ImportResult{}, err
+	}
+	defer file.Close()
+
+	result, err := adif.Import(s, NewReader(file, m), qsllotw, qslqrz, qslclublog, qsleqsl)
+	if err != nil {
+		log.Printf("%+v", err)
+		return result, err
+	}
+
+	return result, nil
+}
+
+// WriteToFile creates a CSV file with all qsos using mapping m
+func WriteToFile(qsos []qso.QSO, fname string, m Mapping) error {
+	// create file
+	f, err := os.Create(fname)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer f.Close()
+
+	err = adif.WriteQSOs(NewWriter(f, m), adif.Header{}, qsos)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
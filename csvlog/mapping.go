@@ -0,0 +1,134 @@
+package csvlog
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/bbathe/golog/adif"
+	"gopkg.in/yaml.v2"
+)
+
+// Column maps a CSV column to an ADIF field
+type Column struct {
+	// column heading, used to find the column when the file has a heading row
+	Heading string `yaml:"heading"`
+
+	// ADIF field name, ie CALL, BAND, QSO_DATE or any other ADIF field
+	Field string `yaml:"field"`
+
+	// Go time layout of date and time fields, ie 01/02/2006 or 15:04, without it values must be in ADIF format
+	Format string `yaml:"format"`
+}
+
+// Mapping describes the layout of a CSV file
+type Mapping struct {
+	// first row is column headings, columns are matched by heading instead of position
+	HeadingRow bool `yaml:"heading-row"`
+
+	// field separator, defaults to a comma
+	Comma string `yaml:"comma"`
+
+	Columns []Column `yaml:"columns"`
+}
+
+// DefaultMapping returns the mapping used when the user doesn't provide one, a column for each field of the qsos table
+func DefaultMapping() Mapping {
+	return Mapping{
+		HeadingRow: true,
+		Columns: []Column{
+			{Heading: "Station Callsign", Field: "STATION_CALLSIGN"},
+			{Heading: "Date", Field: "QSO_DATE", Format: "2006-01-02"},
+			{Heading: "Time", Field: "TIME_ON", Format: "15:04:05"},
+			{Heading: "Time Off", Field: "TIME_OFF", Format: "15:04:05"},
+			{Heading: "Call", Field: "CALL"},
+			{Heading: "Band", Field: "BAND"},
+			{Heading: "Mode", Field: "MODE"},
+			{Heading: "Submode", Field: "SUBMODE"},
+			{Heading: "RST Rcvd", Field: "RST_RCVD"},
+			{Heading: "RST Sent", Field: "RST_SENT"},
+		},
+	}
+}
+
+// Validate tests the mapping is usable
+func (m Mapping) Validate() error {
+	if len(m.Columns) == 0 {
+		err := fmt.Errorf("no columns in mapping")
+		log.Printf("%+v", err)
+		return err
+	}
+	if len([]rune(m.Comma)) > 1 {
+		err := fmt.Errorf("comma must be a single character")
+		log.Printf("%+v", err)
+		return err
+	}
+
+	for i, c := range m.Columns {
+		if c.Field == "" {
+			err := fmt.Errorf("column %d has no field", i+1)
+			log.Printf("%+v", err)
+			return err
+		}
+		if m.HeadingRow && c.Heading == "" {
+			err := fmt.Errorf("column %d has no heading", i+1)
+			log.Printf("%+v", err)
+			return err
+		}
+		if c.Format != "" && !isDateTime(c.Field) {
+			err := fmt.Errorf("column %d has a format but %s is not a date or time", i+1, c.Field)
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// comma returns the field separator
+func (m Mapping) comma() rune {
+	if m.Comma == "" {
+		return ','
+	}
+	return []rune(m.Comma)[0]
+}
+
+// isDateTime returns true if the adif field is a date or time
+func isDateTime(field string) bool {
+	t := adif.FieldType(strings.ToUpper(field), "")
+	return t == adif.TypeDate || t == adif.TypeTime
+}
+
+// adifLayout returns the time layout adif uses for the field
+func adifLayout(field string) string {
+	if adif.FieldType(strings.ToUpper(field), "") == adif.TypeDate {
+		return "20060102"
+	}
+	return "150405"
+}
+
+// ReadMappingFromFile reads a mapping from the YAML file fname
+func ReadMappingFromFile(fname string) (Mapping, error) {
+	// #nosec G304
+	bs, err := os.ReadFile(fname)
+	if err != nil {
+		log.Printf("%+v", err)
+		return Mapping{}, err
+	}
+
+	var m Mapping
+	err = yaml.Unmarshal(bs, &m)
+	if err != nil {
+		log.Printf("%+v", err)
+		return Mapping{}, err
+	}
+
+	err = m.Validate()
+	if err != nil {
+		log.Printf("%+v", err)
+		return Mapping{}, err
+	}
+
+	return m, nil
+}
@@ -0,0 +1,142 @@
+package csvlog
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bbathe/golog/adif"
+)
+
+// Reader reads CSV rows as ADIF records, implements adif.RecordReader
+type Reader struct {
+	r *csv.Reader
+	m Mapping
+
+	// position of each mapping column in the rows
+	index []int
+
+	// where the last row read started
+	rowOffset int64
+	rowLine   int
+}
+
+// NewReader returns a Reader that reads from r using mapping m
+func NewReader(r io.Reader, m Mapping) *Reader {
+	cr := csv.NewReader(r)
+	cr.Comma = m.comma()
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	return &Reader{
+		r: cr,
+		m: m,
+	}
+}
+
+// Header returns an empty header, CSV files don't have one
+func (r *Reader) Header() adif.Header {
+	return adif.Header{}
+}
+
+// Offset returns the number of bytes consumed so far
+func (r *Reader) Offset() int64 {
+	return r.r.InputOffset()
+}
+
+// RecordOffset returns the byte offset and line number of the start of the row last read
+func (r *Reader) RecordOffset() (int64, int) {
+	return r.rowOffset, r.rowLine
+}
+
+// findColumns works out the position of each mapping column
+func (r *Reader) findColumns() error {
+	r.index = make([]int, len(r.m.Columns))
+
+	if !r.m.HeadingRow {
+		for i := range r.m.Columns {
+			r.index[i] = i
+		}
+		return nil
+	}
+
+	headings, err := r.r.Read()
+	if err != nil {
+		return err
+	}
+
+	for i, c := range r.m.Columns {
+		r.index[i] = -1
+		for j, h := range headings {
+			if strings.EqualFold(strings.TrimSpace(h), c.Heading) {
+				r.index[i] = j
+				break
+			}
+		}
+		if r.index[i] < 0 {
+			return fmt.Errorf("no column with heading %s", c.Heading)
+		}
+	}
+
+	return nil
+}
+
+// Read returns the next row as a record, returns io.EOF when there are no more rows
+// a *adif.ParseError is returned for a row that can't be read, Read can be called again to continue with the next row
+func (r *Reader) Read() (adif.Record, error) {
+	if r.index == nil {
+		err := r.findColumns()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.rowOffset = r.r.InputOffset()
+	row, err := r.r.Read()
+	if err != nil {
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			return nil, &adif.ParseError{Offset: r.rowOffset, Line: pe.StartLine, Err: pe.Err}
+		}
+		return nil, err
+	}
+	r.rowLine, _ = r.r.FieldPos(0)
+
+	record, err := r.record(row)
+	if err != nil {
+		return nil, &adif.ParseError{Offset: r.rowOffset, Line: r.rowLine, Err: err}
+	}
+
+	return record, nil
+}
+
+// record maps the row to adif fields, converting dates and times to adif format
+func (r *Reader) record(row []string) (adif.Record, error) {
+	record := adif.Record{}
+
+	for i, c := range r.m.Columns {
+		if r.index[i] >= len(row) {
+			continue
+		}
+
+		v := strings.TrimSpace(row[r.index[i]])
+		if v == "" {
+			continue
+		}
+
+		if c.Format != "" {
+			t, err := time.Parse(c.Format, v)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", c.Heading, err)
+			}
+			v = t.Format(adifLayout(c.Field))
+		}
+
+		record.Set(c.Field, v)
+	}
+
+	return record, nil
+}
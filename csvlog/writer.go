@@ -0,0 +1,84 @@
+package csvlog
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+
+	"github.com/bbathe/golog/adif"
+	"github.com/bbathe/golog/models/qso"
+)
+
+// Writer writes ADIF records as CSV rows, implements adif.RecordWriter
+type Writer struct {
+	w *csv.Writer
+	m Mapping
+}
+
+// NewWriter returns a Writer that writes to w using mapping m
+func NewWriter(w io.Writer, m Mapping) *Writer {
+	cw := csv.NewWriter(w)
+	cw.Comma = m.comma()
+
+	return &Writer{
+		w: cw,
+		m: m,
+	}
+}
+
+// WriteHeader writes the heading row if the mapping has one, the adif header has nowhere to go
+func (w *Writer) WriteHeader(_ adif.Header) error {
+	if !w.m.HeadingRow {
+		return nil
+	}
+
+	headings := make([]string, 0, len(w.m.Columns))
+	for _, c := range w.m.Columns {
+		headings = append(headings, c.Heading)
+	}
+
+	return w.w.Write(headings)
+}
+
+// WriteRecord writes the record as a row, converting dates and times from adif format
+func (w *Writer) WriteRecord(r adif.Record) error {
+	row := make([]string, 0, len(w.m.Columns))
+
+	for _, c := range w.m.Columns {
+		v, _ := r.Get(c.Field)
+
+		if v != "" && c.Format != "" {
+			// adif times can be with or without seconds
+			layout := adifLayout(c.Field)
+			if layout == "150405" && len(v) == 4 {
+				layout = "1504"
+			}
+
+			t, err := time.Parse(layout, v)
+			if err != nil {
+				return err
+			}
+			v = t.Format(c.Format)
+		}
+
+		row = append(row, v)
+	}
+
+	return w.w.Write(row)
+}
+
+// WriteQSO writes the qso as a row
+func (w *Writer) WriteQSO(q qso.QSO) error {
+	r, err := adif.QSOToRecord(q)
+	if err != nil {
+		return err
+	}
+
+	return w.WriteRecord(r)
+}
+
+// Close flushes the Writer, it does not close the underlying io.Writer
+func (w *Writer) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
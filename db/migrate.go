@@ -0,0 +1,218 @@
+package db
+
+import (
+	"embed"
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/util"
+)
+
+// migrations to the qso database schema, named <version>_<description>.sql
+// versions are applied in order and never change once released, add a new one instead
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migration is one step up in the schema version
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// number of pre-migration backups to keep
+const keepMigrationBackups = 5
+
+// loadMigrations returns the embedded migrations ordered by version
+func loadMigrations() ([]migration, error) {
+	files, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(files))
+	for _, f := range files {
+		v, _, _ := strings.Cut(f.Name(), "_")
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			err = fmt.Errorf("migration %s doesn't start with a version number", f.Name())
+			log.Printf("%+v", err)
+			return nil, err
+		}
+
+		bs, err := migrationFiles.ReadFile(path.Join("migrations", f.Name()))
+		if err != nil {
+			log.Printf("%+v", err)
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{
+			version: version,
+			name:    f.Name(),
+			sql:     string(bs),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	// versions have to be 1, 2, 3...
+	for i, m := range migrations {
+		if m.version != i+1 {
+			err = fmt.Errorf("migration %s is out of sequence", m.name)
+			log.Printf("%+v", err)
+			return nil, err
+		}
+	}
+
+	return migrations, nil
+}
+
+// schemaVersion returns the version of the qso database schema, 0 for an empty database
+func schemaVersion() (int, error) {
+	_, err := QSODb.Exec(`
+		create table if not exists schema_version (
+			version integer primary key not null,
+			applied_at text not null
+		)
+	`)
+	if err != nil {
+		log.Printf("%+v", err)
+		return 0, err
+	}
+
+	var version int
+	err = QSODb.Get(&version, "select coalesce(max(version), 0) from schema_version")
+	if err != nil {
+		log.Printf("%+v", err)
+		return 0, err
+	}
+	if version > 0 {
+		return version, nil
+	}
+
+	// databases from before migrations have the qsos table but no version, they are the baseline
+	var tables int
+	err = QSODb.Get(&tables, "select count(*) from sqlite_master where type = 'table' and name = 'qsos'")
+	if err != nil {
+		log.Printf("%+v", err)
+		return 0, err
+	}
+	if tables > 0 {
+		_, err = QSODb.Exec("insert into schema_version (version, applied_at) values (1, ?)", time.Now().UTC().Format(time.RFC3339))
+		if err != nil {
+			log.Printf("%+v", err)
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+// backupQSODb copies the qso database to the backup directory before it's migrated from version
+func backupQSODb(version int) error {
+	prefix := "BackupQSODb-"
+	fname := filepath.Join(config.BackupDirectory, fmt.Sprintf("%sv%d-%s.db", prefix, version, time.Now().UTC().Format("2006-Jan-02_15-04-05")))
+
+	// vacuum into makes a consistent copy thru the open connection
+	_, err := QSODb.Exec("vacuum into ?", fname)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = util.DeleteHistoricalFiles(keepMigrationBackups, config.BackupDirectory, prefix, ".db")
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyMigration runs the migration and records its version in a single transaction
+func applyMigration(m migration) error {
+	tx, err := QSODb.Beginx()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	_, err = tx.Exec(m.sql)
+	if err != nil {
+		err = fmt.Errorf("migration %s: %w", m.name, err)
+		log.Printf("%+v", err)
+		_ = tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("insert into schema_version (version, applied_at) values (?, ?)", m.version, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		log.Printf("%+v", err)
+		_ = tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// migrateQSODb brings the qso database schema up to the latest version
+func migrateQSODb() error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	version, err := schemaVersion()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	if version > len(migrations) {
+		err = fmt.Errorf("qso database schema version %d is newer than this program supports (%d)", version, len(migrations))
+		log.Printf("%+v", err)
+		return err
+	}
+	if version == len(migrations) {
+		return nil
+	}
+
+	// nothing to lose in a new database
+	if version > 0 {
+		err = backupQSODb(version)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	for _, m := range migrations[version:] {
+		err = applyMigration(m)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+		log.Printf("qso database migrated to version %d (%s)", m.version, m.name)
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+-- qsos table as created by the first releases
+create table qsos (
+	id integer primary key asc not null,
+	loaded_at integer not null,
+	station_callsign text not null,
+	band text not null,
+	call text not null,
+	mode text not null,
+	qso_date text not null,
+	qso_time text not null,
+	rst_rcvd text null,
+	rst_sent text null,
+	qsl_lotw integer not null default 0 check (qsl_lotw in (0, 1)),
+	qsl_qrz integer not null default 0 check (qsl_qrz in (0, 1)),
+	qsl_clublog integer not null default 0 check (qsl_clublog in (0, 1)),
+	qsl_card integer not null default 0 check (qsl_card in (0, 1))
+);
+
+-- what uniquely defines a qso
+create unique index unique_qso on qsos(station_callsign, band, call, mode, qso_date, qso_time);
+
+-- quering by loaded date/time
+create index loaded_at on qsos(loaded_at);
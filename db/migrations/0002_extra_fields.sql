@@ -0,0 +1,10 @@
+-- adif fields without a column of their own
+alter table qsos add column extra_fields text null;
+
+-- user-defined fields declared in imported adif headers
+create table userdefs (
+	id integer primary key asc not null,
+	name text not null unique collate nocase,
+	indicator text null,
+	field_values text null
+);
@@ -0,0 +1,4 @@
+-- times are kept with seconds, time off is empty when unknown
+update qsos set qso_time = qso_time || ':00' where length(qso_time) = 5;
+
+alter table qsos add column qso_time_off text not null default '';
@@ -0,0 +1,4 @@
+-- frequencies are in Hz, power in watts
+alter table qsos add column freq integer not null default 0;
+alter table qsos add column freq_rx integer not null default 0;
+alter table qsos add column tx_pwr real not null default 0;
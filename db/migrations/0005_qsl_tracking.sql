@@ -0,0 +1,24 @@
+-- when qsls were sent & received for each service, dates are yyyy-mm-dd and empty when unknown
+-- received is the adif qsl_rcvd enumeration
+alter table qsos add column qsl_lotw_sdate text not null default '';
+alter table qsos add column qsl_lotw_rcvd text not null default 'N';
+alter table qsos add column qsl_lotw_rdate text not null default '';
+
+alter table qsos add column qsl_qrz_sdate text not null default '';
+alter table qsos add column qsl_qrz_rcvd text not null default 'N';
+alter table qsos add column qsl_qrz_rdate text not null default '';
+
+alter table qsos add column qsl_clublog_sdate text not null default '';
+alter table qsos add column qsl_clublog_rcvd text not null default 'N';
+alter table qsos add column qsl_clublog_rdate text not null default '';
+
+alter table qsos add column qsl_eqsl_sdate text not null default '';
+alter table qsos add column qsl_eqsl_rcvd text not null default 'N';
+alter table qsos add column qsl_eqsl_rdate text not null default '';
+
+-- paper cards also track how they went, the adif qsl_via enumeration
+alter table qsos add column qsl_card_sdate text not null default '';
+alter table qsos add column qsl_card_sent_via text not null default '';
+alter table qsos add column qsl_card_rcvd text not null default 'N';
+alter table qsos add column qsl_card_rdate text not null default '';
+alter table qsos add column qsl_card_rcvd_via text not null default '';
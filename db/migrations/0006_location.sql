@@ -0,0 +1,5 @@
+-- where the contacted station is, zero or empty when unknown
+alter table qsos add column dxcc integer not null default 0;
+alter table qsos add column gridsquare text not null default '';
+alter table qsos add column cqz integer not null default 0;
+alter table qsos add column ituz integer not null default 0;
@@ -0,0 +1,19 @@
+-- id of the qso in the QRZ.com logbook, zero when not known
+alter table qsos add column qrz_logid integer not null default 0;
+
+-- changes to make to logbook services for qsos that were edited or deleted after being uploaded
+-- the qso's values are from before the change, the qso itself may be gone
+create table corrections (
+	id integer primary key asc not null,
+	created_at integer not null,
+	service text not null,
+	action text not null,
+	qso_id integer not null,
+	station_callsign text not null,
+	call text not null,
+	band text not null,
+	mode text not null,
+	qso_date text not null,
+	qso_time text not null,
+	qrz_logid integer not null default 0
+);
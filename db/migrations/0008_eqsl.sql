@@ -0,0 +1,2 @@
+-- eqsl.cc is a logbook service qsls are sent to
+alter table qsos add column qsl_eqsl integer not null default 0 check (qsl_eqsl in (0, 1));
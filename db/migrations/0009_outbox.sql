@@ -0,0 +1,24 @@
+-- every attempt at uploading a qso to a logbook service
+-- error_class is empty when the upload worked
+create table upload_attempts (
+	id integer primary key asc not null,
+	qso_id integer not null,
+	service text not null,
+	attempted_at integer not null,
+	error_class text not null default '',
+	response text not null default ''
+);
+create index upload_attempts_qso on upload_attempts (qso_id, service);
+
+-- qsos that failed to upload, waiting to be retried or rejected by the service
+create table outbox (
+	qso_id integer not null,
+	service text not null,
+	state text not null check (state in ('retrying', 'rejected')),
+	attempts integer not null default 0,
+	last_attempt_at integer not null default 0,
+	next_attempt_at integer not null default 0,
+	error_class text not null default '',
+	response text not null default '',
+	primary key (qso_id, service)
+);
@@ -0,0 +1,14 @@
+-- every change made to a qso, who made it and the whole qso before & after as json
+-- old_values is empty for inserts and new_values for deletes, undone changes can be redone
+create table audit (
+	id integer primary key asc not null,
+	qso_id integer not null,
+	changed_at integer not null,
+	source text not null,
+	action text not null check (action in ('insert', 'update', 'delete', 'qsl')),
+	old_values text not null default '',
+	new_values text not null default '',
+	undone integer not null default 0 check (undone in (0, 1))
+);
+create index audit_qso on audit (qso_id);
+create index audit_source on audit (source, undone);
@@ -0,0 +1,5 @@
+-- corrections the service couldn't make are retried with backoff like the outbox
+alter table corrections add column attempts integer not null default 0;
+alter table corrections add column next_attempt_at integer not null default 0;
+alter table corrections add column error_class text not null default '';
+alter table corrections add column response text not null default '';
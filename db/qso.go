@@ -46,6 +46,17 @@ func OpenQSODb() error {
 		return err
 	}
 
+	// bring schema up to date, new databases get created here too
+	err = migrateQSODb()
+	if err != nil {
+		log.Printf("%+v", err)
+		cerr := CloseQSODb()
+		if cerr != nil {
+			log.Printf("%+v", cerr)
+		}
+		return err
+	}
+
 	return nil
 }
 
@@ -84,54 +95,12 @@ func NewQSODb() error {
 	}
 	d.Close()
 
-	// open new database
+	// open new database, migrations create the schema
 	err = OpenQSODb()
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
 	}
 
-	// create qso table
-	_, err = QSODb.Exec(`
-		create table qsos (
-			id integer primary key asc not null,
-			loaded_at integer not null,
-			station_callsign text not null,
-			band text not null,
-			call text not null,
-			mode text not null,
-			qso_date text not null,
-			qso_time text not null,
-			rst_rcvd text null,
-			rst_sent text null,
-			qsl_lotw integer not null default 0 check (qsl_lotw in (0, 1)),
-			qsl_qrz integer not null default 0 check (qsl_qrz in (0, 1)),
-			qsl_clublog integer not null default 0 check (qsl_clublog in (0, 1)),
-			qsl_card integer not null default 0 check (qsl_card in (0, 1))
-		)
-	`)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-
-	// index for what uniquely defines a qso
-	_, err = QSODb.Exec(`
-		create unique index unique_qso on qsos(station_callsign, band, call, mode, qso_date, qso_time)
-	`)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-
-	// index for quering by loaded date/time
-	_, err = QSODb.Exec(`
-		create index loaded_at on qsos(loaded_at)
-	`)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-
 	return nil
 }
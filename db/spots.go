@@ -70,7 +70,12 @@ func NewSpotDb() error {
 			band text null,
 			frequency text null,
 			comments text null,
-			spotter text null
+			spotter text null,
+			dxcc integer not null default 0,
+			country text not null default '',
+			continent text not null default '',
+			cqz integer not null default 0,
+			ituz integer not null default 0
 		)
 	`)
 	if err != nil {
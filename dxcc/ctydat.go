@@ -0,0 +1,143 @@
+package dxcc
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadCtyDat reads a BigCTY cty.dat country file
+// each entity is a header line of name:cq:itu:continent:lat:lon:utc offset:primary prefix: followed by its prefixes up to a ;
+// =call is a whole call, (cq) [itu] <lat/lon> {continent} & ~utc offset~ override the entity's values
+// entities with a primary prefix starting with * aren't DXCC entities so they're skipped
+func (r *Resolver) ReadCtyDat(rdr io.Reader) error {
+	b, err := io.ReadAll(rdr)
+	if err != nil {
+		return err
+	}
+
+	t := newTable()
+
+	for _, record := range strings.Split(string(b), ";") {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+
+		fields := strings.Split(record, ":")
+		if len(fields) != 9 {
+			return fmt.Errorf("invalid entity %q", strings.TrimSpace(record))
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		if strings.HasPrefix(fields[7], "*") {
+			continue
+		}
+
+		e := Entity{
+			Name:      fields[0],
+			Prefix:    strings.ToUpper(fields[7]),
+			Continent: fields[3],
+		}
+
+		var lon float64
+		for i, v := range []*int{&e.CQZone, &e.ITUZone} {
+			*v, err = strconv.Atoi(fields[i+1])
+			if err != nil {
+				return fmt.Errorf("invalid zone for %s: %w", e.Name, err)
+			}
+		}
+		for i, v := range []*float64{&e.Latitude, &lon} {
+			*v, err = strconv.ParseFloat(fields[i+4], 64)
+			if err != nil {
+				return fmt.Errorf("invalid location for %s: %w", e.Name, err)
+			}
+		}
+
+		// cty.dat has west longitudes positive
+		e.Longitude = -lon
+
+		for _, alias := range strings.Split(fields[8], ",") {
+			alias = strings.TrimSpace(alias)
+			if alias == "" {
+				continue
+			}
+
+			prefix, ae, err := parseAlias(alias, e)
+			if err != nil {
+				return fmt.Errorf("invalid prefix %s for %s: %w", alias, e.Name, err)
+			}
+
+			if strings.HasPrefix(prefix, "=") {
+				call := prefix[1:]
+				t.calls[call] = append(t.calls[call], rule{entity: ae})
+			} else {
+				t.addPrefix(prefix, rule{entity: ae})
+			}
+		}
+	}
+
+	r.add(t)
+	return nil
+}
+
+// closing characters of the overrides a prefix can have
+var overrides = map[byte]byte{
+	'(': ')',
+	'[': ']',
+	'<': '>',
+	'{': '}',
+	'~': '~',
+}
+
+// parseAlias splits a cty.dat prefix from its overrides, returning it & the entity with them applied
+func parseAlias(alias string, e Entity) (string, Entity, error) {
+	i := strings.IndexAny(alias, "([<{~")
+	if i < 0 {
+		return strings.ToUpper(alias), e, nil
+	}
+
+	prefix := strings.ToUpper(alias[:i])
+	rest := alias[i:]
+	for rest != "" {
+		closing, ok := overrides[rest[0]]
+		if !ok {
+			return "", e, fmt.Errorf("unexpected %q", rest)
+		}
+
+		j := strings.IndexByte(rest[1:], closing)
+		if j < 0 {
+			return "", e, fmt.Errorf("missing %q", closing)
+		}
+		v := rest[1 : j+1]
+
+		var err error
+		switch rest[0] {
+		case '(':
+			e.CQZone, err = strconv.Atoi(v)
+		case '[':
+			e.ITUZone, err = strconv.Atoi(v)
+		case '<':
+			lat, lon, found := strings.Cut(v, "/")
+			if !found {
+				return "", e, fmt.Errorf("invalid location %q", v)
+			}
+			e.Latitude, err = strconv.ParseFloat(lat, 64)
+			if err == nil {
+				e.Longitude, err = strconv.ParseFloat(lon, 64)
+				e.Longitude = -e.Longitude
+			}
+		case '{':
+			e.Continent = v
+		}
+		if err != nil {
+			return "", e, err
+		}
+
+		rest = rest[j+2:]
+	}
+
+	return prefix, e, nil
+}
@@ -0,0 +1,158 @@
+package dxcc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ctyxml is the layout of the Club Log cty.xml country file
+type ctyxml struct {
+	XMLName  xml.Name `xml:"clublog"`
+	Entities []struct {
+		ADIF    int     `xml:"adif"`
+		Name    string  `xml:"name"`
+		Prefix  string  `xml:"prefix"`
+		Deleted bool    `xml:"deleted"`
+		CQZ     int     `xml:"cqz"`
+		Cont    string  `xml:"cont"`
+		Long    float64 `xml:"long"`
+		Lat     float64 `xml:"lat"`
+	} `xml:"entities>entity"`
+	Exceptions []ctyxmlcall `xml:"exceptions>exception"`
+	Prefixes   []ctyxmlcall `xml:"prefixes>prefix"`
+	Invalid    []struct {
+		Call  string `xml:"call"`
+		Start string `xml:"start"`
+		End   string `xml:"end"`
+	} `xml:"invalid_operations>invalid"`
+	ZoneExceptions []struct {
+		Call  string `xml:"call"`
+		Zone  int    `xml:"zone"`
+		Start string `xml:"start"`
+		End   string `xml:"end"`
+	} `xml:"zone_exceptions>zone_exception"`
+}
+
+// ctyxmlcall is a whole call exception or a prefix
+type ctyxmlcall struct {
+	Call   string  `xml:"call"`
+	Entity string  `xml:"entity"`
+	ADIF   int     `xml:"adif"`
+	CQZ    int     `xml:"cqz"`
+	Cont   string  `xml:"cont"`
+	Long   float64 `xml:"long"`
+	Lat    float64 `xml:"lat"`
+	Start  string  `xml:"start"`
+	End    string  `xml:"end"`
+}
+
+// period parses the start & end times, empty for open ended
+func period(start, end string) (time.Time, time.Time, error) {
+	var s, e time.Time
+	var err error
+
+	if start != "" {
+		s, err = time.Parse(time.RFC3339, start)
+		if err != nil {
+			return s, e, err
+		}
+	}
+	if end != "" {
+		e, err = time.Parse(time.RFC3339, end)
+		if err != nil {
+			return s, e, err
+		}
+	}
+
+	return s, e, nil
+}
+
+// ReadCtyXML reads a Club Log cty.xml country file
+// exceptions, prefixes, invalid operations & zone exceptions all apply between their start & end times
+func (r *Resolver) ReadCtyXML(rdr io.Reader) error {
+	var c ctyxml
+	err := xml.NewDecoder(rdr).Decode(&c)
+	if err != nil {
+		return err
+	}
+
+	t := newTable()
+
+	// primary prefixes of the entities, cty.dat entities get their codes by name or prefix
+	prefixes := map[int]string{}
+	for _, e := range c.Entities {
+		prefixes[e.ADIF] = strings.ToUpper(e.Prefix)
+		if e.Deleted {
+			continue
+		}
+		t.codes[strings.ToUpper(e.Name)] = e.ADIF
+		t.codes[strings.ToUpper(e.Prefix)] = e.ADIF
+	}
+
+	toRule := func(x ctyxmlcall) (rule, error) {
+		start, end, err := period(x.Start, x.End)
+		if err != nil {
+			return rule{}, fmt.Errorf("invalid time for %s: %w", x.Call, err)
+		}
+
+		return rule{
+			entity: Entity{
+				DXCC:      x.ADIF,
+				Name:      x.Entity,
+				Prefix:    prefixes[x.ADIF],
+				Continent: x.Cont,
+				CQZone:    x.CQZ,
+				Latitude:  x.Lat,
+				Longitude: x.Long,
+			},
+			start: start,
+			end:   end,
+		}, nil
+	}
+
+	for _, x := range c.Exceptions {
+		rl, err := toRule(x)
+		if err != nil {
+			return err
+		}
+		call := strings.ToUpper(x.Call)
+		t.calls[call] = append(t.calls[call], rl)
+	}
+
+	for _, x := range c.Prefixes {
+		// prefixes that aren't in an entity are left to the shorter ones
+		if x.ADIF == 0 {
+			continue
+		}
+
+		rl, err := toRule(x)
+		if err != nil {
+			return err
+		}
+		t.addPrefix(strings.ToUpper(x.Call), rl)
+	}
+
+	for _, x := range c.Invalid {
+		start, end, err := period(x.Start, x.End)
+		if err != nil {
+			return fmt.Errorf("invalid time for %s: %w", x.Call, err)
+		}
+		call := strings.ToUpper(x.Call)
+		t.invalid[call] = append(t.invalid[call], rule{start: start, end: end})
+	}
+
+	for _, x := range c.ZoneExceptions {
+		start, end, err := period(x.Start, x.End)
+		if err != nil {
+			return fmt.Errorf("invalid time for %s: %w", x.Call, err)
+		}
+		call := strings.ToUpper(x.Call)
+		t.zones[call] = append(t.zones[call], zoneRule{zone: x.Zone, start: start, end: end})
+	}
+
+	r.add(t)
+	return nil
+}
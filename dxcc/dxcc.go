@@ -0,0 +1,353 @@
+package dxcc
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entity is the DXCC entity a callsign is in and where it is
+type Entity struct {
+	// adif entity code, zero when not known, cty.dat doesn't have them
+	DXCC int `json:"dxcc"`
+
+	Name      string `json:"name"`
+	Prefix    string `json:"prefix"`
+	Continent string `json:"continent"`
+
+	// zero when not known, cty.xml doesn't have itu zones
+	CQZone  int `json:"cqz"`
+	ITUZone int `json:"ituz"`
+
+	// degrees, north & east are positive
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lon"`
+}
+
+// rule resolves calls to an entity between start & end, zero times are open ended
+type rule struct {
+	entity Entity
+	start  time.Time
+	end    time.Time
+}
+
+func (r rule) valid(at time.Time) bool {
+	return (r.start.IsZero() || !at.Before(r.start)) && (r.end.IsZero() || !at.After(r.end))
+}
+
+// zoneRule overrides the cq zone of a call between start & end
+type zoneRule struct {
+	zone  int
+	start time.Time
+	end   time.Time
+}
+
+// table is what was read from one country file
+type table struct {
+	// whole calls, then the longest matching prefix
+	calls     map[string][]rule
+	prefixes  map[string][]rule
+	maxPrefix int
+
+	// calls that don't count for any entity & calls in a different cq zone, cty.xml only
+	invalid map[string][]rule
+	zones   map[string][]zoneRule
+
+	// adif entity codes by upper case name & primary prefix, cty.xml only
+	codes map[string]int
+}
+
+func newTable() *table {
+	return &table{
+		calls:    map[string][]rule{},
+		prefixes: map[string][]rule{},
+		invalid:  map[string][]rule{},
+		zones:    map[string][]zoneRule{},
+		codes:    map[string]int{},
+	}
+}
+
+func (t *table) addPrefix(prefix string, r rule) {
+	t.prefixes[prefix] = append(t.prefixes[prefix], r)
+	if len(prefix) > t.maxPrefix {
+		t.maxPrefix = len(prefix)
+	}
+}
+
+// find returns the first rule valid at that time
+func find(rules []rule, at time.Time) (Entity, bool) {
+	for _, r := range rules {
+		if r.valid(at) {
+			return r.entity, true
+		}
+	}
+	return Entity{}, false
+}
+
+// resolve returns the entity for call, already split by splitCall
+func (t *table) resolve(call, lookup string, whole bool, at time.Time) (Entity, bool) {
+	e, ok := find(t.calls[call], at)
+	if !ok && whole {
+		e, ok = find(t.calls[lookup], at)
+	}
+
+	// longest prefix
+	for l := min(len(lookup), t.maxPrefix); !ok && l > 0; l-- {
+		e, ok = find(t.prefixes[lookup[:l]], at)
+	}
+	if !ok {
+		return Entity{}, false
+	}
+
+	for _, z := range t.zones[call] {
+		if (rule{start: z.start, end: z.end}).valid(at) {
+			e.CQZone = z.zone
+			break
+		}
+	}
+
+	return e, true
+}
+
+// Resolver finds the DXCC entity of callsigns from country files
+// tables read first take precedence, later ones only fill in what they didn't know
+type Resolver struct {
+	tables []*table
+}
+
+// NewResolver returns a Resolver without any country files
+func NewResolver() *Resolver {
+	return &Resolver{}
+}
+
+// ReadFile reads a country file, Club Log cty.xml when the name ends in .xml (or .xml.gz), otherwise BigCTY cty.dat
+func (r *Resolver) ReadFile(fname string) error {
+	// #nosec G304
+	file, err := os.Open(fname)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer file.Close()
+
+	var rdr io.Reader = file
+
+	name := strings.ToLower(fname)
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+		defer gz.Close()
+
+		rdr = gz
+		name = strings.TrimSuffix(name, ".gz")
+	}
+
+	if filepath.Ext(name) == ".xml" {
+		err = r.ReadCtyXML(rdr)
+	} else {
+		err = r.ReadCtyDat(rdr)
+	}
+	if err != nil {
+		err = fmt.Errorf("%s: %w", filepath.Base(fname), err)
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// add appends a table and gives cty.dat entities the adif codes from cty.xml
+func (r *Resolver) add(t *table) {
+	r.tables = append(r.tables, t)
+
+	codes := map[string]int{}
+	for _, t := range r.tables {
+		for k, v := range t.codes {
+			if _, ok := codes[k]; !ok {
+				codes[k] = v
+			}
+		}
+	}
+	if len(codes) == 0 {
+		return
+	}
+
+	code := func(e *Entity) {
+		if e.DXCC != 0 {
+			return
+		}
+		if c, ok := codes[strings.ToUpper(e.Name)]; ok {
+			e.DXCC = c
+		} else if c, ok := codes[e.Prefix]; ok {
+			e.DXCC = c
+		}
+	}
+	for _, t := range r.tables {
+		for _, rules := range []map[string][]rule{t.calls, t.prefixes} {
+			for _, rs := range rules {
+				for i := range rs {
+					code(&rs[i].entity)
+				}
+			}
+		}
+	}
+}
+
+// portable designators that don't change the entity
+var designators = map[string]bool{
+	"P":    true,
+	"M":    true,
+	"A":    true,
+	"B":    true,
+	"J":    true,
+	"LH":   true,
+	"QRP":  true,
+	"QRPP": true,
+}
+
+// splitCall returns the call cleaned up, the part of it to look up & whether that's the whole call
+// false when the call can't be in an entity, maritime & aeronautical mobile
+func splitCall(call string) (string, string, bool, bool) {
+	call = strings.ToUpper(strings.TrimSpace(call))
+
+	var parts []string
+	for _, p := range strings.Split(call, "/") {
+		switch {
+		case p == "MM" || p == "AM":
+			return call, "", false, false
+		case p == "" || designators[p]:
+			continue
+		}
+		parts = append(parts, p)
+	}
+
+	switch len(parts) {
+	case 0:
+		return call, "", false, false
+	case 1:
+		return call, parts[0], true, true
+	}
+
+	base, other := parts[0], parts[1]
+	if len(other) > len(base) {
+		base, other = other, base
+	}
+
+	// a call area, K1ABC/4 is looked up as K4ABC
+	if len(other) == 1 && other[0] >= '0' && other[0] <= '9' {
+		i := strings.IndexAny(base, "0123456789")
+		if i < 0 {
+			return call, base, false, true
+		}
+		j := i
+		for j < len(base) && base[j] >= '0' && base[j] <= '9' {
+			j++
+		}
+		return call, base[:i] + other + base[j:], false, true
+	}
+
+	// the shorter part is the prefix, DL/K1ABC or K1ABC/VE3
+	return call, other, false, true
+}
+
+// Resolve returns the entity call was in at that time, false if it can't be resolved
+func (r *Resolver) Resolve(call string, at time.Time) (Entity, bool) {
+	call, lookup, whole, ok := splitCall(call)
+	if !ok {
+		return Entity{}, false
+	}
+
+	// an invalid operation doesn't count for any entity, whatever the other files say
+	for _, t := range r.tables {
+		if _, invalid := find(t.invalid[call], at); invalid {
+			return Entity{}, false
+		}
+	}
+
+	var found Entity
+	ok = false
+	for _, t := range r.tables {
+		e, matched := t.resolve(call, lookup, whole, at)
+		if !matched {
+			continue
+		}
+		if !ok {
+			found, ok = e, true
+			continue
+		}
+
+		// later tables only add to the same entity
+		if e.DXCC != 0 && found.DXCC != 0 && e.DXCC != found.DXCC {
+			continue
+		}
+		if found.DXCC == 0 {
+			found.DXCC = e.DXCC
+		}
+		if found.Continent == "" {
+			found.Continent = e.Continent
+		}
+		if found.CQZone == 0 {
+			found.CQZone = e.CQZone
+		}
+		if found.ITUZone == 0 {
+			found.ITUZone = e.ITUZone
+		}
+	}
+
+	return found, ok
+}
+
+// the country files in use
+var (
+	mutex    sync.RWMutex
+	resolver *Resolver
+)
+
+// Load reads the country files, replacing the ones in use, files that don't exist are skipped
+// files earlier in the list take precedence
+func Load(fnames ...string) error {
+	r := NewResolver()
+
+	for _, fname := range fnames {
+		_, err := os.Stat(fname)
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		err = r.ReadFile(fname)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	resolver = r
+	if len(r.tables) == 0 {
+		resolver = nil
+	}
+
+	return nil
+}
+
+// Lookup returns the entity call was in at that time using the country files loaded, false if it can't be resolved
+func Lookup(call string, at time.Time) (Entity, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if resolver == nil {
+		return Entity{}, false
+	}
+	return resolver.Resolve(call, at)
+}
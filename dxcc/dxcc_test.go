@@ -0,0 +1,401 @@
+package dxcc
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCtyDat = `Hawaii:                   31:  61:  OC:   21.12:   157.48:    10.0:  KH6:
+    AH6,AH7,KH6,KH7,NH6,WH6;
+Canada:                   05:  09:  NA:   44.35:    78.75:     5.0:  VE:
+    VA,VE,VO,VY,=VE3ABC(4)[4]<45.00/75.00>,VE8(1)[2]{NA};
+United States:            05:  08:  NA:   37.53:    91.67:     5.0:  K:
+    AA,K,N,W,K6(3)[6],W6(3)[6];
+Sov Mil Order of Malta:   15:  28:  EU:   41.90:   -12.43:    -1.0:  *1A:
+    1A;
+`
+
+const testCtyXML = `<?xml version="1.0" encoding="UTF-8"?>
+<clublog date="2024-03-01T00:00:00+00:00" xmlns="https://clublog.org/cty/v1.2">
+<entities>
+<entity><adif>110</adif><name>HAWAII</name><prefix>KH6</prefix><deleted>false</deleted><cqz>31</cqz><cont>OC</cont><long>-157.48</long><lat>21.12</lat></entity>
+<entity><adif>291</adif><name>UNITED STATES OF AMERICA</name><prefix>K</prefix><deleted>false</deleted><cqz>5</cqz><cont>NA</cont><long>-91.67</long><lat>37.53</lat></entity>
+<entity><adif>1</adif><name>CANADA</name><prefix>VE</prefix><deleted>false</deleted><cqz>5</cqz><cont>NA</cont><long>-78.75</long><lat>44.35</lat></entity>
+</entities>
+<exceptions>
+<exception record="1"><call>K1XYZ</call><entity>HAWAII</entity><adif>110</adif><cqz>31</cqz><cont>OC</cont><long>-157.48</long><lat>21.12</lat><start>2020-01-01T00:00:00+00:00</start><end>2020-12-31T23:59:59+00:00</end></exception>
+</exceptions>
+<prefixes>
+<prefix record="1"><call>KH6</call><entity>HAWAII</entity><adif>110</adif><cqz>31</cqz><cont>OC</cont><long>-157.48</long><lat>21.12</lat></prefix>
+<prefix record="2"><call>K</call><entity>UNITED STATES OF AMERICA</entity><adif>291</adif><cqz>5</cqz><cont>NA</cont><long>-91.67</long><lat>37.53</lat></prefix>
+<prefix record="3"><call>VE</call><entity>CANADA</entity><adif>1</adif><cqz>5</cqz><cont>NA</cont><long>-78.75</long><lat>44.35</lat></prefix>
+<prefix record="4"><call>KH6X</call><entity>INVALID</entity><adif>0</adif></prefix>
+</prefixes>
+<invalid_operations>
+<invalid record="1"><call>K1BAD</call><start>2021-01-01T00:00:00+00:00</start></invalid>
+</invalid_operations>
+<zone_exceptions>
+<zone_exception record="1"><call>K1ZON</call><zone>4</zone><start>2020-01-01T00:00:00+00:00</start><end>2020-12-31T23:59:59+00:00</end></zone_exception>
+</zone_exceptions>
+</clublog>
+`
+
+func TestSplitCall(t *testing.T) {
+	tests := []struct {
+		call       string
+		wantCall   string
+		wantLookup string
+		wantWhole  bool
+		wantOK     bool
+	}{
+		{call: "k1abc", wantCall: "K1ABC", wantLookup: "K1ABC", wantWhole: true, wantOK: true},
+		{call: " K1ABC/P ", wantCall: "K1ABC/P", wantLookup: "K1ABC", wantWhole: true, wantOK: true},
+		{call: "K1ABC/QRP/P", wantCall: "K1ABC/QRP/P", wantLookup: "K1ABC", wantWhole: true, wantOK: true},
+		{call: "K1ABC/4", wantCall: "K1ABC/4", wantLookup: "K4ABC", wantOK: true},
+		{call: "4/K1ABC", wantCall: "4/K1ABC", wantLookup: "K4ABC", wantOK: true},
+		{call: "WA12ABC/0", wantCall: "WA12ABC/0", wantLookup: "WA0ABC", wantOK: true},
+		{call: "K1ABC/4/P", wantCall: "K1ABC/4/P", wantLookup: "K4ABC", wantOK: true},
+		{call: "ABC/4", wantCall: "ABC/4", wantLookup: "ABC", wantOK: true},
+		{call: "KH6/K1ABC", wantCall: "KH6/K1ABC", wantLookup: "KH6", wantOK: true},
+		{call: "K1ABC/VE3", wantCall: "K1ABC/VE3", wantLookup: "VE3", wantOK: true},
+		{call: "VP2E/K1ABC/P", wantCall: "VP2E/K1ABC/P", wantLookup: "VP2E", wantOK: true},
+		{call: "K1ABC/MM", wantCall: "K1ABC/MM"},
+		{call: "k1abc/am", wantCall: "K1ABC/AM"},
+		{call: "MM/K1ABC", wantCall: "MM/K1ABC"},
+		{call: "/P", wantCall: "/P"},
+		{call: "", wantCall: ""},
+	}
+
+	for _, tt := range tests {
+		call, lookup, whole, ok := splitCall(tt.call)
+		if call != tt.wantCall || lookup != tt.wantLookup || whole != tt.wantWhole || ok != tt.wantOK {
+			t.Errorf("splitCall(%q) = %q, %q, %v, %v, want %q, %q, %v, %v",
+				tt.call, call, lookup, whole, ok, tt.wantCall, tt.wantLookup, tt.wantWhole, tt.wantOK)
+		}
+	}
+}
+
+func newResolver(t *testing.T, ctydat, ctyxml bool, xmlFirst bool) *Resolver {
+	t.Helper()
+
+	r := NewResolver()
+	read := func(xml bool) {
+		var err error
+		if xml {
+			err = r.ReadCtyXML(strings.NewReader(testCtyXML))
+		} else {
+			err = r.ReadCtyDat(strings.NewReader(testCtyDat))
+		}
+		if err != nil {
+			t.Fatalf("reading country file: %v", err)
+		}
+	}
+
+	if ctyxml && xmlFirst {
+		read(true)
+	}
+	if ctydat {
+		read(false)
+	}
+	if ctyxml && !xmlFirst {
+		read(true)
+	}
+
+	return r
+}
+
+func TestResolveCtyDat(t *testing.T) {
+	r := newResolver(t, true, false, false)
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	us := Entity{Name: "United States", Prefix: "K", Continent: "NA", CQZone: 5, ITUZone: 8, Latitude: 37.53, Longitude: -91.67}
+	hawaii := Entity{Name: "Hawaii", Prefix: "KH6", Continent: "OC", CQZone: 31, ITUZone: 61, Latitude: 21.12, Longitude: -157.48}
+
+	west := us
+	west.CQZone, west.ITUZone = 3, 6
+
+	ve3abc := Entity{Name: "Canada", Prefix: "VE", Continent: "NA", CQZone: 4, ITUZone: 4, Latitude: 45, Longitude: -75}
+
+	ve8 := Entity{Name: "Canada", Prefix: "VE", Continent: "NA", CQZone: 1, ITUZone: 2, Latitude: 44.35, Longitude: -78.75}
+
+	tests := []struct {
+		call   string
+		want   Entity
+		wantOK bool
+	}{
+		{call: "K1ABC", want: us, wantOK: true},
+		{call: "AA1AA", want: us, wantOK: true},
+		{call: "KH6ABC", want: hawaii, wantOK: true},
+		{call: "K6ABC", want: west, wantOK: true},
+		{call: "K1ABC/6", want: west, wantOK: true},
+		{call: "W6ABC/1", want: us, wantOK: true},
+		{call: "KH6/K1ABC", want: hawaii, wantOK: true},
+		{call: "K1ABC/KH6", want: hawaii, wantOK: true},
+		{call: "VE3ABC", want: ve3abc, wantOK: true},
+		{call: "VE3ABC/P", want: ve3abc, wantOK: true},
+		{call: "VE3ABD", want: Entity{Name: "Canada", Prefix: "VE", Continent: "NA", CQZone: 5, ITUZone: 9, Latitude: 44.35, Longitude: -78.75}, wantOK: true},
+		{call: "VE8ABC", want: ve8, wantOK: true},
+		{call: "VE3ABC/W6", want: west, wantOK: true},
+		{call: "K1ABC/MM"},
+		{call: "K1ABC/AM"},
+		{call: "1A0KM"},
+		{call: "Q1ABC"},
+		{call: ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := r.Resolve(tt.call, at)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("Resolve(%q) = %+v, %v, want %+v, %v", tt.call, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestResolveCtyXML(t *testing.T) {
+	r := newResolver(t, false, true, false)
+	in2020 := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	in2022 := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	us := Entity{DXCC: 291, Name: "UNITED STATES OF AMERICA", Prefix: "K", Continent: "NA", CQZone: 5, Latitude: 37.53, Longitude: -91.67}
+	hawaii := Entity{DXCC: 110, Name: "HAWAII", Prefix: "KH6", Continent: "OC", CQZone: 31, Latitude: 21.12, Longitude: -157.48}
+
+	zone4 := us
+	zone4.CQZone = 4
+
+	tests := []struct {
+		call   string
+		at     time.Time
+		want   Entity
+		wantOK bool
+	}{
+		{call: "K1ABC", at: in2022, want: us, wantOK: true},
+		{call: "KH6ABC", at: in2022, want: hawaii, wantOK: true},
+		{call: "KH6XYZ", at: in2022, want: hawaii, wantOK: true},
+		{call: "K1XYZ", at: in2020, want: hawaii, wantOK: true},
+		{call: "K1XYZ", at: time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC), want: hawaii, wantOK: true},
+		{call: "K1XYZ", at: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), want: us, wantOK: true},
+		{call: "K1XYZ", at: time.Date(2019, 12, 31, 23, 59, 59, 0, time.UTC), want: us, wantOK: true},
+		{call: "K1XYZ/P", at: in2020, want: hawaii, wantOK: true},
+		{call: "K1BAD", at: in2020, want: us, wantOK: true},
+		{call: "K1BAD", at: in2022},
+		{call: "K1ZON", at: in2020, want: zone4, wantOK: true},
+		{call: "K1ZON", at: in2022, want: us, wantOK: true},
+		{call: "K1ZON/4", at: in2022, want: us, wantOK: true},
+		{call: "K1ABC/MM", at: in2022},
+	}
+
+	for _, tt := range tests {
+		got, ok := r.Resolve(tt.call, tt.at)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("Resolve(%q, %s) = %+v, %v, want %+v, %v", tt.call, tt.at.Format(time.DateOnly), got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestResolveBothFiles(t *testing.T) {
+	in2020 := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	in2022 := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		xmlFirst bool
+		call     string
+		at       time.Time
+		want     Entity
+		wantOK   bool
+	}{
+		{
+			name: "cty.dat entity gets the adif code by prefix when the names differ",
+			call: "K1ABC", at: in2022,
+			want:   Entity{DXCC: 291, Name: "United States", Prefix: "K", Continent: "NA", CQZone: 5, ITUZone: 8, Latitude: 37.53, Longitude: -91.67},
+			wantOK: true,
+		},
+		{
+			name: "cty.dat entity gets the adif code by name",
+			call: "VE3ABD", at: in2022,
+			want:   Entity{DXCC: 1, Name: "Canada", Prefix: "VE", Continent: "NA", CQZone: 5, ITUZone: 9, Latitude: 44.35, Longitude: -78.75},
+			wantOK: true,
+		},
+		{
+			name: "cty.xml fills in the itu zone", xmlFirst: true,
+			call: "K1ABC", at: in2022,
+			want:   Entity{DXCC: 291, Name: "UNITED STATES OF AMERICA", Prefix: "K", Continent: "NA", CQZone: 5, ITUZone: 8, Latitude: 37.53, Longitude: -91.67},
+			wantOK: true,
+		},
+		{
+			name: "a different entity doesn't fill in", xmlFirst: true,
+			call: "K1XYZ", at: in2020,
+			want:   Entity{DXCC: 110, Name: "HAWAII", Prefix: "KH6", Continent: "OC", CQZone: 31, Latitude: 21.12, Longitude: -157.48},
+			wantOK: true,
+		},
+		{
+			name: "invalid operations apply whatever the order",
+			call: "K1BAD", at: in2022,
+		},
+		{
+			name: "cty.dat prefix overrides only fill in what cty.xml didn't know", xmlFirst: true,
+			call: "K6ABC", at: in2022,
+			want:   Entity{DXCC: 291, Name: "UNITED STATES OF AMERICA", Prefix: "K", Continent: "NA", CQZone: 5, ITUZone: 6, Latitude: 37.53, Longitude: -91.67},
+			wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newResolver(t, true, true, tt.xmlFirst)
+
+			got, ok := r.Resolve(tt.call, tt.at)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Resolve(%q) = %+v, %v, want %+v, %v", tt.call, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestReadCtyDatMalformed(t *testing.T) {
+	const header = "United States:  05:  08:  NA:   37.53:    91.67:     5.0:  K:\n"
+
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{name: "empty", in: ""},
+		{name: "whitespace", in: "\n  \n"},
+		{name: "no terminator", in: header + "    K,W"},
+		{name: "too few fields", in: "United States:  05:  08:  NA:  K,W;", wantErr: true},
+		{name: "too many fields", in: header + "  K:W;", wantErr: true},
+		{name: "bad zone", in: "United States:  xx:  08:  NA:   37.53:    91.67:     5.0:  K:  K;", wantErr: true},
+		{name: "bad location", in: "United States:  05:  08:  NA:   north:    91.67:     5.0:  K:  K;", wantErr: true},
+		{name: "unclosed override", in: header + "  K(4;", wantErr: true},
+		{name: "bad override", in: header + "  K(four);", wantErr: true},
+		{name: "text after override", in: header + "  K(4)x;", wantErr: true},
+		{name: "location without longitude", in: header + "  K<45.0>;", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewResolver().ReadCtyDat(strings.NewReader(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadCtyDat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadCtyXMLMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{name: "no entries", in: "<clublog></clublog>"},
+		{name: "empty", in: "", wantErr: true},
+		{name: "not xml", in: testCtyDat, wantErr: true},
+		{name: "other root", in: "<adx></adx>", wantErr: true},
+		{name: "truncated", in: testCtyXML[:len(testCtyXML)/2], wantErr: true},
+		{
+			name:    "bad exception time",
+			in:      "<clublog><exceptions><exception><call>K1XYZ</call><adif>110</adif><start>2020-01-01</start></exception></exceptions></clublog>",
+			wantErr: true,
+		},
+		{
+			name:    "bad prefix time",
+			in:      "<clublog><prefixes><prefix><call>K</call><adif>291</adif><end>yesterday</end></prefix></prefixes></clublog>",
+			wantErr: true,
+		},
+		{
+			name:    "bad invalid operation time",
+			in:      "<clublog><invalid_operations><invalid><call>K1BAD</call><start>2021</start></invalid></invalid_operations></clublog>",
+			wantErr: true,
+		},
+		{
+			name:    "bad zone exception time",
+			in:      "<clublog><zone_exceptions><zone_exception><call>K1ZON</call><zone>4</zone><end>2020-12-31 23:59</end></zone_exception></zone_exceptions></clublog>",
+			wantErr: true,
+		},
+		{
+			name:    "bad zone",
+			in:      "<clublog><zone_exceptions><zone_exception><call>K1ZON</call><zone>four</zone></zone_exception></zone_exceptions></clublog>",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewResolver().ReadCtyXML(strings.NewReader(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadCtyXML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	dat := filepath.Join(dir, "cty.dat")
+	err := os.WriteFile(dat, []byte(testCtyDat), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// cty.xml is downloaded gzipped
+	xmlgz := filepath.Join(dir, "cty.xml.gz")
+	f, err := os.Create(xmlgz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	_, err = gz.Write([]byte(testCtyXML))
+	if err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = Load()
+	})
+
+	err = Load(xmlgz, filepath.Join(dir, "missing.dat"), dat)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	e, ok := Lookup("K1ABC/6", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	if !ok || e.DXCC != 291 || e.ITUZone != 6 {
+		t.Errorf("Lookup() = %+v, %v, want 291 in itu zone 6", e, ok)
+	}
+
+	// a bad file names the file & leaves the country files in use alone
+	bad := filepath.Join(dir, "bad.xml")
+	err = os.WriteFile(bad, []byte(testCtyDat), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Load(bad)
+	if err == nil || !strings.HasPrefix(err.Error(), "bad.xml: ") {
+		t.Errorf("Load() error = %v, want one for bad.xml", err)
+	}
+	if _, ok := Lookup("K1ABC", time.Now()); !ok {
+		t.Error("Lookup() failed after a bad Load()")
+	}
+
+	// nothing loaded
+	err = Load(filepath.Join(dir, "missing.dat"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, ok := Lookup("K1ABC", time.Now()); ok {
+		t.Error("Lookup() resolved without any country files")
+	}
+}
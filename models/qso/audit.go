@@ -0,0 +1,720 @@
+package qso
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditSource is what made a change to a qso
+type AuditSource string
+
+const (
+	SourceManual     AuditSource = "manual"
+	SourceSourceFile AuditSource = "sourcefile"
+	SourceImport     AuditSource = "import"
+
+	// background tasks working with the logbook services
+	SourceSync AuditSource = "sync"
+
+	// changes made undoing & redoing other changes
+	SourceUndo AuditSource = "undo"
+	SourceRedo AuditSource = "redo"
+
+	// the context didn't say
+	SourceUnknown AuditSource = "unknown"
+)
+
+// AuditAction is the kind of change made to a qso
+type AuditAction string
+
+const (
+	AuditInsert AuditAction = "insert"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+
+	// sent or received status for a logbook service or qsl card
+	AuditQSL AuditAction = "qsl"
+)
+
+// AuditEntry is a change made to a qso, the values are the whole qso as json
+type AuditEntry struct {
+	ID        int64       `db:"id" json:"id"`
+	QSOID     int64       `db:"qso_id" json:"qsoId"`
+	ChangedAt int64       `db:"changed_at" json:"changedAt"`
+	Source    AuditSource `db:"source" json:"source"`
+	Action    AuditAction `db:"action" json:"action"`
+	OldValues string      `db:"old_values" json:"oldValues,omitempty"`
+	NewValues string      `db:"new_values" json:"newValues,omitempty"`
+	Undone    bool        `db:"undone" json:"undone"`
+}
+
+// UndoStore is a QSOStore that keeps an audit trail of the changes made to qsos so they can be undone
+type UndoStore interface {
+	QSOStore
+
+	// Undo reverts the last n changes made by the source in ctx that haven't been undone, latest first
+	Undo(ctx context.Context, n int) ([]AuditEntry, error)
+
+	// Redo makes the last n changes undone by the source in ctx again, in the order they were first made
+	// changes made after they were undone can't be redone
+	Redo(ctx context.Context, n int) ([]AuditEntry, error)
+
+	// Restore puts back the deleted qso identified by ID as it was when it was deleted
+	Restore(ctx context.Context, ID int64) (*QSO, error)
+
+	// AuditTrail returns the changes made to the qso identified by ID, oldest first
+	AuditTrail(ctx context.Context, ID int64) ([]AuditEntry, error)
+
+	// Deleted returns the deletes of qsos that can be restored, latest first
+	Deleted(ctx context.Context) ([]AuditEntry, error)
+}
+
+const (
+	stmtAuditInsert = `
+		insert into audit (
+			qso_id,
+			changed_at,
+			source,
+			action,
+			old_values,
+			new_values
+		) values (
+			:qso_id,
+			:changed_at,
+			:source,
+			:action,
+			:old_values,
+			:new_values
+		)
+	`
+
+	stmtAuditSelect = `
+		select
+			id,
+			qso_id,
+			changed_at,
+			source,
+			action,
+			old_values,
+			new_values,
+			undone
+		from
+			audit
+	`
+
+	// the latest change by the source that hasn't been undone
+	stmtAuditSelectUndo = stmtAuditSelect + `
+		where
+			source = :source
+			and undone = 0
+		order by id desc
+		limit 1
+	`
+
+	// the first change by the source undone since the source's latest change that wasn't
+	stmtAuditSelectRedo = stmtAuditSelect + `
+		where
+			source = :source
+			and undone = 1
+			and id > (select coalesce(max(id), 0) from audit where source = :source and undone = 0)
+		order by id asc
+		limit 1
+	`
+
+	// the qso's latest change, which is the delete if it's gone
+	stmtAuditSelectLatest = stmtAuditSelect + `
+		where
+			qso_id = :qso_id
+		order by id desc
+		limit 1
+	`
+
+	stmtAuditSelectDeleted = stmtAuditSelect + `
+		where
+			action = 'delete'
+			and undone = 0
+			and qso_id not in (select id from qsos)
+		order by id desc
+	`
+
+	stmtQSOQSLUpdate = `
+		update qsos set
+			qsl_lotw = :qsl_lotw,
+			qsl_qrz = :qsl_qrz,
+			qsl_clublog = :qsl_clublog,
+			qsl_eqsl = :qsl_eqsl,
+			qsl_card = :qsl_card,
+			qsl_lotw_sdate = :qsl_lotw_sdate,
+			qsl_lotw_rcvd = :qsl_lotw_rcvd,
+			qsl_lotw_rdate = :qsl_lotw_rdate,
+			qsl_qrz_sdate = :qsl_qrz_sdate,
+			qsl_qrz_rcvd = :qsl_qrz_rcvd,
+			qsl_qrz_rdate = :qsl_qrz_rdate,
+			qsl_clublog_sdate = :qsl_clublog_sdate,
+			qsl_clublog_rcvd = :qsl_clublog_rcvd,
+			qsl_clublog_rdate = :qsl_clublog_rdate,
+			qsl_eqsl_sdate = :qsl_eqsl_sdate,
+			qsl_eqsl_rcvd = :qsl_eqsl_rcvd,
+			qsl_eqsl_rdate = :qsl_eqsl_rdate,
+			qsl_card_sdate = :qsl_card_sdate,
+			qsl_card_sent_via = :qsl_card_sent_via,
+			qsl_card_rcvd = :qsl_card_rcvd,
+			qsl_card_rdate = :qsl_card_rdate,
+			qsl_card_rcvd_via = :qsl_card_rcvd_via
+		where
+			id = :id
+	`
+)
+
+// key for the source in a context
+type sourceKey struct{}
+
+// WithSource returns a context saying changes made with it come from source
+func WithSource(ctx context.Context, source AuditSource) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// SourceFrom returns the source of changes made with ctx, SourceUnknown if it wasn't set
+func SourceFrom(ctx context.Context) AuditSource {
+	if source, ok := ctx.Value(sourceKey{}).(AuditSource); ok {
+		return source
+	}
+	return SourceUnknown
+}
+
+// Old returns the qso as it was before the change, nil for inserts
+func (a *AuditEntry) Old() (*QSO, error) {
+	return auditValues(a.OldValues)
+}
+
+// New returns the qso as it was after the change, nil for deletes
+func (a *AuditEntry) New() (*QSO, error) {
+	return auditValues(a.NewValues)
+}
+
+// auditValues returns the qso in the json, nil if there isn't one
+func auditValues(values string) (*QSO, error) {
+	if values == "" {
+		return nil, nil
+	}
+
+	var qso QSO
+	err := json.Unmarshal([]byte(values), &qso)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return &qso, nil
+}
+
+// recordAudit records the change to each qso in the transaction, qsos that didn't change are left out
+// before is empty for inserts and after for deletes, otherwise they're matched by ID
+func recordAudit(tx *sqlx.Tx, source AuditSource, action AuditAction, before, after []QSO) error {
+	type change struct {
+		id                   int64
+		oldValues, newValues string
+	}
+
+	var changes []change
+	olds := make(map[int64]string, len(before))
+	for _, qso := range before {
+		bs, err := json.Marshal(qso)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+		olds[qso.ID] = string(bs)
+
+		if action == AuditDelete {
+			changes = append(changes, change{id: qso.ID, oldValues: string(bs)})
+		}
+	}
+	for _, qso := range after {
+		bs, err := json.Marshal(qso)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+
+		old := olds[qso.ID]
+		if old == string(bs) {
+			continue
+		}
+		changes = append(changes, change{id: qso.ID, oldValues: old, newValues: string(bs)})
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	q, err := tx.PrepareNamed(stmtAuditInsert)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	changedAt := time.Now().Unix()
+	for _, c := range changes {
+		_, err = q.Exec(map[string]interface{}{
+			"qso_id":     c.id,
+			"changed_at": changedAt,
+			"source":     source,
+			"action":     action,
+			"old_values": c.oldValues,
+			"new_values": c.newValues,
+		})
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sameQSO returns an error if the qso isn't the one the change was made to
+// what uniquely defines a qso has to match, anything else can have changed since
+func sameQSO(current *QSO, expected *QSO) error {
+	if current == nil {
+		err := fmt.Errorf("qso %d no longer exists", expected.ID)
+		log.Printf("%+v", err)
+		return err
+	}
+
+	if current.StationCallsign != expected.StationCallsign || current.Band != expected.Band || current.Call != expected.Call ||
+		current.Mode != expected.Mode || current.Date != expected.Date || current.Time != expected.Time {
+		err := fmt.Errorf("qso %d has been changed since", expected.ID)
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// restoreUploads sets the qso's QSL status for the logbook services its delete was queued for
+// deletes still waiting are dropped so the service keeps its copy, otherwise it is uploaded again
+func restoreUploads(tx *sqlx.Tx, qso *QSO) error {
+	for _, service := range []QSLService{QSLClublog, QSLQrz} {
+		sent, sdate := qso.sentFields(service)
+		if *sent != Sent {
+			continue
+		}
+
+		result, err := tx.Exec("delete from corrections where qso_id = ? and service = ?", qso.ID, service)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+
+		*sent = NotSent
+		*sdate = ""
+		if service == QSLQrz {
+			qso.QRZLogID = 0
+		}
+	}
+
+	return nil
+}
+
+// findInTx returns the qso identified by ID as it is in the transaction, nil if it isn't there
+func findInTx(tx *sqlx.Tx, ID int64) (*QSO, error) {
+	qsos, err := snapshot(tx, []QSO{{ID: ID}})
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	if len(qsos) == 0 {
+		return nil, nil
+	}
+	return &qsos[0], nil
+}
+
+// restoreInTx inserts the qso as it was, with the ID it had
+func restoreInTx(ctx context.Context, tx *sqlx.Tx, qso QSO) (*QSO, error) {
+	err := restoreUploads(tx, &qso)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+	qso.defaultQSLRcvd()
+
+	q, err := tx.PrepareNamedContext(ctx, stmtQSORestore)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	result, err := q.ExecContext(ctx, qso)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+	if n == 0 {
+		err = fmt.Errorf("restore would cause duplicate qso")
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	err = recordAudit(tx, SourceFrom(ctx), AuditInsert, nil, []QSO{qso})
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return &qso, nil
+}
+
+// setQSLInTx sets all the QSL fields of the qso to the values in qso, returns the qso before & after
+func setQSLInTx(ctx context.Context, tx *sqlx.Tx, qso QSO) (*QSO, *QSO, error) {
+	old, err := findInTx(tx, qso.ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+	err = sameQSO(old, &qso)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	q, err := tx.PrepareNamedContext(ctx, stmtQSOQSLUpdate)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	_, err = q.ExecContext(ctx, qso)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	updated, err := getInTx(tx, qso.ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	err = recordAudit(tx, SourceFrom(ctx), AuditQSL, []QSO{*old}, []QSO{*updated})
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	return old, updated, nil
+}
+
+// selectAudit returns the audit entries stmt selects with params in the transaction
+func selectAudit(ctx context.Context, tx *sqlx.Tx, stmt string, params map[string]interface{}) ([]AuditEntry, error) {
+	q, err := tx.PrepareNamedContext(ctx, stmt)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	var entries []AuditEntry
+	err = q.SelectContext(ctx, &entries, params)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// revert makes the change in the entry or reverts it in the transaction, returning the event for it
+func revert(ctx context.Context, tx *sqlx.Tx, entry AuditEntry, undo bool) (QSOEvent, error) {
+	oldQSO, err := entry.Old()
+	if err != nil {
+		log.Printf("%+v", err)
+		return QSOEvent{}, err
+	}
+	newQSO, err := entry.New()
+	if err != nil {
+		log.Printf("%+v", err)
+		return QSOEvent{}, err
+	}
+
+	// what the qso has to be now and what it's made into
+	from, to := oldQSO, newQSO
+	if undo {
+		from, to = newQSO, oldQSO
+	}
+
+	var before, after *QSO
+	switch {
+	case from == nil:
+		// put it back
+		after, err = restoreInTx(ctx, tx, *to)
+
+	case to == nil:
+		before, err = findInTx(tx, entry.QSOID)
+		if err == nil {
+			err = sameQSO(before, from)
+		}
+		if err == nil {
+			before, err = deleteInTx(ctx, tx, entry.QSOID)
+		}
+
+	case entry.Action == AuditQSL:
+		before, after, err = setQSLInTx(ctx, tx, *to)
+
+	default:
+		before, err = findInTx(tx, entry.QSOID)
+		if err == nil {
+			err = sameQSO(before, from)
+		}
+		if err == nil {
+			before, after, err = updateInTx(ctx, tx, to)
+		}
+	}
+	if err != nil {
+		log.Printf("%+v", err)
+		return QSOEvent{}, err
+	}
+
+	e := QSOEvent{IDs: []int64{entry.QSOID}}
+	switch {
+	case before == nil:
+		e.Type = QSOAdded
+		e.After = []QSO{*after}
+	case after == nil:
+		e.Type = QSODeleted
+		e.Before = []QSO{*before}
+	case entry.Action == AuditQSL:
+		e.Type = QSLChanged
+		e.Before = []QSO{*before}
+		e.After = []QSO{*after}
+	default:
+		e.Type = QSOUpdated
+		e.Before = []QSO{*before}
+		e.After = []QSO{*after}
+	}
+
+	return e, nil
+}
+
+// replay undoes or redoes up to n of the changes stmt selects one at a time, each in a transaction of its own
+func (s *SQLiteStore) replay(ctx context.Context, n int, stmt string, undo bool) ([]AuditEntry, error) {
+	params := map[string]interface{}{
+		"source": SourceFrom(ctx),
+	}
+
+	// the changes made are from undoing or redoing
+	rctx := WithSource(ctx, SourceRedo)
+	if undo {
+		rctx = WithSource(ctx, SourceUndo)
+	}
+
+	var replayed []AuditEntry
+	for i := 0; i < n; i++ {
+		entry, e, err := s.replayOne(rctx, stmt, params, undo)
+		if err != nil {
+			log.Printf("%+v", err)
+			return replayed, err
+		}
+		if entry == nil {
+			// nothing left
+			break
+		}
+
+		events.Publish(e)
+		replayed = append(replayed, *entry)
+	}
+
+	return replayed, nil
+}
+
+// replayOne undoes or redoes the change stmt selects in a transaction, nil if there isn't one
+func (s *SQLiteStore) replayOne(ctx context.Context, stmt string, params map[string]interface{}, undo bool) (*AuditEntry, QSOEvent, error) {
+	// in a transaction
+	tx, err := s.begin(ctx)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, QSOEvent{}, err
+	}
+	defer func() {
+		// if we've had an error or there was nothing to do, rollback
+		if err != nil {
+			err = tx.Rollback()
+			if err != nil {
+				log.Printf("%+v", err)
+			}
+		}
+	}()
+
+	entries, err := selectAudit(ctx, tx, stmt, params)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, QSOEvent{}, err
+	}
+	if len(entries) == 0 {
+		err = tx.Rollback()
+		if err != nil {
+			log.Printf("%+v", err)
+			return nil, QSOEvent{}, err
+		}
+		return nil, QSOEvent{}, nil
+	}
+	entry := entries[0]
+
+	e, err := revert(ctx, tx, entry, undo)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, QSOEvent{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, "update audit set undone = ? where id = ?", undo, entry.ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, QSOEvent{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, QSOEvent{}, err
+	}
+
+	entry.Undone = undo
+	return &entry, e, nil
+}
+
+// Undo reverts the last n changes made by the source in ctx that haven't been undone, latest first
+// stops at the first change that can't be undone because the qso was changed by some other source since
+func (s *SQLiteStore) Undo(ctx context.Context, n int) ([]AuditEntry, error) {
+	return s.replay(ctx, n, stmtAuditSelectUndo, true)
+}
+
+// Redo makes the last n changes undone by the source in ctx again, in the order they were first made
+func (s *SQLiteStore) Redo(ctx context.Context, n int) ([]AuditEntry, error) {
+	return s.replay(ctx, n, stmtAuditSelectRedo, false)
+}
+
+// Restore puts back the deleted qso identified by ID as it was when it was deleted
+// logbook services that have already deleted their copy get it uploaded again
+func (s *SQLiteStore) Restore(ctx context.Context, ID int64) (*QSO, error) {
+	// in a transaction
+	tx, err := s.begin(ctx)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+	defer func() {
+		// if we've had an error, rollback
+		if err != nil {
+			err = tx.Rollback()
+			if err != nil {
+				log.Printf("%+v", err)
+			}
+		}
+	}()
+
+	entries, err := selectAudit(ctx, tx, stmtAuditSelectLatest, map[string]interface{}{"qso_id": ID})
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+	if len(entries) == 0 || entries[0].Action != AuditDelete {
+		err = fmt.Errorf("qso %d wasn't deleted", ID)
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	old, err := entries[0].Old()
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	restored, err := restoreInTx(ctx, tx, *old)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	// the delete is done with, restoring it is what gets undone
+	_, err = tx.ExecContext(ctx, "update audit set undone = 1 where id = ?", entries[0].ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	publishQSOEvent(QSOAdded, nil, []QSO{*restored})
+	return restored, nil
+}
+
+// AuditTrail returns the changes made to the qso identified by ID, oldest first
+func (s *SQLiteStore) AuditTrail(ctx context.Context, ID int64) ([]AuditEntry, error) {
+	var err error
+
+	if s.db == nil {
+		err = errNoConnection
+		log.Printf("%+v", err)
+		return []AuditEntry{}, err
+	}
+
+	q, err := s.db.PrepareNamedContext(ctx, stmtAuditSelect+" where qso_id = :qso_id order by id asc")
+	if err != nil {
+		log.Printf("%+v", err)
+		return []AuditEntry{}, err
+	}
+	defer q.Close()
+
+	var entries []AuditEntry
+	err = q.SelectContext(ctx, &entries, map[string]interface{}{"qso_id": ID})
+	if err != nil {
+		log.Printf("%+v", err)
+		return []AuditEntry{}, err
+	}
+
+	return entries, nil
+}
+
+// Deleted returns the deletes of qsos that can be restored, latest first
+func (s *SQLiteStore) Deleted(ctx context.Context) ([]AuditEntry, error) {
+	var err error
+
+	if s.db == nil {
+		err = errNoConnection
+		log.Printf("%+v", err)
+		return []AuditEntry{}, err
+	}
+
+	var entries []AuditEntry
+	err = s.db.SelectContext(ctx, &entries, stmtAuditSelectDeleted)
+	if err != nil {
+		log.Printf("%+v", err)
+		return []AuditEntry{}, err
+	}
+
+	return entries, nil
+}
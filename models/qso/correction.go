@@ -0,0 +1,361 @@
+package qso
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CorrectionAction is what has to be done to a logbook service's copy of a qso
+type CorrectionAction string
+
+const (
+	// remove the copy, a changed qso is uploaded again by resetting its QSL status
+	CorrectionDelete CorrectionAction = "delete"
+)
+
+// Correction is a change to make to a logbook service for a qso edited or deleted after it was uploaded
+// the qso fields are the values the service has
+type Correction struct {
+	ID              int64            `db:"id"`
+	CreatedAt       int64            `db:"created_at"`
+	Service         QSLService       `db:"service"`
+	Action          CorrectionAction `db:"action"`
+	QSOID           int64            `db:"qso_id"`
+	StationCallsign string           `db:"station_callsign"`
+	Call            string           `db:"call"`
+	Band            string           `db:"band"`
+	Mode            string           `db:"mode"`
+	Date            string           `db:"qso_date"`
+	Time            string           `db:"qso_time"`
+	QRZLogID        int64            `db:"qrz_logid"`
+
+	// failed attempts at making it, it isn't tried again until next attempt at
+	Attempts      int              `db:"attempts"`
+	NextAttemptAt int64            `db:"next_attempt_at"`
+	ErrorClass    UploadErrorClass `db:"error_class"`
+	Response      string           `db:"response"`
+}
+
+const (
+	stmtCorrectionInsert = `
+		insert into corrections (
+			created_at,
+			service,
+			action,
+			qso_id,
+			station_callsign,
+			call,
+			band,
+			mode,
+			qso_date,
+			qso_time,
+			qrz_logid
+		) values (
+			:created_at,
+			:service,
+			:action,
+			:qso_id,
+			:station_callsign,
+			:call,
+			:band,
+			:mode,
+			:qso_date,
+			:qso_time,
+			:qrz_logid
+		)
+	`
+
+	stmtCorrectionSelect = `
+		select
+			id,
+			created_at,
+			service,
+			action,
+			qso_id,
+			station_callsign,
+			call,
+			band,
+			mode,
+			qso_date,
+			qso_time,
+			qrz_logid,
+			attempts,
+			next_attempt_at,
+			error_class,
+			response
+		from
+			corrections
+		where
+			service = :service
+		order by id asc
+	`
+
+	stmtCorrectionFailed = `
+		update corrections set
+			attempts = attempts + 1,
+			next_attempt_at = :attempted_at + min(:backoff << min(attempts, 20), :maxbackoff),
+			error_class = :error_class,
+			response = :response
+		where
+			id = :id
+	`
+)
+
+// keyFields are what identifies a qso, a copy with the same key is replaced on QRZ.com
+func keyFields(q QSO) []interface{} {
+	return []interface{}{q.StationCallsign, q.Call, q.Band, q.Mode, q.Date, q.Time}
+}
+
+// clublogFields are what Club Log keeps of a qso, QSL statuses aren't here because updates don't change them
+func clublogFields(q QSO) []interface{} {
+	return append(keyFields(q), q.Frequency, q.FrequencyRx, q.DXCC, q.GridSquare, q.Extra.get("PROP_MODE"), q.Extra.get("SAT_NAME"))
+}
+
+// qrzFields are what QRZ.com keeps of a qso, which is all of it
+func qrzFields(q QSO) []interface{} {
+	fields := append(keyFields(q), q.TimeOff, q.RSTRcvd, q.RSTSent, q.Frequency, q.FrequencyRx, q.TxPower, q.DXCC, q.GridSquare, q.CQZone, q.ITUZone)
+	for _, f := range q.Extra {
+		fields = append(fields, f.Name, f.Value)
+	}
+	return fields
+}
+
+// eqslFields are what eQSL.cc puts on the card
+func eqslFields(q QSO) []interface{} {
+	return append(keyFields(q), q.RSTSent, q.Extra.get("PROP_MODE"), q.Extra.get("SAT_NAME"), q.Extra.get("QSLMSG"))
+}
+
+// changed returns true if any of the fields are different between old & new
+func changed(fields func(QSO) []interface{}, old, new QSO) bool {
+	a, b := fields(old), fields(new)
+	if len(a) != len(b) {
+		return true
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// correctionsFor returns the corrections needed for the services the qso was uploaded to
+// and the services it needs to be uploaded to again, new is the qso after an edit and nil when it's deleted
+// only changes to what a service keeps need it corrected
+func correctionsFor(old QSO, new *QSO) ([]Correction, []QSLService) {
+	var corrections []Correction
+	var reupload []QSLService
+
+	correction := func(service QSLService) Correction {
+		return Correction{
+			CreatedAt:       time.Now().Unix(),
+			Service:         service,
+			Action:          CorrectionDelete,
+			QSOID:           old.ID,
+			StationCallsign: old.StationCallsign,
+			Call:            old.Call,
+			Band:            old.Band,
+			Mode:            old.Mode,
+			Date:            old.Date,
+			Time:            old.Time,
+			QRZLogID:        old.QRZLogID,
+		}
+	}
+
+	if old.QSLClublog == Sent && (new == nil || changed(clublogFields, old, *new)) {
+		corrections = append(corrections, correction(QSLClublog))
+		reupload = append(reupload, QSLClublog)
+	}
+
+	// QRZ.com can only delete by logid, the upload replaces the qso if it is still the same qso
+	if old.QRZLogID != 0 && (new == nil || changed(keyFields, old, *new)) {
+		corrections = append(corrections, correction(QSLQrz))
+	}
+	if old.QSLQrz == Sent && new != nil && changed(qrzFields, old, *new) {
+		reupload = append(reupload, QSLQrz)
+	}
+
+	// eQSL.cc can't delete, the corrected qso is uploaded alongside the old one
+	if old.QSLEqsl == Sent && new != nil && changed(eqslFields, old, *new) {
+		reupload = append(reupload, QSLEqsl)
+	}
+
+	return corrections, reupload
+}
+
+// enqueueCorrections records the corrections in the transaction
+func enqueueCorrections(tx *sqlx.Tx, corrections []Correction) error {
+	if len(corrections) == 0 {
+		return nil
+	}
+
+	q, err := tx.PrepareNamed(stmtCorrectionInsert)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	for _, c := range corrections {
+		_, err = q.Exec(c)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resetUploads marks the qso as needing to be uploaded to the services again
+// a QRZ.com logid is cleared when the copy it's for is being deleted
+func resetUploads(tx *sqlx.Tx, old QSO, corrections []Correction, services []QSLService) error {
+	for _, service := range services {
+		_, err := tx.Exec(fmt.Sprintf("update qsos set %s = %d, %s = '' where id = ?", service, NotSent, sentDateColumn(service)), old.ID)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	for _, c := range corrections {
+		if c.Service != QSLQrz {
+			continue
+		}
+
+		_, err := tx.Exec("update qsos set qrz_logid = 0 where id = ?", old.ID)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getInTx returns the qso identified by ID as it is in the transaction
+func getInTx(tx *sqlx.Tx, ID int64) (*QSO, error) {
+	q, err := tx.PrepareNamed(stmtQSOSelectAll + " where id = :id")
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	var qso QSO
+	err = q.Get(&qso, map[string]interface{}{"id": ID})
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return &qso, nil
+}
+
+// PendingCorrections returns the corrections waiting to be made to a service, oldest first
+// including the ones that failed and aren't due to be tried again yet
+func (s *SQLiteStore) PendingCorrections(ctx context.Context, service QSLService) ([]Correction, error) {
+	var err error
+
+	if s.db == nil {
+		err = errNoConnection
+		log.Printf("%+v", err)
+		return []Correction{}, err
+	}
+
+	q, err := s.db.PrepareNamedContext(ctx, stmtCorrectionSelect)
+	if err != nil {
+		log.Printf("%+v", err)
+		return []Correction{}, err
+	}
+	defer q.Close()
+
+	var corrections []Correction
+	err = q.SelectContext(ctx, &corrections, map[string]interface{}{"service": service})
+	if err != nil {
+		log.Printf("%+v", err)
+		return []Correction{}, err
+	}
+
+	return corrections, nil
+}
+
+// CorrectionDone removes the correction once it has been made
+func (s *SQLiteStore) CorrectionDone(ctx context.Context, c Correction) error {
+	var err error
+
+	if s.db == nil {
+		err = errNoConnection
+		log.Printf("%+v", err)
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, "delete from corrections where id = ?", c.ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateQRZLogID records the QRZ.com logbook id of the qso
+func (s *SQLiteStore) UpdateQRZLogID(ctx context.Context, ID, logID int64) error {
+	var err error
+
+	if s.db == nil {
+		err = errNoConnection
+		log.Printf("%+v", err)
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, "update qsos set qrz_logid = ? where id = ?", logID, ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// CorrectionFailed records the failed attempt at making the correction
+// the service rejecting it means there's nothing to correct, ie the qso isn't there, so it's dropped
+// otherwise it's retried with exponential backoff
+func (s *SQLiteStore) CorrectionFailed(ctx context.Context, c Correction, class UploadErrorClass, response string) error {
+	var err error
+
+	if s.db == nil {
+		err = errNoConnection
+		log.Printf("%+v", err)
+		return err
+	}
+
+	if class == ErrorRejected {
+		log.Printf("dropping %s correction for %s %s %s %s: %s", c.Service, c.Call, c.Band, c.Date, c.Time, truncateResponse(response))
+		return s.CorrectionDone(ctx, c)
+	}
+
+	q, err := s.db.PrepareNamedContext(ctx, stmtCorrectionFailed)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer q.Close()
+
+	_, err = q.ExecContext(ctx, map[string]interface{}{
+		"id":           c.ID,
+		"attempted_at": time.Now().Unix(),
+		"error_class":  class,
+		"response":     truncateResponse(response),
+		"backoff":      int64(outboxFirstRetry.Seconds()),
+		"maxbackoff":   int64(outboxMaxRetry.Seconds()),
+	})
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
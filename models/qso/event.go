@@ -0,0 +1,95 @@
+package qso
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/bbathe/golog/util"
+	"github.com/jmoiron/sqlx"
+)
+
+// QSOEventType is what happened to the QSOs in a QSOEvent
+type QSOEventType string
+
+const (
+	QSOAdded   QSOEventType = "added"
+	QSOUpdated QSOEventType = "updated"
+	QSODeleted QSOEventType = "deleted"
+
+	// sent or received status for a logbook service or qsl card changed
+	QSLChanged QSOEventType = "qslchanged"
+)
+
+// QSOEvent is published after QSOs change
+type QSOEvent struct {
+	Type QSOEventType
+	IDs  []int64
+
+	// the qsos before & after the change in the same order as IDs
+	// Before is empty for QSOAdded, After is empty for QSODeleted
+	Before []QSO
+	After  []QSO
+}
+
+// allow callers to register to recieve events after qsos change
+// handlers are called on a goroutine of their own, not the one that made the change
+type QSOChangeEventHandler func(QSOEvent)
+
+var events util.EventBus[QSOEvent]
+
+func Attach(handler QSOChangeEventHandler) int {
+	return events.Attach(handler)
+}
+
+func Detach(handle int) {
+	events.Detach(handle)
+}
+
+// publishQSOEvent publishes the change to the qsos, nothing is published when no qsos changed
+func publishQSOEvent(t QSOEventType, before, after []QSO) {
+	changed := after
+	if t == QSODeleted {
+		changed = before
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	ids := make([]int64, 0, len(changed))
+	for _, q := range changed {
+		ids = append(ids, q.ID)
+	}
+
+	events.Publish(QSOEvent{
+		Type:   t,
+		IDs:    ids,
+		Before: before,
+		After:  after,
+	})
+}
+
+// snapshot returns the qsos as they are in the transaction, qsos that aren't there are left out
+func snapshot(tx *sqlx.Tx, qsos []QSO) ([]QSO, error) {
+	q, err := tx.PrepareNamed(stmtQSOSelectAll + " where id = :id")
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	s := make([]QSO, 0, len(qsos))
+	for _, qso := range qsos {
+		var c QSO
+		err = q.Get(&c, map[string]interface{}{"id": qso.ID})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			log.Printf("%+v", err)
+			return nil, err
+		}
+		s = append(s, c)
+	}
+
+	return s, nil
+}
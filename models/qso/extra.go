@@ -0,0 +1,67 @@
+package qso
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ExtraField is a field from an imported QSO that golog has no column for (APP_*, USERDEFn, etc.)
+// kept so the QSO can be exported without losing anything
+type ExtraField struct {
+	Name      string `json:"name"`
+	Indicator string `json:"indicator,omitempty"`
+	Value     string `json:"value"`
+}
+
+// ExtraFields is the ordered set of extra fields on a QSO, stored as json in the qso database
+type ExtraFields []ExtraField
+
+// Value implements driver.Valuer
+func (ef ExtraFields) Value() (driver.Value, error) {
+	if len(ef) == 0 {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(ef)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (ef *ExtraFields) Scan(src interface{}) error {
+	var b []byte
+
+	switch v := src.(type) {
+	case nil:
+		*ef = nil
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("cannot scan %T into ExtraFields", src)
+	}
+
+	if len(b) == 0 {
+		*ef = nil
+		return nil
+	}
+
+	return json.Unmarshal(b, ef)
+}
+
+// get returns the value of the extra field called name, empty if there isn't one
+func (ef ExtraFields) get(name string) string {
+	for _, f := range ef {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
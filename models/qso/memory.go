@@ -0,0 +1,857 @@
+package qso
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps QSOs in memory, for tests and programs embedding golog without a qso database
+// there are no logbook services behind it so changes don't queue corrections
+type MemoryStore struct {
+	mutex    sync.RWMutex
+	qsos     map[int64]QSO
+	lastID   int64
+	userdefs []UserDef
+}
+
+// NewMemoryStore returns an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		qsos: map[int64]QSO{},
+	}
+}
+
+// duplicate returns the ID of the qso already in the store with the same station, band, call, mode, date & time
+// zero if there isn't one, assumes the mutex is held
+func (m *MemoryStore) duplicate(qso QSO) int64 {
+	for id, q := range m.qsos {
+		if q.StationCallsign == qso.StationCallsign && q.Band == qso.Band && q.Call == qso.Call &&
+			q.Mode == qso.Mode && q.Date == qso.Date && q.Time == qso.Time {
+			return id
+		}
+	}
+	return 0
+}
+
+// insert adds the qso to the store unless it is a duplicate, assumes the mutex is held
+func (m *MemoryStore) insert(qso QSO) (int64, bool) {
+	if m.duplicate(qso) != 0 {
+		return 0, false
+	}
+
+	m.lastID++
+	qso.ID = m.lastID
+	m.qsos[qso.ID] = qso
+
+	return qso.ID, true
+}
+
+// selectQSOs returns the qsos keep returns true for sorted by date & time, latest first if desc
+func (m *MemoryStore) selectQSOs(keep func(QSO) bool, desc bool, limit int) []QSO {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	qsos := []QSO{}
+	for _, q := range m.qsos {
+		if keep(q) {
+			qsos = append(qsos, q)
+		}
+	}
+
+	sort.Slice(qsos, func(i, j int) bool {
+		a := qsos[i].Date + " " + qsos[i].Time
+		b := qsos[j].Date + " " + qsos[j].Time
+		if a == b {
+			return qsos[i].ID < qsos[j].ID
+		}
+		if desc {
+			return a > b
+		}
+		return a < b
+	})
+
+	if limit > 0 && len(qsos) > limit {
+		qsos = qsos[:limit]
+	}
+
+	return qsos
+}
+
+// Add inserts a single QSO into the store
+// sets qso.LoadedAt before insert and qso.ID after
+// does not insert QSL fields, they default to false
+func (m *MemoryStore) Add(ctx context.Context, qso *QSO) error {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	// set LoadedAt to now
+	qso.LoadedAt = time.Now().Unix()
+
+	err = qso.Validate(false)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	qso.resolveDXCC()
+
+	// only the qso fields
+	q := QSO{
+		LoadedAt:        qso.LoadedAt,
+		StationCallsign: qso.StationCallsign,
+		Band:            qso.Band,
+		Call:            qso.Call,
+		Mode:            qso.Mode,
+		Date:            qso.Date,
+		Time:            qso.Time,
+		TimeOff:         qso.TimeOff,
+		RSTRcvd:         qso.RSTRcvd,
+		RSTSent:         qso.RSTSent,
+		Frequency:       qso.Frequency,
+		FrequencyRx:     qso.FrequencyRx,
+		TxPower:         qso.TxPower,
+		DXCC:            qso.DXCC,
+		GridSquare:      qso.GridSquare,
+		CQZone:          qso.CQZone,
+		ITUZone:         qso.ITUZone,
+		Extra:           qso.Extra,
+	}
+	q.defaultQSLRcvd()
+
+	m.mutex.Lock()
+	id, ok := m.insert(q)
+	m.mutex.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	qso.ID = id
+	q.ID = id
+
+	publishQSOEvent(QSOAdded, nil, []QSO{q})
+	return nil
+}
+
+// BulkAdd inserts all QSOs into the store, returning how many were inserted
+// QSOs already in the store are skipped
+// assumes qso.LoadedAt was already set
+func (m *MemoryStore) BulkAdd(ctx context.Context, qsos []QSO) (int, error) {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return 0, err
+	}
+
+	// all or nothing, like the qso database transaction
+	for _, qso := range qsos {
+		err = qso.Validate(false)
+		if err != nil {
+			log.Printf("%+v", err)
+			return 0, err
+		}
+	}
+
+	m.mutex.Lock()
+	var added []QSO
+	for _, qso := range qsos {
+		qso.defaultQSLRcvd()
+		qso.resolveDXCC()
+		if id, ok := m.insert(qso); ok {
+			qso.ID = id
+			added = append(added, qso)
+		}
+	}
+	m.mutex.Unlock()
+
+	publishQSOEvent(QSOAdded, nil, added)
+	return len(added), nil
+}
+
+// Update updates the QSO only fields in the store
+// does not update QSL fields
+func (m *MemoryStore) Update(ctx context.Context, qso *QSO) error {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = qso.Validate(true)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	m.mutex.Lock()
+	q, ok := m.qsos[qso.ID]
+	if !ok {
+		m.mutex.Unlock()
+		err = ErrQSONotFound
+		log.Printf("%+v", err)
+		return err
+	}
+
+	// if there is already a qso matching this update that is not this qso then error out
+	if id := m.duplicate(*qso); id != 0 && id != qso.ID {
+		m.mutex.Unlock()
+		err = fmt.Errorf("update would cause duplicate qso")
+		log.Printf("%+v", err)
+		return err
+	}
+
+	old := q
+	q.StationCallsign = qso.StationCallsign
+	q.Band = qso.Band
+	q.Call = qso.Call
+	q.Mode = qso.Mode
+	q.Date = qso.Date
+	q.Time = qso.Time
+	q.TimeOff = qso.TimeOff
+	q.RSTRcvd = qso.RSTRcvd
+	q.RSTSent = qso.RSTSent
+	q.Frequency = qso.Frequency
+	q.FrequencyRx = qso.FrequencyRx
+	q.TxPower = qso.TxPower
+	q.DXCC = qso.DXCC
+	q.GridSquare = qso.GridSquare
+	q.CQZone = qso.CQZone
+	q.ITUZone = qso.ITUZone
+	q.Extra = qso.Extra
+	m.qsos[q.ID] = q
+	m.mutex.Unlock()
+
+	publishQSOEvent(QSOUpdated, []QSO{old}, []QSO{q})
+	return nil
+}
+
+// Delete removes a qso from the store
+func (m *MemoryStore) Delete(ctx context.Context, qso *QSO) error {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = qso.Validate(true)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	m.mutex.Lock()
+	old, ok := m.qsos[qso.ID]
+	delete(m.qsos, qso.ID)
+	m.mutex.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	publishQSOEvent(QSODeleted, []QSO{old}, nil)
+	return nil
+}
+
+// Get returns a single QSO identified by ID
+func (m *MemoryStore) Get(ctx context.Context, ID int64) (*QSO, error) {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	m.mutex.RLock()
+	q, ok := m.qsos[ID]
+	m.mutex.RUnlock()
+
+	if !ok {
+		err = ErrQSONotFound
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return &q, nil
+}
+
+// likeMatcher returns a func testing values against the sql like pattern, case insensitive
+func likeMatcher(pattern string) func(string) bool {
+	var re strings.Builder
+	re.WriteString("(?is)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			re.WriteString(".*")
+		case '_':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString("$")
+
+	// everything in the pattern is quoted so it always compiles
+	m := regexp.MustCompile(re.String())
+	return m.MatchString
+}
+
+// Search returns all QSOs matching the criteria limited by count limit
+func (m *MemoryStore) Search(ctx context.Context, criteria QSO, limit int) ([]QSO, error) {
+	return m.Find(ctx, CriteriaQuery(criteria, limit))
+}
+
+// matchCondition returns a func testing qsos against a single condition
+func matchCondition(c Condition) func(QSO) bool {
+	f := queryFields[c.Field]
+
+	var tests []func(QSO) bool
+	for _, v := range c.Values {
+		v := v
+		switch {
+		case f.kind == kindQSL:
+			status := QSLStatus(v)
+			tests = append(tests, func(q QSO) bool {
+				sent, _ := q.sentFields(f.service)
+				rcvd, _ := q.rcvdFields(f.service)
+				switch status {
+				case QSLStatusSent:
+					return *sent == Sent
+				case QSLStatusConfirmed:
+					return rcvd.Confirmed()
+				}
+				return *rcvd == RcvdRequest
+			})
+		case c.Op == OpLike:
+			match := likeMatcher(v)
+			tests = append(tests, func(q QSO) bool {
+				return match(f.text(q))
+			})
+		case f.kind == kindText:
+			tests = append(tests, func(q QSO) bool {
+				a := f.text(q)
+				switch c.Op {
+				case OpEqual:
+					return strings.EqualFold(a, v)
+				case OpLess:
+					return a < v
+				case OpLessEqual:
+					return a <= v
+				case OpGreater:
+					return a > v
+				}
+				return a >= v
+			})
+		default:
+			// already validated
+			n, _ := strconv.ParseFloat(v, 64)
+			if c.Op == OpEqual && f.kind == kindFrequency {
+				// anywhere within the same kHz
+				low := float64(int64(n) - int64(n)%1000)
+				tests = append(tests, func(q QSO) bool {
+					a := f.number(q)
+					return a >= low && a < low+1000
+				})
+				continue
+			}
+			tests = append(tests, func(q QSO) bool {
+				a := f.number(q)
+				switch c.Op {
+				case OpEqual:
+					return a == n
+				case OpLess:
+					return a < n
+				case OpLessEqual:
+					return a <= n
+				case OpGreater:
+					return a > n
+				}
+				return a >= n
+			})
+		}
+	}
+
+	return func(q QSO) bool {
+		for _, t := range tests {
+			if t(q) {
+				return !c.Not
+			}
+		}
+		return c.Not
+	}
+}
+
+// compareQSOs returns -1, 0 or 1 as a sorts before, with or after b by the sort keys
+func compareQSOs(keys []SortKey, a, b QSO) int {
+	for _, k := range keys {
+		f := queryFields[k.Field]
+
+		var r int
+		if f.text != nil {
+			r = strings.Compare(f.text(a), f.text(b))
+		} else {
+			x, y := f.number(a), f.number(b)
+			if x < y {
+				r = -1
+			} else if x > y {
+				r = 1
+			}
+		}
+
+		if k.Desc {
+			r = -r
+		}
+		if r != 0 {
+			return r
+		}
+	}
+	return 0
+}
+
+// Find returns the QSOs matching the query
+func (m *MemoryStore) Find(ctx context.Context, query Query) ([]QSO, error) {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	err = query.Validate()
+	if err != nil {
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	var groups [][]func(QSO) bool
+	for _, g := range query.Groups {
+		var tests []func(QSO) bool
+		for _, c := range g {
+			tests = append(tests, matchCondition(c))
+		}
+		groups = append(groups, tests)
+	}
+
+	keys := query.sortKeys()
+
+	// page starts after the last qso of the previous one
+	var last *QSO
+	if query.After != 0 {
+		last, err = m.Get(ctx, query.After)
+		if err != nil {
+			log.Printf("%+v", err)
+			return []QSO{}, err
+		}
+	}
+
+	keep := func(q QSO) bool {
+		if last != nil && compareQSOs(keys, q, *last) <= 0 {
+			return false
+		}
+		if len(groups) == 0 {
+			return true
+		}
+		for _, tests := range groups {
+			all := true
+			for _, t := range tests {
+				if !t(q) {
+					all = false
+					break
+				}
+			}
+			if all {
+				return true
+			}
+		}
+		return false
+	}
+
+	qsos := m.selectQSOs(keep, true, 0)
+	sort.Slice(qsos, func(i, j int) bool {
+		return compareQSOs(keys, qsos[i], qsos[j]) < 0
+	})
+
+	if query.Offset >= len(qsos) {
+		return []QSO{}, nil
+	}
+	qsos = qsos[query.Offset:]
+	if query.Limit > 0 && len(qsos) > query.Limit {
+		qsos = qsos[:query.Limit]
+	}
+
+	return qsos, nil
+}
+
+// History returns all QSO loaded after days ago limited by count limit
+func (m *MemoryStore) History(ctx context.Context, days, limit int) ([]QSO, error) {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	// how much history?
+	var then int64
+	if days > 0 {
+		then = time.Now().Add(-(time.Duration(days*24) * time.Hour)).Unix()
+	}
+
+	return m.selectQSOs(func(q QSO) bool {
+		return q.LoadedAt >= then
+	}, true, limit), nil
+}
+
+// FindQSLsToSend returns all QSOs that need QSLs for a specific service before delay minutes ago
+func (m *MemoryStore) FindQSLsToSend(ctx context.Context, service QSLService, delay int) ([]QSO, error) {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	if sent, _ := (&QSO{}).sentFields(service); sent == nil {
+		err = fmt.Errorf("unknown qsl service %s", service)
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	// handle delay for sending QSL
+	then := time.Now().Unix()
+	if delay > 0 {
+		then = time.Now().Add(-(time.Duration(delay) * time.Minute)).Unix()
+	}
+
+	// return results to caller with oldest first
+	return m.selectQSOs(func(q QSO) bool {
+		sent, _ := q.sentFields(service)
+		return *sent == NotSent && (delay <= 0 || q.LoadedAt <= then)
+	}, false, 0), nil
+}
+
+// UpdateQSL updates the QSOs QSL status, the sent date is set to today or cleared
+func (m *MemoryStore) UpdateQSL(ctx context.Context, qsos []QSO, service QSLService, sent QSLSent) error {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	if s, _ := (&QSO{}).sentFields(service); s == nil {
+		err = fmt.Errorf("unknown qsl service %s", service)
+		log.Printf("%+v", err)
+		return err
+	}
+
+	sdate := ""
+	if sent == Sent {
+		sdate = today()
+	}
+
+	var before, after []QSO
+
+	m.mutex.Lock()
+	for _, qso := range qsos {
+		q, ok := m.qsos[qso.ID]
+		if !ok {
+			continue
+		}
+		before = append(before, q)
+
+		s, date := q.sentFields(service)
+		*s = sent
+		*date = sdate
+		m.qsos[q.ID] = q
+		after = append(after, q)
+	}
+	m.mutex.Unlock()
+
+	publishQSOEvent(QSLChanged, before, after)
+	return nil
+}
+
+// All returns all QSOs in the store, oldest first
+func (m *MemoryStore) All(ctx context.Context) ([]QSO, error) {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	return m.selectQSOs(func(q QSO) bool { return true }, false, 0), nil
+}
+
+// Between returns all QSOs made from from thru to, oldest first
+func (m *MemoryStore) Between(ctx context.Context, from, to time.Time) ([]QSO, error) {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	// dates & times are kept as text in utc so they compare in order
+	f := from.UTC().Format("2006-01-02 15:04:05")
+	t := to.UTC().Format("2006-01-02 15:04:05")
+
+	return m.selectQSOs(func(q QSO) bool {
+		at := q.Date + " " + q.Time
+		return at >= f && at <= t
+	}, false, 0), nil
+}
+
+// Near returns the QSOs with call on band made within window of at, closest first
+func (m *MemoryStore) Near(ctx context.Context, call, band string, at time.Time, window time.Duration) ([]QSO, error) {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	f := at.Add(-window).UTC().Format("2006-01-02 15:04:05")
+	t := at.Add(window).UTC().Format("2006-01-02 15:04:05")
+
+	qsos := m.selectQSOs(func(q QSO) bool {
+		d := q.Date + " " + q.Time
+		return q.Call == call && q.Band == band && d >= f && d <= t
+	}, false, 0)
+
+	sortNear(qsos, at, window)
+	return qsos, nil
+}
+
+// UpdateQSLReceived updates the QSOs QSL received status for a service, date is yyyy-mm-dd and defaults to today
+func (m *MemoryStore) UpdateQSLReceived(ctx context.Context, qsos []QSO, service QSLService, rcvd QSLRcvd, date string) error {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	if r, _ := (&QSO{}).rcvdFields(service); r == nil {
+		err = fmt.Errorf("unknown qsl service %s", service)
+		log.Printf("%+v", err)
+		return err
+	}
+
+	if date == "" && rcvd.Confirmed() {
+		date = today()
+	}
+
+	var before, after []QSO
+
+	m.mutex.Lock()
+	for _, qso := range qsos {
+		q, ok := m.qsos[qso.ID]
+		if !ok {
+			continue
+		}
+		before = append(before, q)
+
+		r, rdate := q.rcvdFields(service)
+		*r = rcvd
+		*rdate = date
+		m.qsos[q.ID] = q
+		after = append(after, q)
+	}
+	m.mutex.Unlock()
+
+	publishQSOEvent(QSLChanged, before, after)
+	return nil
+}
+
+// LastQSLReceived returns the latest date a QSL was received from a service, empty if there hasn't been one
+func (m *MemoryStore) LastQSLReceived(ctx context.Context, service QSLService) (string, error) {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	if r, _ := (&QSO{}).rcvdFields(service); r == nil {
+		err = fmt.Errorf("unknown qsl service %s", service)
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var last string
+	for _, q := range m.qsos {
+		_, rdate := q.rcvdFields(service)
+		if *rdate > last {
+			last = *rdate
+		}
+	}
+
+	return last, nil
+}
+
+// UpdateLocation updates the dxcc entity, grid & zones of the QSOs
+func (m *MemoryStore) UpdateLocation(ctx context.Context, qsos []QSO) error {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	var before, after []QSO
+
+	m.mutex.Lock()
+	for _, qso := range qsos {
+		q, ok := m.qsos[qso.ID]
+		if !ok {
+			continue
+		}
+		before = append(before, q)
+
+		q.DXCC = qso.DXCC
+		q.GridSquare = qso.GridSquare
+		q.CQZone = qso.CQZone
+		q.ITUZone = qso.ITUZone
+		m.qsos[q.ID] = q
+		after = append(after, q)
+	}
+	m.mutex.Unlock()
+
+	publishQSOEvent(QSOUpdated, before, after)
+	return nil
+}
+
+// UploadSucceeded marks the QSOs as sent to the service, attempts aren't kept
+func (m *MemoryStore) UploadSucceeded(ctx context.Context, qsos []QSO, service QSLService, response string) error {
+	return m.UpdateQSL(ctx, qsos, service, Sent)
+}
+
+// UploadFailed does nothing, there is no outbox so the QSOs are tried again by the next upload
+func (m *MemoryStore) UploadFailed(ctx context.Context, qsos []QSO, service QSLService, class UploadErrorClass, response string) error {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateQRZLogID records the QRZ.com logbook id of the qso
+func (m *MemoryStore) UpdateQRZLogID(ctx context.Context, ID, logID int64) error {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	q, ok := m.qsos[ID]
+	if !ok {
+		err = ErrQSONotFound
+		log.Printf("%+v", err)
+		return err
+	}
+
+	q.QRZLogID = logID
+	m.qsos[ID] = q
+
+	return nil
+}
+
+// PendingCorrections returns no corrections, changes don't queue them
+func (m *MemoryStore) PendingCorrections(ctx context.Context, service QSLService) ([]Correction, error) {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return []Correction{}, err
+	}
+
+	return []Correction{}, nil
+}
+
+// CorrectionDone does nothing, there are no corrections
+func (m *MemoryStore) CorrectionDone(ctx context.Context, c Correction) error {
+	return ctx.Err()
+}
+
+// CorrectionFailed does nothing, there are no corrections
+func (m *MemoryStore) CorrectionFailed(ctx context.Context, c Correction, class UploadErrorClass, response string) error {
+	return ctx.Err()
+}
+
+// AddUserDefs adds the user-defined field declarations to the store
+// declarations that already exist are updated
+func (m *MemoryStore) AddUserDefs(ctx context.Context, userdefs []UserDef) error {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, u := range userdefs {
+		found := false
+		for i := range m.userdefs {
+			if strings.EqualFold(m.userdefs[i].Name, u.Name) {
+				m.userdefs[i].Indicator = u.Indicator
+				m.userdefs[i].Values = u.Values
+				found = true
+				break
+			}
+		}
+		if !found {
+			u.ID = int64(len(m.userdefs) + 1)
+			m.userdefs = append(m.userdefs, u)
+		}
+	}
+
+	return nil
+}
+
+// UserDefs returns all user-defined field declarations in the store
+func (m *MemoryStore) UserDefs(ctx context.Context) ([]UserDef, error) {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return []UserDef{}, err
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return append([]UserDef{}, m.userdefs...), nil
+}
+
+// FailedUploads returns no QSOs, there is no outbox
+func (m *MemoryStore) FailedUploads(ctx context.Context) ([]OutboxItem, error) {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return []OutboxItem{}, err
+	}
+
+	return []OutboxItem{}, nil
+}
+
+// UploadAttempts returns no attempts, they aren't kept
+func (m *MemoryStore) UploadAttempts(ctx context.Context, ID int64) ([]UploadAttempt, error) {
+	err := ctx.Err()
+	if err != nil {
+		log.Printf("%+v", err)
+		return []UploadAttempt{}, err
+	}
+
+	return []UploadAttempt{}, nil
+}
+
+// RetryUpload does nothing, there is no outbox
+func (m *MemoryStore) RetryUpload(ctx context.Context, o OutboxItem) error {
+	return ctx.Err()
+}
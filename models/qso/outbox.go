@@ -0,0 +1,406 @@
+package qso
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// UploadErrorClass is why an upload to a logbook service failed
+type UploadErrorClass string
+
+const (
+	// couldn't reach the service or it had a problem of its own, retried
+	ErrorTransport UploadErrorClass = "transport"
+
+	// the service refused the upload as a whole, ie bad credentials, retried
+	ErrorService UploadErrorClass = "service"
+
+	// the service says the qso itself is bad, not retried until the qso is edited or retried by hand
+	ErrorRejected UploadErrorClass = "rejected"
+)
+
+// OutboxState is where a qso that failed to upload is at
+type OutboxState string
+
+const (
+	OutboxRetrying OutboxState = "retrying"
+	OutboxRejected OutboxState = "rejected"
+)
+
+const (
+	// backoff doubles from the first retry up to the max
+	outboxFirstRetry = 5 * time.Minute
+	outboxMaxRetry   = 24 * time.Hour
+
+	// how much of the service's response is kept
+	maxUploadResponse = 1000
+)
+
+// UploadAttempt is one attempt at uploading a qso to a logbook service
+type UploadAttempt struct {
+	ID          int64            `db:"id" json:"id"`
+	QSOID       int64            `db:"qso_id" json:"qsoId"`
+	Service     QSLService       `db:"service" json:"service"`
+	AttemptedAt int64            `db:"attempted_at" json:"attemptedAt"`
+	ErrorClass  UploadErrorClass `db:"error_class" json:"errorClass,omitempty"`
+	Response    string           `db:"response" json:"response,omitempty"`
+}
+
+// OutboxItem is a qso that failed to upload to a logbook service
+type OutboxItem struct {
+	QSOID         int64            `db:"qso_id" json:"qsoId"`
+	Service       QSLService       `db:"service" json:"service"`
+	State         OutboxState      `db:"state" json:"state"`
+	Attempts      int              `db:"attempts" json:"attempts"`
+	LastAttemptAt int64            `db:"last_attempt_at" json:"lastAttemptAt"`
+	NextAttemptAt int64            `db:"next_attempt_at" json:"nextAttemptAt"`
+	ErrorClass    UploadErrorClass `db:"error_class" json:"errorClass"`
+	Response      string           `db:"response" json:"response"`
+
+	// the qso, so it can be shown without looking it up
+	Call string `db:"call" json:"call"`
+	Band string `db:"band" json:"band"`
+	Mode string `db:"mode" json:"mode"`
+	Date string `db:"qso_date" json:"date"`
+	Time string `db:"qso_time" json:"time"`
+}
+
+const (
+	stmtUploadAttemptInsert = `
+		insert into upload_attempts (
+			qso_id,
+			service,
+			attempted_at,
+			error_class,
+			response
+		) values (
+			:qso_id,
+			:service,
+			:attempted_at,
+			:error_class,
+			:response
+		)
+	`
+
+	stmtOutboxUpsert = `
+		insert into outbox (
+			qso_id,
+			service,
+			state,
+			attempts,
+			last_attempt_at,
+			next_attempt_at,
+			error_class,
+			response
+		) values (
+			:qso_id,
+			:service,
+			:state,
+			1,
+			:attempted_at,
+			:attempted_at + :backoff,
+			:error_class,
+			:response
+		)
+		on conflict(qso_id, service) do update set
+			state = excluded.state,
+			attempts = attempts + 1,
+			last_attempt_at = excluded.last_attempt_at,
+			next_attempt_at = excluded.last_attempt_at + min(:backoff << min(attempts, 20), :maxbackoff),
+			error_class = excluded.error_class,
+			response = excluded.response
+	`
+
+	stmtOutboxSelect = `
+		select
+			o.qso_id,
+			o.service,
+			o.state,
+			o.attempts,
+			o.last_attempt_at,
+			o.next_attempt_at,
+			o.error_class,
+			o.response,
+			q.call,
+			q.band,
+			q.mode,
+			q.qso_date,
+			q.qso_time
+		from
+			outbox o
+			join qsos q on q.id = o.qso_id
+		order by o.service asc, q.qso_date asc, q.qso_time asc
+	`
+
+	stmtUploadAttemptSelect = `
+		select
+			id,
+			qso_id,
+			service,
+			attempted_at,
+			error_class,
+			response
+		from
+			upload_attempts
+		where
+			qso_id = :qso_id
+		order by id asc
+	`
+
+	// qsos waiting out their backoff or rejected aren't sent
+	stmtOutboxHeld = `
+		id not in (
+			select qso_id from outbox where service = :service and (state = 'rejected' or next_attempt_at > :now)
+		)
+	`
+)
+
+// truncateResponse keeps the start of a service's response
+func truncateResponse(response string) string {
+	if len(response) > maxUploadResponse {
+		return response[:maxUploadResponse]
+	}
+	return response
+}
+
+// recordUploadAttempts records an attempt for each qso in the transaction
+func recordUploadAttempts(tx *sqlx.Tx, qsos []QSO, service QSLService, class UploadErrorClass, response string, attemptedAt int64) error {
+	q, err := tx.PrepareNamed(stmtUploadAttemptInsert)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	for _, qso := range qsos {
+		_, err = q.Exec(UploadAttempt{
+			QSOID:       qso.ID,
+			Service:     service,
+			AttemptedAt: attemptedAt,
+			ErrorClass:  class,
+			Response:    truncateResponse(response),
+		})
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clearOutbox removes the qso from the outbox for all services so it is sent as usual
+func clearOutbox(tx *sqlx.Tx, ID int64) error {
+	_, err := tx.Exec("delete from outbox where qso_id = ?", ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// UploadSucceeded marks the QSOs as sent to the service and records the attempt, the change is audited with the source of ctx
+func (s *SQLiteStore) UploadSucceeded(ctx context.Context, qsos []QSO, service QSLService, response string) error {
+	// in a transaction
+	tx, err := s.begin(ctx)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer func() {
+		// if we've had an error, rollback
+		if err != nil {
+			err = tx.Rollback()
+			if err != nil {
+				log.Printf("%+v", err)
+			}
+		}
+	}()
+
+	before, err := snapshot(tx, qsos)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = recordUploadAttempts(tx, qsos, service, "", response, time.Now().Unix())
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	q, err := tx.PrepareNamedContext(ctx, fmt.Sprintf("update qsos set %s = %d, %s = :sdate where id = :id", service, Sent, sentDateColumn(service)))
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	sdate := today()
+	for _, qso := range qsos {
+		_, err = q.ExecContext(ctx, map[string]interface{}{"id": qso.ID, "sdate": sdate})
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "delete from outbox where qso_id = ? and service = ?", qso.ID, service)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	after, err := snapshot(tx, qsos)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = recordAudit(tx, SourceFrom(ctx), AuditQSL, before, after)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	publishQSOEvent(QSLChanged, before, after)
+	return nil
+}
+
+// UploadFailed records the failed attempt and puts the QSOs in the outbox
+// rejected QSOs stay there until retried, others are retried with exponential backoff
+func (s *SQLiteStore) UploadFailed(ctx context.Context, qsos []QSO, service QSLService, class UploadErrorClass, response string) error {
+	// in a transaction
+	tx, err := s.begin(ctx)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer func() {
+		// if we've had an error, rollback
+		if err != nil {
+			err = tx.Rollback()
+			if err != nil {
+				log.Printf("%+v", err)
+			}
+		}
+	}()
+
+	attemptedAt := time.Now().Unix()
+	err = recordUploadAttempts(tx, qsos, service, class, response, attemptedAt)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	state := OutboxRetrying
+	if class == ErrorRejected {
+		state = OutboxRejected
+	}
+
+	q, err := tx.PrepareNamedContext(ctx, stmtOutboxUpsert)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	for _, qso := range qsos {
+		_, err = q.ExecContext(ctx, map[string]interface{}{
+			"qso_id":       qso.ID,
+			"service":      service,
+			"state":        state,
+			"attempted_at": attemptedAt,
+			"error_class":  class,
+			"response":     truncateResponse(response),
+			"backoff":      int64(outboxFirstRetry.Seconds()),
+			"maxbackoff":   int64(outboxMaxRetry.Seconds()),
+		})
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// FailedUploads returns the QSOs that failed to upload, by service and oldest first
+func (s *SQLiteStore) FailedUploads(ctx context.Context) ([]OutboxItem, error) {
+	var err error
+
+	if s.db == nil {
+		err = errNoConnection
+		log.Printf("%+v", err)
+		return []OutboxItem{}, err
+	}
+
+	var items []OutboxItem
+	err = s.db.SelectContext(ctx, &items, stmtOutboxSelect)
+	if err != nil {
+		log.Printf("%+v", err)
+		return []OutboxItem{}, err
+	}
+
+	return items, nil
+}
+
+// UploadAttempts returns every attempt at uploading the qso identified by ID, oldest first
+func (s *SQLiteStore) UploadAttempts(ctx context.Context, ID int64) ([]UploadAttempt, error) {
+	var err error
+
+	if s.db == nil {
+		err = errNoConnection
+		log.Printf("%+v", err)
+		return []UploadAttempt{}, err
+	}
+
+	q, err := s.db.PrepareNamedContext(ctx, stmtUploadAttemptSelect)
+	if err != nil {
+		log.Printf("%+v", err)
+		return []UploadAttempt{}, err
+	}
+	defer q.Close()
+
+	var attempts []UploadAttempt
+	err = q.SelectContext(ctx, &attempts, map[string]interface{}{"qso_id": ID})
+	if err != nil {
+		log.Printf("%+v", err)
+		return []UploadAttempt{}, err
+	}
+
+	return attempts, nil
+}
+
+// RetryUpload takes the qso out of the outbox so the next upload to the service sends it
+func (s *SQLiteStore) RetryUpload(ctx context.Context, o OutboxItem) error {
+	var err error
+
+	if s.db == nil {
+		err = errNoConnection
+		log.Printf("%+v", err)
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, "delete from outbox where qso_id = ? and service = ?", o.QSOID, o.Service)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
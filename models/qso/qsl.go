@@ -0,0 +1,186 @@
+package qso
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// QSLRcvd is the adif qsl received status
+type QSLRcvd string
+
+const (
+	NotReceived  QSLRcvd = "N"
+	Received     QSLRcvd = "Y"
+	RcvdRequest  QSLRcvd = "R"
+	RcvdIgnore   QSLRcvd = "I"
+	RcvdVerified QSLRcvd = "V"
+)
+
+// QSLVia is how a paper qsl card was sent or received
+type QSLVia string
+
+const (
+	ViaBureau     QSLVia = "B"
+	ViaDirect     QSLVia = "D"
+	ViaElectronic QSLVia = "E"
+	ViaManager    QSLVia = "M"
+)
+
+// Confirmed returns true if the status means the qso was confirmed
+func (r QSLRcvd) Confirmed() bool {
+	return r == Received || r == RcvdVerified
+}
+
+// defaultQSLRcvd sets the received status the database defaults to when it isn't known
+func (qso *QSO) defaultQSLRcvd() {
+	for _, r := range []*QSLRcvd{&qso.QSLLotwRcvd, &qso.QSLQrzRcvd, &qso.QSLClublogRcvd, &qso.QSLEqslRcvd, &qso.QSLCardRcvd} {
+		if *r == "" {
+			*r = NotReceived
+		}
+	}
+}
+
+// columns holding the dates & received status for a service
+func sentDateColumn(service QSLService) string {
+	return string(service) + "_sdate"
+}
+
+func rcvdColumn(service QSLService) string {
+	return string(service) + "_rcvd"
+}
+
+func rcvdDateColumn(service QSLService) string {
+	return string(service) + "_rdate"
+}
+
+// sentFields returns the fields of the qso holding whether & when it was sent to a service, nil for unknown services
+func (qso *QSO) sentFields(service QSLService) (*QSLSent, *string) {
+	switch service {
+	case QSLLotw:
+		return &qso.QSLLotw, &qso.QSLLotwSentDate
+	case QSLQrz:
+		return &qso.QSLQrz, &qso.QSLQrzSentDate
+	case QSLClublog:
+		return &qso.QSLClublog, &qso.QSLClublogSentDate
+	case QSLEqsl:
+		return &qso.QSLEqsl, &qso.QSLEqslSentDate
+	case QSLCard:
+		return &qso.QSLCard, &qso.QSLCardSentDate
+	}
+	return nil, nil
+}
+
+// rcvdFields returns the fields of the qso holding whether & when it was received from a service, nil for unknown services
+func (qso *QSO) rcvdFields(service QSLService) (*QSLRcvd, *string) {
+	switch service {
+	case QSLLotw:
+		return &qso.QSLLotwRcvd, &qso.QSLLotwRcvdDate
+	case QSLQrz:
+		return &qso.QSLQrzRcvd, &qso.QSLQrzRcvdDate
+	case QSLClublog:
+		return &qso.QSLClublogRcvd, &qso.QSLClublogRcvdDate
+	case QSLEqsl:
+		return &qso.QSLEqslRcvd, &qso.QSLEqslRcvdDate
+	case QSLCard:
+		return &qso.QSLCardRcvd, &qso.QSLCardRcvdDate
+	}
+	return nil, nil
+}
+
+// today returns the current utc date the way qso dates are stored
+func today() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// LastQSLReceived returns the latest date a QSL was received from a service, empty if there hasn't been one
+func (s *SQLiteStore) LastQSLReceived(ctx context.Context, service QSLService) (string, error) {
+	var err error
+
+	if s.db == nil {
+		err = errNoConnection
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	var date string
+	err = s.db.GetContext(ctx, &date, fmt.Sprintf("select coalesce(max(%s), '') from qsos", rcvdDateColumn(service)))
+	if err != nil {
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	return date, nil
+}
+
+// UpdateQSLReceived updates the QSOs QSL received status for a service, date is yyyy-mm-dd and defaults to today
+// the change is audited with the source of ctx
+func (s *SQLiteStore) UpdateQSLReceived(ctx context.Context, qsos []QSO, service QSLService, rcvd QSLRcvd, date string) error {
+	if date == "" && rcvd.Confirmed() {
+		date = today()
+	}
+
+	// in a transaction
+	tx, err := s.begin(ctx)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer func() {
+		// if we've had an error, rollback
+		if err != nil {
+			err = tx.Rollback()
+			if err != nil {
+				log.Printf("%+v", err)
+			}
+		}
+	}()
+
+	before, err := snapshot(tx, qsos)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	q, err := tx.PrepareNamedContext(ctx, fmt.Sprintf("update qsos set %s = :rcvd, %s = :rdate where id = :id", rcvdColumn(service), rcvdDateColumn(service)))
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	for _, qso := range qsos {
+		params := map[string]interface{}{
+			"id":    qso.ID,
+			"rcvd":  rcvd,
+			"rdate": date,
+		}
+
+		_, err = q.ExecContext(ctx, params)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	after, err := snapshot(tx, qsos)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = recordAudit(tx, SourceFrom(ctx), AuditQSL, before, after)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	publishQSOEvent(QSLChanged, before, after)
+	return nil
+}
@@ -1,11 +1,14 @@
 package qso
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/bbathe/golog/db"
+	"github.com/bbathe/golog/dxcc"
 )
 
 type QSLSent int64
@@ -21,6 +24,7 @@ const (
 	QSLLotw    = "qsl_lotw"
 	QSLQrz     = "qsl_qrz"
 	QSLClublog = "qsl_clublog"
+	QSLEqsl    = "qsl_eqsl"
 	QSLCard    = "qsl_card"
 )
 
@@ -34,18 +38,59 @@ type QSO struct {
 	Mode    string `db:"mode"`
 	Date    string `db:"qso_date"`
 	Time    string `db:"qso_time"`
+	TimeOff string `db:"qso_time_off"`
 	RSTRcvd string `db:"rst_rcvd"`
 	RSTSent string `db:"rst_sent"`
 
+	// frequencies are in Hz, power in watts, zero when unknown
+	Frequency   int64   `db:"freq"`
+	FrequencyRx int64   `db:"freq_rx"`
+	TxPower     float64 `db:"tx_pwr"`
+
+	// contacted station's dxcc entity, grid & zones, zero or empty when unknown
+	DXCC       int    `db:"dxcc"`
+	GridSquare string `db:"gridsquare"`
+	CQZone     int    `db:"cqz"`
+	ITUZone    int    `db:"ituz"`
+
 	QSLLotw    QSLSent `db:"qsl_lotw"`
 	QSLQrz     QSLSent `db:"qsl_qrz"`
 	QSLClublog QSLSent `db:"qsl_clublog"`
+	QSLEqsl    QSLSent `db:"qsl_eqsl"`
 	QSLCard    QSLSent `db:"qsl_card"`
+
+	// when qsls were sent & received, dates are yyyy-mm-dd, empty when unknown
+	QSLLotwSentDate    string  `db:"qsl_lotw_sdate"`
+	QSLLotwRcvd        QSLRcvd `db:"qsl_lotw_rcvd"`
+	QSLLotwRcvdDate    string  `db:"qsl_lotw_rdate"`
+	QSLQrzSentDate     string  `db:"qsl_qrz_sdate"`
+	QSLQrzRcvd         QSLRcvd `db:"qsl_qrz_rcvd"`
+	QSLQrzRcvdDate     string  `db:"qsl_qrz_rdate"`
+	QSLClublogSentDate string  `db:"qsl_clublog_sdate"`
+	QSLClublogRcvd     QSLRcvd `db:"qsl_clublog_rcvd"`
+	QSLClublogRcvdDate string  `db:"qsl_clublog_rdate"`
+	QSLEqslSentDate    string  `db:"qsl_eqsl_sdate"`
+	QSLEqslRcvd        QSLRcvd `db:"qsl_eqsl_rcvd"`
+	QSLEqslRcvdDate    string  `db:"qsl_eqsl_rdate"`
+	QSLCardSentDate    string  `db:"qsl_card_sdate"`
+	QSLCardSentVia     QSLVia  `db:"qsl_card_sent_via"`
+	QSLCardRcvd        QSLRcvd `db:"qsl_card_rcvd"`
+	QSLCardRcvdDate    string  `db:"qsl_card_rdate"`
+	QSLCardRcvdVia     QSLVia  `db:"qsl_card_rcvd_via"`
+
+	// id of the copy in the QRZ.com logbook, zero when not known
+	QRZLogID int64 `db:"qrz_logid"`
+
+	Extra ExtraFields `db:"extra_fields"`
 }
 
+// ids of deleted qsos aren't reused so their audit trail stays theirs and they can be restored
+const qsoNextID = "(select max((select coalesce(max(id), 0) from qsos), (select coalesce(max(qso_id), 0) from audit)) + 1)"
+
 const (
 	stmtQSOInsert = `
 		insert into qsos (
+			id,
 			loaded_at,
 			station_callsign,
 			band,
@@ -53,13 +98,42 @@ const (
 			mode,
 			qso_date,
 			qso_time,
+			qso_time_off,
 			rst_rcvd,
 			rst_sent,
+			freq,
+			freq_rx,
+			tx_pwr,
+			dxcc,
+			gridsquare,
+			cqz,
+			ituz,
 			qsl_lotw,
 			qsl_qrz,
 			qsl_clublog,
-			qsl_card
+			qsl_eqsl,
+			qsl_card,
+			qsl_lotw_sdate,
+			qsl_lotw_rcvd,
+			qsl_lotw_rdate,
+			qsl_qrz_sdate,
+			qsl_qrz_rcvd,
+			qsl_qrz_rdate,
+			qsl_clublog_sdate,
+			qsl_clublog_rcvd,
+			qsl_clublog_rdate,
+			qsl_eqsl_sdate,
+			qsl_eqsl_rcvd,
+			qsl_eqsl_rdate,
+			qsl_card_sdate,
+			qsl_card_sent_via,
+			qsl_card_rcvd,
+			qsl_card_rdate,
+			qsl_card_rcvd_via,
+			qrz_logid,
+			extra_fields
 		) values (
+			` + qsoNextID + `,
 			:loaded_at,
 			:station_callsign,
 			:band,
@@ -67,18 +141,47 @@ const (
 			:mode,
 			:qso_date,
 			:qso_time,
+			:qso_time_off,
 			:rst_rcvd,
 			:rst_sent,
+			:freq,
+			:freq_rx,
+			:tx_pwr,
+			:dxcc,
+			:gridsquare,
+			:cqz,
+			:ituz,
 			:qsl_lotw,
 			:qsl_qrz,
 			:qsl_clublog,
-			:qsl_card
+			:qsl_eqsl,
+			:qsl_card,
+			:qsl_lotw_sdate,
+			:qsl_lotw_rcvd,
+			:qsl_lotw_rdate,
+			:qsl_qrz_sdate,
+			:qsl_qrz_rcvd,
+			:qsl_qrz_rdate,
+			:qsl_clublog_sdate,
+			:qsl_clublog_rcvd,
+			:qsl_clublog_rdate,
+			:qsl_eqsl_sdate,
+			:qsl_eqsl_rcvd,
+			:qsl_eqsl_rdate,
+			:qsl_card_sdate,
+			:qsl_card_sent_via,
+			:qsl_card_rcvd,
+			:qsl_card_rdate,
+			:qsl_card_rcvd_via,
+			:qrz_logid,
+			:extra_fields
 		)
 		on conflict(station_callsign, band, call, mode, qso_date, qso_time) do nothing
 	`
 
 	stmtQSOOnlyInsert = `
 		insert into qsos (
+			id,
 			loaded_at,
 			station_callsign,
 			band,
@@ -86,9 +189,19 @@ const (
 			mode,
 			qso_date,
 			qso_time,
+			qso_time_off,
 			rst_rcvd,
-			rst_sent
+			rst_sent,
+			freq,
+			freq_rx,
+			tx_pwr,
+			dxcc,
+			gridsquare,
+			cqz,
+			ituz,
+			extra_fields
 		) values (
+			` + qsoNextID + `,
 			:loaded_at,
 			:station_callsign,
 			:band,
@@ -96,8 +209,17 @@ const (
 			:mode,
 			:qso_date,
 			:qso_time,
+			:qso_time_off,
 			:rst_rcvd,
-			:rst_sent
+			:rst_sent,
+			:freq,
+			:freq_rx,
+			:tx_pwr,
+			:dxcc,
+			:gridsquare,
+			:cqz,
+			:ituz,
+			:extra_fields
 		)
 		on conflict(station_callsign, band, call, mode, qso_date, qso_time) do nothing
 	`
@@ -112,12 +234,40 @@ const (
 			mode,
 			qso_date,
 			qso_time,
+			qso_time_off,
 			rst_rcvd,
 			rst_sent,
+			freq,
+			freq_rx,
+			tx_pwr,
+			dxcc,
+			gridsquare,
+			cqz,
+			ituz,
 			qsl_lotw,
 			qsl_qrz,
 			qsl_clublog,
-			qsl_card
+			qsl_eqsl,
+			qsl_card,
+			qsl_lotw_sdate,
+			qsl_lotw_rcvd,
+			qsl_lotw_rdate,
+			qsl_qrz_sdate,
+			qsl_qrz_rcvd,
+			qsl_qrz_rdate,
+			qsl_clublog_sdate,
+			qsl_clublog_rcvd,
+			qsl_clublog_rdate,
+			qsl_eqsl_sdate,
+			qsl_eqsl_rcvd,
+			qsl_eqsl_rdate,
+			qsl_card_sdate,
+			qsl_card_sent_via,
+			qsl_card_rcvd,
+			qsl_card_rdate,
+			qsl_card_rcvd_via,
+			qrz_logid,
+			extra_fields
 		from
 			qsos
 	`
@@ -148,37 +298,26 @@ const (
 			mode = :mode,
 			qso_date = :qso_date,
 			qso_time = :qso_time,
+			qso_time_off = :qso_time_off,
 			rst_rcvd = :rst_rcvd,
-			rst_sent = :rst_sent
+			rst_sent = :rst_sent,
+			freq = :freq,
+			freq_rx = :freq_rx,
+			tx_pwr = :tx_pwr,
+			dxcc = :dxcc,
+			gridsquare = :gridsquare,
+			cqz = :cqz,
+			ituz = :ituz,
+			extra_fields = :extra_fields
 		where
 			id = :id
 	`
 )
 
-var (
-	errNoConnection = fmt.Errorf("no database connection")
+// stmtQSORestore inserts a deleted qso with the id it had
+var stmtQSORestore = strings.Replace(stmtQSOInsert, qsoNextID, ":id", 1)
 
-	handlers []QSOChangeEventHandler
-)
-
-// allow callers to register to recieve event after any qso changes occur
-type QSOChangeEventHandler func()
-
-func Attach(handler QSOChangeEventHandler) int {
-	handlers = append(handlers, handler)
-
-	return len(handlers) - 1
-}
-
-func Detach(handle int) {
-	handlers[handle] = nil
-}
-
-func publishQSOChange() {
-	for _, h := range handlers {
-		h()
-	}
-}
+var errNoConnection = fmt.Errorf("no database connection")
 
 // Validate tests the required QSO fields
 func (qso *QSO) Validate(checkID bool) error {
@@ -199,6 +338,18 @@ func (qso *QSO) Validate(checkID bool) error {
 		log.Printf("%+v", err)
 		return err
 	}
+	if _, err := time.Parse("15:04:05", qso.Time); err != nil {
+		err = fmt.Errorf("invalid Time %s, expected hh:mm:ss", qso.Time)
+		log.Printf("%+v", err)
+		return err
+	}
+	if qso.TimeOff != "" {
+		if _, err := time.Parse("15:04:05", qso.TimeOff); err != nil {
+			err = fmt.Errorf("invalid TimeOff %s, expected hh:mm:ss", qso.TimeOff)
+			log.Printf("%+v", err)
+			return err
+		}
+	}
 	if qso.Call == "" {
 		err := fmt.Errorf(missingField, "Call")
 		log.Printf("%+v", err)
@@ -228,215 +379,41 @@ func (qso *QSO) Validate(checkID bool) error {
 	return nil
 }
 
-// Add inserts a single QSO into the qso database
-// sets qso.LoadedAt before insert
-// does not insert QSL fields, they default to false
-func (qso *QSO) Add() error {
-	var err error
-
-	if db.QSODb == nil {
-		err = errNoConnection
-		log.Printf("%+v", err)
-		return err
+// resolveDXCC fills in the contacted station's dxcc entity & zones from the country files when they aren't known
+// values that are already set are kept, zones only come from the same entity
+func (qso *QSO) resolveDXCC() {
+	if qso.DXCC != 0 && qso.CQZone != 0 && qso.ITUZone != 0 {
+		return
 	}
 
-	// set LoadedAt to now
-	qso.LoadedAt = time.Now().Unix()
-
-	err = qso.Validate(false)
+	// qso date & time are utc, entities can change during a day
+	at, err := time.Parse("2006-01-02 15:04:05", qso.Date+" "+qso.Time)
 	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-
-	qsoInsert, err := db.QSODb.PrepareNamed(stmtQSOOnlyInsert)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-
-	_, err = qsoInsert.Exec(qso)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-
-	publishQSOChange()
-	return nil
-}
-
-// BulkAdd inserts all QSOs into the qso database
-// assumes qso.LoadedAt was already set
-func BulkAdd(qsos []QSO) error {
-	var err error
-
-	if db.QSODb == nil {
-		err = errNoConnection
-		log.Printf("%+v", err)
-		return err
-	}
-
-	// in a transaction
-	tx := db.QSODb.MustBegin()
-	defer func() {
-		// if we've had an error, rollback
+		at, err = time.Parse("2006-01-02", qso.Date)
 		if err != nil {
-			err = tx.Rollback()
-			if err != nil {
-				log.Printf("%+v", err)
-			}
+			at = time.Now().UTC()
 		}
-	}()
-
-	qsoInsert, err := tx.PrepareNamed(stmtQSOInsert)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-
-	// insert all qsos
-	for _, qso := range qsos {
-		err := qso.Validate(false)
-		if err != nil {
-			log.Printf("%+v", err)
-			return err
-		}
-
-		// create qso record
-		_, err = qsoInsert.Exec(qso)
-		if err != nil {
-			log.Printf("%+v", err)
-			return err
-		}
-	}
-
-	err = tx.Commit()
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-
-	publishQSOChange()
-	return nil
-}
-
-// UpdateOnlyQSO updates the QSO only fields in the qso database
-// does not update QSL fields
-func (qso *QSO) UpdateOnlyQSO() error {
-	var err error
-
-	if db.QSODb == nil {
-		err = errNoConnection
-		log.Printf("%+v", err)
-		return err
-	}
-
-	err = qso.Validate(true)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-
-	// in a transaction
-	tx := db.QSODb.MustBegin()
-	defer func() {
-		// if we've had an error, rollback
-		if err != nil {
-			err = tx.Rollback()
-			if err != nil {
-				log.Printf("%+v", err)
-			}
-		}
-	}()
-
-	// see if there is already a qso matching this update
-	q, err := tx.PrepareNamed(stmtQSOSelectDupTest)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-
-	var qsos []QSO
-	err = q.Select(&qsos, qso)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-
-	// if what we found is not this qso then error out
-	if len(qsos) > 0 && qsos[0].ID != qso.ID {
-		err = fmt.Errorf("update would cause duplicate qso")
-		log.Printf("%+v", err)
-		return err
-	}
-
-	// good to go with update
-	q, err = tx.PrepareNamed(stmtQSOOnlyUpdate)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-
-	_, err = q.Exec(qso)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
+	e, ok := dxcc.Lookup(qso.Call, at)
+	if !ok || (qso.DXCC != 0 && e.DXCC != 0 && qso.DXCC != e.DXCC) {
+		return
 	}
 
-	publishQSOChange()
-	return nil
-}
-
-// Delete removes a qso from the qso database
-func (qso *QSO) Delete() error {
-	var err error
-
-	if db.QSODb == nil {
-		err = errNoConnection
-		log.Printf("%+v", err)
-		return err
+	if qso.DXCC == 0 {
+		qso.DXCC = e.DXCC
 	}
-
-	err = qso.Validate(true)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
+	if qso.CQZone == 0 {
+		qso.CQZone = e.CQZone
 	}
-
-	q, err := db.QSODb.PrepareNamed(stmtQSODelete)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-
-	_, err = q.Exec(qso)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
+	if qso.ITUZone == 0 {
+		qso.ITUZone = e.ITUZone
 	}
-
-	publishQSOChange()
-	return nil
 }
 
 // All returns all QSOs in the qso database
-func All() ([]QSO, error) {
-	var err error
-
-	if db.QSODb == nil {
-		err = errNoConnection
-		log.Printf("%+v", err)
-		return []QSO{}, err
-	}
-
-	var qsos []QSO
-	err = db.QSODb.Select(&qsos, stmtQSOSelectAll)
+func (s *SQLiteStore) All(ctx context.Context) ([]QSO, error) {
+	qsos, err := s.selectQSOs(ctx, stmtQSOSelectAll, map[string]interface{}{})
 	if err != nil {
 		log.Printf("%+v", err)
 		return []QSO{}, err
@@ -445,93 +422,20 @@ func All() ([]QSO, error) {
 	return qsos, nil
 }
 
-// Get returns a single QSO identified by ID
-func Get(ID int64) (*QSO, error) {
-	var err error
-
-	if db.QSODb == nil {
-		err = errNoConnection
-		log.Printf("%+v", err)
-		return nil, err
-	}
-
-	// start with All and add where clause to get single qso
-	stmt := stmtQSOSelectAll
-	stmt += " where id = :id"
-
-	q, err := db.QSODb.PrepareNamed(stmt)
-	if err != nil {
-		log.Printf("%+v", err)
-		return nil, err
-	}
-
-	params := map[string]interface{}{
-		"id": ID,
-	}
-
-	var qso []QSO
-	err = q.Select(&qso, params)
-	if err != nil {
-		log.Printf("%+v", err)
-		return nil, err
-	}
-
-	return &qso[0], nil
-}
-
-// Search returns all QSOs matching the criteria limited by count limit
-func Search(criteria QSO, limit int) ([]QSO, error) {
-	var err error
-
-	if db.QSODb == nil {
-		err = errNoConnection
-		log.Printf("%+v", err)
-		return []QSO{}, err
-	}
-
-	// query parameters
+// Between returns all QSOs made from from thru to, oldest first
+func (s *SQLiteStore) Between(ctx context.Context, from, to time.Time) ([]QSO, error) {
+	// dates & times are stored as text in utc so they compare in order
 	params := map[string]interface{}{
-		"limit": limit,
+		"from": from.UTC().Format("2006-01-02 15:04:05"),
+		"to":   to.UTC().Format("2006-01-02 15:04:05"),
 	}
 
-	// start with All and add where clause dynamically
+	// start with All and add where clause
 	stmt := stmtQSOSelectAll
+	stmt += " where qso_date || ' ' || qso_time >= :from and qso_date || ' ' || qso_time <= :to"
+	stmt += " order by qso_date asc, qso_time asc"
 
-	needAnd := false
-	appendFieldCriteria := func(s, f, v string) string {
-		if v != "" {
-			if needAnd {
-				s += " and "
-			} else {
-				s += " where "
-			}
-			s += fmt.Sprintf(" %s like :%s ", f, f)
-			params[f] = v
-			needAnd = true
-		}
-
-		return s
-	}
-
-	stmt = appendFieldCriteria(stmt, "qso_date", criteria.Date)
-	stmt = appendFieldCriteria(stmt, "qso_time", criteria.Time)
-	stmt = appendFieldCriteria(stmt, "call", criteria.Call)
-	stmt = appendFieldCriteria(stmt, "band", criteria.Band)
-	stmt = appendFieldCriteria(stmt, "mode", criteria.Mode)
-	stmt = appendFieldCriteria(stmt, "rst_rcvd", criteria.RSTRcvd)
-	stmt = appendFieldCriteria(stmt, "rst_sent", criteria.RSTSent)
-
-	// limit result set and order so we get the latest qsos
-	stmt += " order by qso_date desc, qso_time desc limit :limit"
-
-	q, err := db.QSODb.PrepareNamed(stmt)
-	if err != nil {
-		log.Printf("%+v", err)
-		return []QSO{}, err
-	}
-
-	var qsos []QSO
-	err = q.Select(&qsos, params)
+	qsos, err := s.selectQSOs(ctx, stmt, params)
 	if err != nil {
 		log.Printf("%+v", err)
 		return []QSO{}, err
@@ -540,111 +444,55 @@ func Search(criteria QSO, limit int) ([]QSO, error) {
 	return qsos, nil
 }
 
-// History returns all QSO loaded after days ago limited by count limit
-func History(days, limit int) ([]QSO, error) {
-	var err error
-
-	if db.QSODb == nil {
-		err = errNoConnection
-		log.Printf("%+v", err)
-		return []QSO{}, err
+// Near returns the QSOs with call on band made within window of at, closest first
+func (s *SQLiteStore) Near(ctx context.Context, call, band string, at time.Time, window time.Duration) ([]QSO, error) {
+	params := map[string]interface{}{
+		"call": call,
+		"band": band,
+		"from": at.Add(-window).UTC().Format("2006-01-02 15:04:05"),
+		"to":   at.Add(window).UTC().Format("2006-01-02 15:04:05"),
 	}
 
-	// query parameters
-	params := map[string]interface{}{}
-
-	// start with All and add where clause
 	stmt := stmtQSOSelectAll
+	stmt += " where call = :call and band = :band"
+	stmt += " and qso_date || ' ' || qso_time >= :from and qso_date || ' ' || qso_time <= :to"
 
-	// how much history?
-	if days > 0 {
-		now := time.Now()
-		then := now.Add(-(time.Duration(days*24) * time.Hour)).Unix()
-		stmt += " where loaded_at >= :loadedat"
-		params["loadedat"] = then
-	}
-
-	// order so we get the latest qsos
-	stmt += " order by qso_date desc, qso_time desc"
-
-	// limit result set
-	if limit > 0 {
-		stmt += "  limit :limit"
-		params["limit"] = limit
-	}
-
-	q, err := db.QSODb.PrepareNamed(stmt)
-	if err != nil {
-		log.Printf("%+v", err)
-		return []QSO{}, err
-	}
-
-	var qsos []QSO
-	err = q.Select(&qsos, params)
+	qsos, err := s.selectQSOs(ctx, stmt, params)
 	if err != nil {
 		log.Printf("%+v", err)
 		return []QSO{}, err
 	}
 
+	sortNear(qsos, at, window)
 	return qsos, nil
 }
 
-// FindQSLsToSend returns all QSOs that need QSLs for a specific service before delay minutes ago
-func FindQSLsToSend(service QSLService, delay int) ([]QSO, error) {
-	var err error
-
-	if db.QSODb == nil {
-		err = errNoConnection
-		log.Printf("%+v", err)
-		return []QSO{}, err
-	}
-
-	// query parameters
-	params := map[string]interface{}{}
-
-	// start with All and add where clause
-	stmt := stmtQSOSelectAll
-	stmt += fmt.Sprintf(" where %s = 0", service)
-
-	// handle delay for sending QSL
-	if delay > 0 {
-		now := time.Now()
-		then := now.Add(-(time.Duration(delay) * time.Minute)).Unix()
-		stmt += " and loaded_at <= :loadedat"
-		params["loadedat"] = then
-	}
-
-	// return results to caller with oldest first
-	stmt += " order by qso_date asc, qso_time asc"
-
-	q, err := db.QSODb.PrepareNamed(stmt)
-	if err != nil {
-		log.Printf("%+v", err)
-		return []QSO{}, err
-	}
-
-	var qsos []QSO
-	err = q.Select(&qsos, params)
-	if err != nil {
-		log.Printf("%+v", err)
-		return []QSO{}, err
+// sortNear sorts the qsos closest in time to at first
+func sortNear(qsos []QSO, at time.Time, window time.Duration) {
+	distance := func(q QSO) time.Duration {
+		t, err := time.Parse("2006-01-02 15:04:05", q.Date+" "+q.Time)
+		if err != nil {
+			return window
+		}
+		d := t.Sub(at.UTC())
+		if d < 0 {
+			return -d
+		}
+		return d
 	}
-
-	return qsos, nil
+	sort.SliceStable(qsos, func(i, j int) bool {
+		return distance(qsos[i]) < distance(qsos[j])
+	})
 }
 
-// UpdateQSL updates the QSOs QSL status
-func UpdateQSL(qsos []QSO, service QSLService, sent QSLSent) error {
-	var err error
-
-	if db.QSODb == nil {
-		err = errNoConnection
+// UpdateLocation updates the dxcc entity, grid & zones of the QSOs, the change is audited with the source of ctx
+func (s *SQLiteStore) UpdateLocation(ctx context.Context, qsos []QSO) error {
+	// in a transaction
+	tx, err := s.begin(ctx)
+	if err != nil {
 		log.Printf("%+v", err)
 		return err
 	}
-
-	// in a transaction
-	tx := db.QSODb.MustBegin()
 	defer func() {
 		// if we've had an error, rollback
 		if err != nil {
@@ -655,26 +503,44 @@ func UpdateQSL(qsos []QSO, service QSLService, sent QSLSent) error {
 		}
 	}()
 
-	q, err := tx.PrepareNamed(fmt.Sprintf("update qsos set %s = %d where id = :id", service, sent))
+	before, err := snapshot(tx, qsos)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	q, err := tx.PrepareNamedContext(ctx, "update qsos set dxcc = :dxcc, gridsquare = :gridsquare, cqz = :cqz, ituz = :ituz where id = :id")
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
 	}
 
 	for _, qso := range qsos {
-		_, err = q.Exec(qso)
+		_, err = q.ExecContext(ctx, qso)
 		if err != nil {
 			log.Printf("%+v", err)
 			return err
 		}
 	}
 
+	after, err := snapshot(tx, qsos)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = recordAudit(tx, SourceFrom(ctx), AuditUpdate, before, after)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
 	}
 
-	publishQSOChange()
+	publishQSOEvent(QSOUpdated, before, after)
 	return nil
 }
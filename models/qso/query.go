@@ -0,0 +1,406 @@
+package qso
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Field is a QSO field queries can test & sort by
+type Field string
+
+const (
+	FieldID      Field = "id"
+	FieldLoaded  Field = "loaded"
+	FieldStation Field = "station"
+	FieldDate    Field = "date"
+	FieldTime    Field = "time"
+	FieldCall    Field = "call"
+	FieldBand    Field = "band"
+	FieldMode    Field = "mode"
+	FieldRSTRcvd Field = "rstrcvd"
+	FieldRSTSent Field = "rstsent"
+	FieldFreq    Field = "freq"
+	FieldFreqRx  Field = "freqrx"
+	FieldPower   Field = "power"
+	FieldDXCC    Field = "dxcc"
+	FieldGrid    Field = "grid"
+	FieldCQZone  Field = "cqz"
+	FieldITUZone Field = "ituz"
+
+	// qsl status for each service
+	FieldLotw    Field = "lotw"
+	FieldQrz     Field = "qrz"
+	FieldClublog Field = "clublog"
+	FieldEqsl    Field = "eqsl"
+	FieldCard    Field = "card"
+)
+
+// Operator is how a condition compares a field with its values
+type Operator string
+
+const (
+	OpEqual        Operator = "="
+	OpLike         Operator = "like"
+	OpLess         Operator = "<"
+	OpLessEqual    Operator = "<="
+	OpGreater      Operator = ">"
+	OpGreaterEqual Operator = ">="
+)
+
+// QSLStatus is what a condition on a qsl field tests for
+type QSLStatus string
+
+const (
+	QSLStatusSent      QSLStatus = "sent"
+	QSLStatusConfirmed QSLStatus = "confirmed"
+	QSLStatusRequested QSLStatus = "requested"
+)
+
+type fieldKind int
+
+const (
+	kindText fieldKind = iota
+	kindNumber
+	kindFrequency
+	kindQSL
+)
+
+// queryField is where a field is kept and how it's compared
+type queryField struct {
+	column  string
+	kind    fieldKind
+	service QSLService
+	text    func(QSO) string
+	number  func(QSO) float64
+}
+
+var queryFields = map[Field]queryField{
+	FieldID:      {column: "id", kind: kindNumber, number: func(q QSO) float64 { return float64(q.ID) }},
+	FieldLoaded:  {column: "loaded_at", kind: kindNumber, number: func(q QSO) float64 { return float64(q.LoadedAt) }},
+	FieldStation: {column: "station_callsign", text: func(q QSO) string { return q.StationCallsign }},
+	FieldDate:    {column: "qso_date", text: func(q QSO) string { return q.Date }},
+	FieldTime:    {column: "qso_time", text: func(q QSO) string { return q.Time }},
+	FieldCall:    {column: "call", text: func(q QSO) string { return q.Call }},
+	FieldBand:    {column: "band", text: func(q QSO) string { return q.Band }},
+	FieldMode:    {column: "mode", text: func(q QSO) string { return q.Mode }},
+	FieldRSTRcvd: {column: "rst_rcvd", text: func(q QSO) string { return q.RSTRcvd }},
+	FieldRSTSent: {column: "rst_sent", text: func(q QSO) string { return q.RSTSent }},
+	FieldFreq:    {column: "freq", kind: kindFrequency, number: func(q QSO) float64 { return float64(q.Frequency) }},
+	FieldFreqRx:  {column: "freq_rx", kind: kindFrequency, number: func(q QSO) float64 { return float64(q.FrequencyRx) }},
+	FieldPower:   {column: "tx_pwr", kind: kindNumber, number: func(q QSO) float64 { return q.TxPower }},
+	FieldDXCC:    {column: "dxcc", kind: kindNumber, number: func(q QSO) float64 { return float64(q.DXCC) }},
+	FieldGrid:    {column: "gridsquare", text: func(q QSO) string { return q.GridSquare }},
+	FieldCQZone:  {column: "cqz", kind: kindNumber, number: func(q QSO) float64 { return float64(q.CQZone) }},
+	FieldITUZone: {column: "ituz", kind: kindNumber, number: func(q QSO) float64 { return float64(q.ITUZone) }},
+	FieldLotw:    {column: QSLLotw, kind: kindQSL, service: QSLLotw},
+	FieldQrz:     {column: QSLQrz, kind: kindQSL, service: QSLQrz},
+	FieldClublog: {column: QSLClublog, kind: kindQSL, service: QSLClublog},
+	FieldEqsl:    {column: QSLEqsl, kind: kindQSL, service: QSLEqsl},
+	FieldCard:    {column: QSLCard, kind: kindQSL, service: QSLCard},
+}
+
+// Condition tests a field against its values, it matches when any of the values match
+// text fields compare case insensitive for OpEqual and like sql like for OpLike
+// frequencies are in Hz and match anywhere within the same kHz for OpEqual
+// qsl fields only support OpEqual with QSLStatus values
+type Condition struct {
+	Field  Field
+	Op     Operator
+	Values []string
+	Not    bool
+}
+
+// Group is a set of conditions that all have to match
+type Group []Condition
+
+// SortKey is a field to order the QSOs by
+type SortKey struct {
+	Field Field
+	Desc  bool
+}
+
+// Query selects QSOs, they match when they match every condition in any one of the groups, no groups matches all QSOs
+// QSOs are ordered by the sort keys then ID, latest first when there are none
+// After is the ID of the last QSO of the previous page, the page starts with the QSO after it in this order
+// Offset skips QSOs after that and Limit is the most QSOs returned, zero for all of them
+type Query struct {
+	Groups []Group
+	Sort   []SortKey
+	Limit  int
+	Offset int
+	After  int64
+}
+
+// defaultSort is the latest qsos first
+var defaultSort = []SortKey{
+	{Field: FieldDate, Desc: true},
+	{Field: FieldTime, Desc: true},
+}
+
+// sortKeys returns the keys qsos are ordered by, always ending with the ID so the order is stable
+func (q Query) sortKeys() []SortKey {
+	keys := q.Sort
+	if len(keys) == 0 {
+		keys = defaultSort
+	}
+
+	desc := keys[len(keys)-1].Desc
+	return append(append([]SortKey{}, keys...), SortKey{Field: FieldID, Desc: desc})
+}
+
+// Validate returns an error if the query uses unknown fields or values the fields can't be compared with
+func (q Query) Validate() error {
+	for _, g := range q.Groups {
+		for _, c := range g {
+			err := c.validate()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, k := range q.Sort {
+		f, ok := queryFields[k.Field]
+		if !ok {
+			return fmt.Errorf("unknown query field %q", k.Field)
+		}
+		if f.kind == kindQSL {
+			return fmt.Errorf("can't sort by %s", k.Field)
+		}
+	}
+
+	if q.Limit < 0 || q.Offset < 0 {
+		return fmt.Errorf("limit and offset can't be negative")
+	}
+
+	return nil
+}
+
+func (c Condition) validate() error {
+	f, ok := queryFields[c.Field]
+	if !ok {
+		return fmt.Errorf("unknown query field %q", c.Field)
+	}
+
+	if len(c.Values) == 0 {
+		return fmt.Errorf("no values for %s", c.Field)
+	}
+
+	switch c.Op {
+	case OpEqual:
+	case OpLike:
+		if f.kind != kindText {
+			return fmt.Errorf("%s can't be matched with a pattern", c.Field)
+		}
+	case OpLess, OpLessEqual, OpGreater, OpGreaterEqual:
+		if f.kind == kindQSL {
+			return fmt.Errorf("%s can't be compared with %s", c.Field, c.Op)
+		}
+		if len(c.Values) != 1 {
+			return fmt.Errorf("%s%s takes a single value", c.Field, c.Op)
+		}
+	default:
+		return fmt.Errorf("unknown query operator %q", c.Op)
+	}
+
+	for _, v := range c.Values {
+		switch f.kind {
+		case kindNumber, kindFrequency:
+			_, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("%s needs a number, not %q", c.Field, v)
+			}
+		case kindQSL:
+			switch QSLStatus(v) {
+			case QSLStatusSent, QSLStatusConfirmed, QSLStatusRequested:
+			default:
+				return fmt.Errorf("%s needs %s, %s or %s, not %q", c.Field, QSLStatusSent, QSLStatusConfirmed, QSLStatusRequested, v)
+			}
+		}
+	}
+
+	return nil
+}
+
+// CriteriaQuery returns the query matching the fields set in criteria the way Search does
+func CriteriaQuery(criteria QSO, limit int) Query {
+	var g Group
+
+	like := func(f Field, v string) {
+		if v != "" {
+			g = append(g, Condition{Field: f, Op: OpLike, Values: []string{v}})
+		}
+	}
+	like(FieldDate, criteria.Date)
+	like(FieldTime, criteria.Time)
+	like(FieldCall, criteria.Call)
+	like(FieldBand, criteria.Band)
+	like(FieldMode, criteria.Mode)
+	like(FieldRSTRcvd, criteria.RSTRcvd)
+	like(FieldRSTSent, criteria.RSTSent)
+
+	if criteria.Frequency > 0 {
+		g = append(g, Condition{Field: FieldFreq, Op: OpEqual, Values: []string{strconv.FormatInt(criteria.Frequency, 10)}})
+	}
+	if criteria.FrequencyRx > 0 {
+		g = append(g, Condition{Field: FieldFreqRx, Op: OpEqual, Values: []string{strconv.FormatInt(criteria.FrequencyRx, 10)}})
+	}
+	if criteria.TxPower > 0 {
+		g = append(g, Condition{Field: FieldPower, Op: OpEqual, Values: []string{strconv.FormatFloat(criteria.TxPower, 'f', -1, 64)}})
+	}
+
+	q := Query{Limit: limit}
+	if len(g) > 0 {
+		q.Groups = []Group{g}
+	}
+	return q
+}
+
+// ParseQuery parses the textual query syntax, terms are separated by spaces and all have to match
+// field:value,value matches any of the values, * in a value matches anything
+// field=value, field<value, field<=value, field>value & field>=value compare the field
+// ! before a term negates it, "or" (or |) separates groups of terms where any group can match
+// a term without a field matches the call
+// sort:field,-field orders by the fields, - for descending, limit:n, offset:n & after:id page through the QSOs
+// frequencies are in kHz, e.g. band:20m,40m mode:FT8 date>=2024-01-01 !lotw:confirmed
+func ParseQuery(s string) (Query, error) {
+	var q Query
+	var g Group
+
+	endGroup := func() {
+		if len(g) > 0 {
+			q.Groups = append(q.Groups, g)
+		}
+		g = nil
+	}
+
+	for _, term := range strings.Fields(s) {
+		if term == "|" || strings.EqualFold(term, "or") {
+			endGroup()
+			continue
+		}
+
+		not := strings.HasPrefix(term, "!")
+		term = strings.TrimPrefix(term, "!")
+
+		name, op, value := splitTerm(term)
+		name = strings.ToLower(name)
+
+		// paging & sorting apply to the whole query
+		switch name {
+		case "sort", "limit", "offset", "after":
+			if not || op != OpEqual {
+				return Query{}, fmt.Errorf("%s has to be %s:value", name, name)
+			}
+
+			err := q.setOption(name, value)
+			if err != nil {
+				return Query{}, err
+			}
+			continue
+		case "":
+			name = string(FieldCall)
+		}
+
+		c := Condition{
+			Field:  Field(name),
+			Op:     op,
+			Values: strings.Split(value, ","),
+			Not:    not,
+		}
+		if op == OpEqual {
+			for _, v := range c.Values {
+				if strings.Contains(v, "*") {
+					c.Op = OpLike
+				}
+			}
+		}
+		for i, v := range c.Values {
+			c.Values[i] = queryValue(c.Field, c.Op, v)
+		}
+
+		err := c.validate()
+		if err != nil {
+			return Query{}, err
+		}
+
+		g = append(g, c)
+	}
+	endGroup()
+
+	return q, nil
+}
+
+// splitTerm splits a term into the field name, operator & value, no field name for a bare value
+func splitTerm(term string) (string, Operator, string) {
+	i := strings.IndexAny(term, ":=<>")
+	if i < 0 {
+		return "", OpEqual, term
+	}
+
+	name, rest := term[:i], term[i:]
+	for _, op := range []Operator{OpLessEqual, OpGreaterEqual, OpLess, OpGreater, OpEqual} {
+		if strings.HasPrefix(rest, string(op)) {
+			return name, op, rest[len(op):]
+		}
+	}
+
+	return name, OpEqual, rest[1:]
+}
+
+// queryValue converts a value as typed to how the field is kept
+func queryValue(field Field, op Operator, v string) string {
+	f, ok := queryFields[field]
+	if !ok {
+		return v
+	}
+
+	switch f.kind {
+	case kindText:
+		if op == OpLike {
+			return strings.ReplaceAll(v, "*", "%")
+		}
+	case kindFrequency:
+		// typed in kHz, kept in Hz
+		khz, err := strconv.ParseFloat(v, 64)
+		if err == nil {
+			return strconv.FormatInt(int64(khz*1000), 10)
+		}
+	case kindQSL:
+		return strings.ToLower(v)
+	}
+
+	return v
+}
+
+// setOption sets a paging or sorting option from the textual query syntax
+func (q *Query) setOption(name, value string) error {
+	switch name {
+	case "sort":
+		q.Sort = nil
+		for _, v := range strings.Split(value, ",") {
+			k := SortKey{Field: Field(strings.ToLower(strings.TrimPrefix(v, "-"))), Desc: strings.HasPrefix(v, "-")}
+			q.Sort = append(q.Sort, k)
+		}
+		return q.Validate()
+	case "limit", "offset":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%s needs a count, not %q", name, value)
+		}
+		if name == "limit" {
+			q.Limit = n
+		} else {
+			q.Offset = n
+		}
+	case "after":
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("after needs a QSO id, not %q", value)
+		}
+		q.After = id
+	}
+
+	return nil
+}
@@ -0,0 +1,758 @@
+package qso
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SQLiteStore keeps QSOs in a qso database
+// changes to QSOs already uploaded to logbook services queue corrections and clear them from the outbox
+type SQLiteStore struct {
+	db *sqlx.DB
+}
+
+// NewSQLiteStore returns a store using the qso database connection d, usually db.QSODb
+// a new store is needed when the database is reopened
+func NewSQLiteStore(d *sqlx.DB) *SQLiteStore {
+	return &SQLiteStore{db: d}
+}
+
+// begin starts a transaction
+func (s *SQLiteStore) begin(ctx context.Context) (*sqlx.Tx, error) {
+	if s.db == nil {
+		err := errNoConnection
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// selectQSOs returns the QSOs stmt selects with params
+func (s *SQLiteStore) selectQSOs(ctx context.Context, stmt string, params map[string]interface{}) ([]QSO, error) {
+	var err error
+
+	if s.db == nil {
+		err = errNoConnection
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	q, err := s.db.PrepareNamedContext(ctx, stmt)
+	if err != nil {
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+	defer q.Close()
+
+	var qsos []QSO
+	err = q.SelectContext(ctx, &qsos, params)
+	if err != nil {
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	return qsos, nil
+}
+
+// Add inserts a single QSO into the qso database
+// sets qso.LoadedAt before insert and qso.ID after
+// does not insert QSL fields, they default to false
+func (s *SQLiteStore) Add(ctx context.Context, qso *QSO) error {
+	// set LoadedAt to now
+	qso.LoadedAt = time.Now().Unix()
+
+	err := qso.Validate(false)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	qso.resolveDXCC()
+
+	// in a transaction
+	tx, err := s.begin(ctx)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer func() {
+		// if we've had an error, rollback
+		if err != nil {
+			err = tx.Rollback()
+			if err != nil {
+				log.Printf("%+v", err)
+			}
+		}
+	}()
+
+	qsoInsert, err := tx.PrepareNamedContext(ctx, stmtQSOOnlyInsert)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	result, err := qsoInsert.ExecContext(ctx, qso)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	// duplicates don't insert a row
+	n, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	if n == 0 {
+		err = tx.Rollback()
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+		return nil
+	}
+
+	qso.ID, err = result.LastInsertId()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	added, err := getInTx(tx, qso.ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = recordAudit(tx, SourceFrom(ctx), AuditInsert, nil, []QSO{*added})
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	publishQSOEvent(QSOAdded, nil, []QSO{*added})
+	return nil
+}
+
+// BulkAdd inserts all QSOs into the qso database, returning how many were inserted
+// QSOs already in the database are skipped
+// assumes qso.LoadedAt was already set
+func (s *SQLiteStore) BulkAdd(ctx context.Context, qsos []QSO) (int, error) {
+	// in a transaction
+	tx, err := s.begin(ctx)
+	if err != nil {
+		log.Printf("%+v", err)
+		return 0, err
+	}
+	defer func() {
+		// if we've had an error, rollback
+		if err != nil {
+			err = tx.Rollback()
+			if err != nil {
+				log.Printf("%+v", err)
+			}
+		}
+	}()
+
+	qsoInsert, err := tx.PrepareNamedContext(ctx, stmtQSOInsert)
+	if err != nil {
+		log.Printf("%+v", err)
+		return 0, err
+	}
+
+	// insert all qsos
+	var added []QSO
+	for _, qso := range qsos {
+		err = qso.Validate(false)
+		if err != nil {
+			log.Printf("%+v", err)
+			return 0, err
+		}
+		qso.defaultQSLRcvd()
+		qso.resolveDXCC()
+
+		// create qso record
+		var result sql.Result
+		result, err = qsoInsert.ExecContext(ctx, qso)
+		if err != nil {
+			log.Printf("%+v", err)
+			return 0, err
+		}
+
+		// duplicates don't insert a row
+		var n int64
+		n, err = result.RowsAffected()
+		if err != nil {
+			log.Printf("%+v", err)
+			return 0, err
+		}
+		if n == 0 {
+			continue
+		}
+
+		qso.ID, err = result.LastInsertId()
+		if err != nil {
+			log.Printf("%+v", err)
+			return 0, err
+		}
+		added = append(added, qso)
+	}
+
+	err = recordAudit(tx, SourceFrom(ctx), AuditInsert, nil, added)
+	if err != nil {
+		log.Printf("%+v", err)
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%+v", err)
+		return 0, err
+	}
+
+	publishQSOEvent(QSOAdded, nil, added)
+	return len(added), nil
+}
+
+// Update updates the QSO only fields in the qso database
+// does not update QSL fields, logbook services the QSO was uploaded to are queued to be corrected
+func (s *SQLiteStore) Update(ctx context.Context, qso *QSO) error {
+	// in a transaction
+	tx, err := s.begin(ctx)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer func() {
+		// if we've had an error, rollback
+		if err != nil {
+			err = tx.Rollback()
+			if err != nil {
+				log.Printf("%+v", err)
+			}
+		}
+	}()
+
+	old, updated, err := updateInTx(ctx, tx, qso)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	publishQSOEvent(QSOUpdated, []QSO{*old}, []QSO{*updated})
+	return nil
+}
+
+// updateInTx updates the QSO only fields in the transaction, returning the qso before & after
+func updateInTx(ctx context.Context, tx *sqlx.Tx, qso *QSO) (*QSO, *QSO, error) {
+	err := qso.Validate(true)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	// see if there is already a qso matching this update
+	q, err := tx.PrepareNamedContext(ctx, stmtQSOSelectDupTest)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	var qsos []QSO
+	err = q.SelectContext(ctx, &qsos, qso)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	// if what we found is not this qso then error out
+	if len(qsos) > 0 && qsos[0].ID != qso.ID {
+		err = fmt.Errorf("update would cause duplicate qso")
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	// logbook services that have the qso need to be corrected
+	old, err := getInTx(tx, qso.ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+	corrections, reupload := correctionsFor(*old, qso)
+	err = enqueueCorrections(tx, corrections)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	// edits can fix what a service rejected
+	err = clearOutbox(tx, qso.ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	// good to go with update
+	q, err = tx.PrepareNamedContext(ctx, stmtQSOOnlyUpdate)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	_, err = q.ExecContext(ctx, qso)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	err = resetUploads(tx, *old, corrections, reupload)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	updated, err := getInTx(tx, qso.ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	err = recordAudit(tx, SourceFrom(ctx), AuditUpdate, []QSO{*old}, []QSO{*updated})
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil, err
+	}
+
+	return old, updated, nil
+}
+
+// Delete removes a qso from the qso database
+// logbook services the QSO was uploaded to are queued to delete it too
+func (s *SQLiteStore) Delete(ctx context.Context, qso *QSO) error {
+	err := qso.Validate(true)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	// in a transaction
+	tx, err := s.begin(ctx)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer func() {
+		// if we've had an error, rollback
+		if err != nil {
+			err = tx.Rollback()
+			if err != nil {
+				log.Printf("%+v", err)
+			}
+		}
+	}()
+
+	old, err := deleteInTx(ctx, tx, qso.ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	publishQSOEvent(QSODeleted, []QSO{*old}, nil)
+	return nil
+}
+
+// deleteInTx removes the qso identified by ID in the transaction, returning the qso as it was
+// its audit trail is kept so it can be restored
+func deleteInTx(ctx context.Context, tx *sqlx.Tx, ID int64) (*QSO, error) {
+	// logbook services that have the qso need it removed
+	old, err := getInTx(tx, ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+	corrections, _ := correctionsFor(*old, nil)
+	err = enqueueCorrections(tx, corrections)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	err = clearOutbox(tx, ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, "delete from upload_attempts where qso_id = ?", ID)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	q, err := tx.PrepareNamedContext(ctx, stmtQSODelete)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	_, err = q.ExecContext(ctx, map[string]interface{}{"id": ID})
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	err = recordAudit(tx, SourceFrom(ctx), AuditDelete, []QSO{*old}, nil)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return old, nil
+}
+
+// Get returns a single QSO identified by ID
+func (s *SQLiteStore) Get(ctx context.Context, ID int64) (*QSO, error) {
+	// start with All and add where clause to get single qso
+	stmt := stmtQSOSelectAll
+	stmt += " where id = :id"
+
+	params := map[string]interface{}{
+		"id": ID,
+	}
+
+	qsos, err := s.selectQSOs(ctx, stmt, params)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	if len(qsos) == 0 {
+		err = ErrQSONotFound
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return &qsos[0], nil
+}
+
+// Search returns all QSOs matching the criteria limited by count limit
+func (s *SQLiteStore) Search(ctx context.Context, criteria QSO, limit int) ([]QSO, error) {
+	return s.Find(ctx, CriteriaQuery(criteria, limit))
+}
+
+// sqlCondition returns the sql testing a single condition, adding its values to params
+func sqlCondition(c Condition, params map[string]interface{}) string {
+	f := queryFields[c.Field]
+
+	// each value gets a parameter of its own
+	param := func(v interface{}) string {
+		name := fmt.Sprintf("q%d", len(params))
+		params[name] = v
+		return ":" + name
+	}
+	number := func(v string) float64 {
+		// already validated
+		n, _ := strconv.ParseFloat(v, 64)
+		return n
+	}
+
+	var tests []string
+	for _, v := range c.Values {
+		switch {
+		case f.kind == kindQSL:
+			switch QSLStatus(v) {
+			case QSLStatusSent:
+				tests = append(tests, fmt.Sprintf("%s = %d", f.column, Sent))
+			case QSLStatusConfirmed:
+				tests = append(tests, fmt.Sprintf("%s in ('%s', '%s')", rcvdColumn(f.service), Received, RcvdVerified))
+			case QSLStatusRequested:
+				tests = append(tests, fmt.Sprintf("%s = '%s'", rcvdColumn(f.service), RcvdRequest))
+			}
+		case c.Op == OpLike:
+			tests = append(tests, fmt.Sprintf("%s like %s", f.column, param(v)))
+		case c.Op == OpEqual && f.kind == kindText:
+			tests = append(tests, fmt.Sprintf("%s = %s collate nocase", f.column, param(v)))
+		case c.Op == OpEqual && f.kind == kindFrequency:
+			// anywhere within the same kHz
+			low := int64(number(v))
+			low -= low % 1000
+			tests = append(tests, fmt.Sprintf("(%s >= %s and %s < %s)", f.column, param(low), f.column, param(low+1000)))
+		case f.kind == kindText:
+			tests = append(tests, fmt.Sprintf("%s %s %s", f.column, c.Op, param(v)))
+		default:
+			tests = append(tests, fmt.Sprintf("%s %s %s", f.column, c.Op, param(number(v))))
+		}
+	}
+
+	where := "(" + strings.Join(tests, " or ") + ")"
+	if c.Not {
+		where = "not " + where
+	}
+	return where
+}
+
+// sqlAfter returns the sql selecting the qsos after qso in the order of keys, adding its values to params
+func sqlAfter(keys []SortKey, qso QSO, params map[string]interface{}) string {
+	var after []string
+	for i, k := range keys {
+		var tests []string
+		for j, key := range keys[:i+1] {
+			f := queryFields[key.Field]
+
+			name := fmt.Sprintf("a%d", j)
+			if f.text != nil {
+				params[name] = f.text(qso)
+			} else {
+				params[name] = f.number(qso)
+			}
+
+			op := "="
+			if j == i {
+				op = ">"
+				if k.Desc {
+					op = "<"
+				}
+			}
+			tests = append(tests, fmt.Sprintf("%s %s :%s", f.column, op, name))
+		}
+		after = append(after, "("+strings.Join(tests, " and ")+")")
+	}
+
+	return "(" + strings.Join(after, " or ") + ")"
+}
+
+// Find returns the QSOs matching the query
+func (s *SQLiteStore) Find(ctx context.Context, query Query) ([]QSO, error) {
+	err := query.Validate()
+	if err != nil {
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	// query parameters
+	params := map[string]interface{}{}
+
+	var where []string
+
+	var groups []string
+	for _, g := range query.Groups {
+		var conditions []string
+		for _, c := range g {
+			conditions = append(conditions, sqlCondition(c, params))
+		}
+		groups = append(groups, "("+strings.Join(conditions, " and ")+")")
+	}
+	if len(groups) > 0 {
+		where = append(where, "("+strings.Join(groups, " or ")+")")
+	}
+
+	keys := query.sortKeys()
+
+	// page starts after the last qso of the previous one
+	if query.After != 0 {
+		last, err := s.Get(ctx, query.After)
+		if err != nil {
+			log.Printf("%+v", err)
+			return []QSO{}, err
+		}
+		where = append(where, sqlAfter(keys, *last, params))
+	}
+
+	// start with All and add where clause dynamically
+	stmt := stmtQSOSelectAll
+	if len(where) > 0 {
+		stmt += " where " + strings.Join(where, " and ")
+	}
+
+	var order []string
+	for _, k := range keys {
+		dir := "asc"
+		if k.Desc {
+			dir = "desc"
+		}
+		order = append(order, queryFields[k.Field].column+" "+dir)
+	}
+	stmt += " order by " + strings.Join(order, ", ")
+
+	// sqlite needs a limit for an offset, -1 is no limit
+	params["limit"] = -1
+	if query.Limit > 0 {
+		params["limit"] = query.Limit
+	}
+	params["offset"] = query.Offset
+	stmt += " limit :limit offset :offset"
+
+	qsos, err := s.selectQSOs(ctx, stmt, params)
+	if err != nil {
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	return qsos, nil
+}
+
+// History returns all QSO loaded after days ago limited by count limit
+func (s *SQLiteStore) History(ctx context.Context, days, limit int) ([]QSO, error) {
+	// query parameters
+	params := map[string]interface{}{}
+
+	// start with All and add where clause
+	stmt := stmtQSOSelectAll
+
+	// how much history?
+	if days > 0 {
+		now := time.Now()
+		then := now.Add(-(time.Duration(days*24) * time.Hour)).Unix()
+		stmt += " where loaded_at >= :loadedat"
+		params["loadedat"] = then
+	}
+
+	// order so we get the latest qsos
+	stmt += " order by qso_date desc, qso_time desc"
+
+	// limit result set
+	if limit > 0 {
+		stmt += "  limit :limit"
+		params["limit"] = limit
+	}
+
+	qsos, err := s.selectQSOs(ctx, stmt, params)
+	if err != nil {
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	return qsos, nil
+}
+
+// FindQSLsToSend returns all QSOs that need QSLs for a specific service before delay minutes ago
+// QSOs in the outbox are left out until they're due to be retried
+func (s *SQLiteStore) FindQSLsToSend(ctx context.Context, service QSLService, delay int) ([]QSO, error) {
+	// query parameters
+	params := map[string]interface{}{}
+
+	// start with All and add where clause
+	stmt := stmtQSOSelectAll
+	stmt += fmt.Sprintf(" where %s = 0 and ", service) + stmtOutboxHeld
+	params["service"] = service
+	params["now"] = time.Now().Unix()
+
+	// handle delay for sending QSL
+	if delay > 0 {
+		now := time.Now()
+		then := now.Add(-(time.Duration(delay) * time.Minute)).Unix()
+		stmt += " and loaded_at <= :loadedat"
+		params["loadedat"] = then
+	}
+
+	// return results to caller with oldest first
+	stmt += " order by qso_date asc, qso_time asc"
+
+	qsos, err := s.selectQSOs(ctx, stmt, params)
+	if err != nil {
+		log.Printf("%+v", err)
+		return []QSO{}, err
+	}
+
+	return qsos, nil
+}
+
+// UpdateQSL updates the QSOs QSL status, the sent date is set to today or cleared
+func (s *SQLiteStore) UpdateQSL(ctx context.Context, qsos []QSO, service QSLService, sent QSLSent) error {
+	// in a transaction
+	tx, err := s.begin(ctx)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer func() {
+		// if we've had an error, rollback
+		if err != nil {
+			err = tx.Rollback()
+			if err != nil {
+				log.Printf("%+v", err)
+			}
+		}
+	}()
+
+	sdate := ""
+	if sent == Sent {
+		sdate = today()
+	}
+
+	before, err := snapshot(tx, qsos)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	q, err := tx.PrepareNamedContext(ctx, fmt.Sprintf("update qsos set %s = %d, %s = :sdate where id = :id", service, sent, sentDateColumn(service)))
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	for _, qso := range qsos {
+		params := map[string]interface{}{
+			"id":    qso.ID,
+			"sdate": sdate,
+		}
+
+		_, err = q.ExecContext(ctx, params)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	after, err := snapshot(tx, qsos)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = recordAudit(tx, SourceFrom(ctx), AuditQSL, before, after)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	publishQSOEvent(QSLChanged, before, after)
+	return nil
+}
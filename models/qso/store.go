@@ -0,0 +1,110 @@
+package qso
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrQSONotFound is returned when there is no QSO with the ID asked for
+var ErrQSONotFound = errors.New("qso not found")
+
+// QSOStore is where QSOs are kept, SQLiteStore for the qso database and MemoryStore for everything else
+// all of them publish a QSOEvent after changing QSOs
+type QSOStore interface {
+	// Add inserts a single QSO, setting qso.LoadedAt and qso.ID
+	// QSL fields are not inserted, they default to not sent
+	Add(ctx context.Context, qso *QSO) error
+
+	// BulkAdd inserts all QSOs including their QSL fields, returning how many were inserted
+	// QSOs already in the store are skipped, assumes qso.LoadedAt was already set
+	BulkAdd(ctx context.Context, qsos []QSO) (int, error)
+
+	// Update updates the QSO only fields, not the QSL fields
+	Update(ctx context.Context, qso *QSO) error
+
+	// Delete removes the QSO
+	Delete(ctx context.Context, qso *QSO) error
+
+	// Get returns a single QSO identified by ID, ErrQSONotFound if there isn't one
+	Get(ctx context.Context, ID int64) (*QSO, error)
+
+	// Search returns the latest QSOs matching the criteria limited by count limit
+	// text fields match like sql like, frequencies anywhere within the same kHz
+	Search(ctx context.Context, criteria QSO, limit int) ([]QSO, error)
+
+	// Find returns the QSOs matching the query, see Query & ParseQuery
+	Find(ctx context.Context, query Query) ([]QSO, error)
+
+	// History returns the latest QSOs loaded after days ago limited by count limit, zero for all of them
+	History(ctx context.Context, days, limit int) ([]QSO, error)
+
+	// FindQSLsToSend returns the QSOs that need QSLs for the service loaded before delay minutes ago, oldest first
+	FindQSLsToSend(ctx context.Context, service QSLService, delay int) ([]QSO, error)
+
+	// UpdateQSL updates the QSOs QSL status, the sent date is set to today or cleared
+	UpdateQSL(ctx context.Context, qsos []QSO, service QSLService, sent QSLSent) error
+
+	// All returns all QSOs
+	All(ctx context.Context) ([]QSO, error)
+
+	// Between returns the QSOs made from from thru to, oldest first
+	Between(ctx context.Context, from, to time.Time) ([]QSO, error)
+
+	// Near returns the QSOs with call on band made within window of at, closest first
+	Near(ctx context.Context, call, band string, at time.Time, window time.Duration) ([]QSO, error)
+
+	// UpdateQSLReceived updates the QSOs QSL received status for a service, date is yyyy-mm-dd and defaults to today
+	UpdateQSLReceived(ctx context.Context, qsos []QSO, service QSLService, rcvd QSLRcvd, date string) error
+
+	// LastQSLReceived returns the latest date a QSL was received from a service, empty if there hasn't been one
+	LastQSLReceived(ctx context.Context, service QSLService) (string, error)
+
+	// UpdateLocation updates the dxcc entity, grid & zones of the QSOs
+	UpdateLocation(ctx context.Context, qsos []QSO) error
+
+	// AddUserDefs adds the user-defined field declarations, declarations that already exist are updated
+	AddUserDefs(ctx context.Context, userdefs []UserDef) error
+
+	// UserDefs returns all user-defined field declarations
+	UserDefs(ctx context.Context) ([]UserDef, error)
+}
+
+// ServiceStore is a QSOStore that keeps track of the copies of QSOs on the logbook services
+// the background tasks work thru one, stores without logbook services behind them just have nothing to correct
+type ServiceStore interface {
+	QSOStore
+
+	// UploadSucceeded marks the QSOs as sent to the service and records the attempt
+	UploadSucceeded(ctx context.Context, qsos []QSO, service QSLService, response string) error
+
+	// UploadFailed records the failed attempt and puts the QSOs in the outbox
+	UploadFailed(ctx context.Context, qsos []QSO, service QSLService, class UploadErrorClass, response string) error
+
+	// UpdateQRZLogID records the QRZ.com logbook id of the QSO
+	UpdateQRZLogID(ctx context.Context, ID, logID int64) error
+
+	// PendingCorrections returns the corrections waiting to be made to a service, oldest first
+	PendingCorrections(ctx context.Context, service QSLService) ([]Correction, error)
+
+	// CorrectionDone removes the correction once it has been made
+	CorrectionDone(ctx context.Context, c Correction) error
+
+	// CorrectionFailed records the failed attempt at making the correction, rejected ones are dropped
+	CorrectionFailed(ctx context.Context, c Correction, class UploadErrorClass, response string) error
+
+	// FailedUploads returns the QSOs in the outbox, by service and oldest first
+	FailedUploads(ctx context.Context) ([]OutboxItem, error)
+
+	// UploadAttempts returns every attempt at uploading the QSO identified by ID, oldest first
+	UploadAttempts(ctx context.Context, ID int64) ([]UploadAttempt, error)
+
+	// RetryUpload takes the QSO out of the outbox so the next upload to the service sends it
+	RetryUpload(ctx context.Context, o OutboxItem) error
+}
+
+// both stores are ServiceStores
+var (
+	_ ServiceStore = (*SQLiteStore)(nil)
+	_ ServiceStore = (*MemoryStore)(nil)
+)
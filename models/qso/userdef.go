@@ -0,0 +1,109 @@
+package qso
+
+import (
+	"context"
+	"log"
+)
+
+// UserDef is a user-defined field declared in the header of an imported ADIF file
+type UserDef struct {
+	ID        int64  `db:"id"`
+	Name      string `db:"name"`
+	Indicator string `db:"indicator"`
+	Values    string `db:"field_values"`
+}
+
+const (
+	stmtUserDefInsert = `
+		insert into userdefs (
+			name,
+			indicator,
+			field_values
+		) values (
+			:name,
+			:indicator,
+			:field_values
+		)
+		on conflict(name) do update set
+			indicator = excluded.indicator,
+			field_values = excluded.field_values
+	`
+
+	stmtUserDefSelectAll = `
+		select
+			id,
+			name,
+			indicator,
+			field_values
+		from
+			userdefs
+		order by
+			id
+	`
+)
+
+// AddUserDefs inserts the user-defined field declarations into the qso database
+// declarations that already exist are updated
+func (s *SQLiteStore) AddUserDefs(ctx context.Context, userdefs []UserDef) error {
+	if len(userdefs) == 0 {
+		return nil
+	}
+
+	// in a transaction
+	tx, err := s.begin(ctx)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer func() {
+		// if we've had an error, rollback
+		if err != nil {
+			err = tx.Rollback()
+			if err != nil {
+				log.Printf("%+v", err)
+			}
+		}
+	}()
+
+	q, err := tx.PrepareNamedContext(ctx, stmtUserDefInsert)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	for _, u := range userdefs {
+		_, err = q.ExecContext(ctx, u)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// UserDefs returns all user-defined field declarations in the qso database
+func (s *SQLiteStore) UserDefs(ctx context.Context) ([]UserDef, error) {
+	var err error
+
+	if s.db == nil {
+		err = errNoConnection
+		log.Printf("%+v", err)
+		return []UserDef{}, err
+	}
+
+	var userdefs []UserDef
+	err = s.db.SelectContext(ctx, &userdefs, stmtUserDefSelectAll)
+	if err != nil {
+		log.Printf("%+v", err)
+		return []UserDef{}, err
+	}
+
+	return userdefs, nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/bbathe/golog/config"
 	"github.com/bbathe/golog/db"
+	"github.com/bbathe/golog/dxcc"
 	"github.com/bbathe/golog/util"
 )
 
@@ -20,6 +21,13 @@ type Spot struct {
 	Frequency string `db:"frequency"`
 	Comments  string `db:"comments"`
 	Spotter   string `db:"spotter"`
+
+	// entity of the spotted call from the country files, zero or empty when not known
+	DXCC      int    `db:"dxcc"`
+	Country   string `db:"country"`
+	Continent string `db:"continent"`
+	CQZone    int    `db:"cqz"`
+	ITUZone   int    `db:"ituz"`
 }
 
 const (
@@ -30,14 +38,24 @@ const (
 			band,
 			frequency,
 			comments,
-			spotter
+			spotter,
+			dxcc,
+			country,
+			continent,
+			cqz,
+			ituz
 		) values (
 			:timestamp,
 			:call,
 			:band,
 			:frequency,
 			:comments,
-			:spotter
+			:spotter,
+			:dxcc,
+			:country,
+			:continent,
+			:cqz,
+			:ituz
 		)
 		on conflict(timestamp, call, band, spotter) do nothing
 	`
@@ -50,7 +68,12 @@ const (
 			band,
 			frequency,
 			comments,
-			spotter
+			spotter,
+			dxcc,
+			country,
+			continent,
+			cqz,
+			ituz
 		from
 			spots
 		where
@@ -58,29 +81,33 @@ const (
 	`
 )
 
-var (
-	errNoConnection = fmt.Errorf("no database connection")
+var errNoConnection = fmt.Errorf("no database connection")
+
+// SpotEventType is what happened to the spot in a SpotEvent
+type SpotEventType string
 
-	handlers []SpotChangeEventHandler
+const (
+	SpotAdded SpotEventType = "added"
 )
 
-// allow callers to register to recieve event after any spot changes occur
-type SpotChangeEventHandler func()
+// SpotEvent is published after a spot changes
+type SpotEvent struct {
+	Type SpotEventType
+	Spot Spot
+}
 
-func Attach(handler SpotChangeEventHandler) int {
-	handlers = append(handlers, handler)
+// allow callers to register to recieve events after spots change
+// handlers are called on a goroutine of their own, not the one that made the change
+type SpotChangeEventHandler func(SpotEvent)
 
-	return len(handlers) - 1
-}
+var events util.EventBus[SpotEvent]
 
-func Detach(handle int) {
-	handlers[handle] = nil
+func Attach(handler SpotChangeEventHandler) int {
+	return events.Attach(handler)
 }
 
-func publishSpotChange() {
-	for _, h := range handlers {
-		h()
-	}
+func Detach(handle int) {
+	events.Detach(handle)
 }
 
 // Add inserts a single spot into the spot database
@@ -117,19 +144,45 @@ func Add(timestamp, call, frequency, comments, spotter string) error {
 		Spotter:   strings.ToUpper(spotter),
 	}
 
+	// spots are from now, the timestamp doesn't have a date
+	e, ok := dxcc.Lookup(spot.Call, time.Now().UTC())
+	if ok {
+		spot.DXCC = e.DXCC
+		spot.Country = e.Name
+		spot.Continent = e.Continent
+		spot.CQZone = e.CQZone
+		spot.ITUZone = e.ITUZone
+	}
+
 	spotInsert, err := db.SpotDb.PrepareNamed(stmtSpotInsert)
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
 	}
 
-	_, err = spotInsert.Exec(spot)
+	result, err := spotInsert.Exec(spot)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	// spots we already have don't insert a row
+	n, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	spot.ID, err = result.LastInsertId()
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
 	}
 
-	publishSpotChange()
+	events.Publish(SpotEvent{Type: SpotAdded, Spot: spot})
 	return nil
 }
 
@@ -0,0 +1,180 @@
+package stats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Matrix returns the band by mode matrix, bands & modes in the same order as the report's counts
+// cells[i][j] is how many QSOs there are on bands[i] with modes[j]
+func (r *Report) Matrix() ([]string, []string, [][]int) {
+	bands := make([]string, 0, len(r.Bands))
+	bandIdx := make(map[string]int, len(r.Bands))
+	for i, b := range r.Bands {
+		bands = append(bands, b.Key)
+		bandIdx[b.Key] = i
+	}
+
+	modes := make([]string, 0, len(r.Modes))
+	modeIdx := make(map[string]int, len(r.Modes))
+	for i, m := range r.Modes {
+		modes = append(modes, m.Key)
+		modeIdx[m.Key] = i
+	}
+
+	cells := make([][]int, len(bands))
+	for i := range cells {
+		cells[i] = make([]int, len(modes))
+	}
+	for _, bm := range r.BandModes {
+		cells[bandIdx[bm.Band]][modeIdx[bm.Mode]] = bm.QSOs
+	}
+
+	return bands, modes, cells
+}
+
+// table is a section of a report as rows of columns, the first row is the headings
+type table struct {
+	title string
+	rows  [][]string
+}
+
+func countTable(title, heading string, counts []Count) table {
+	t := table{
+		title: title,
+		rows:  [][]string{{heading, "QSOs"}},
+	}
+	for _, c := range counts {
+		t.rows = append(t.rows, []string{c.Key, strconv.Itoa(c.QSOs)})
+	}
+	return t
+}
+
+func percent(f float64) string {
+	return strconv.FormatFloat(f*100, 'f', 1, 64) + "%"
+}
+
+// tables returns the sections of the report in the order they're written
+func (r *Report) tables() []table {
+	tables := []table{
+		{
+			title: "Summary",
+			rows: [][]string{
+				{"QSOs", "Unique Calls", "From", "To"},
+				{strconv.Itoa(r.QSOs), strconv.Itoa(r.UniqueCalls), r.From, r.To},
+			},
+		},
+		countTable("Bands", "Band", r.Bands),
+		countTable("Modes", "Mode", r.Modes),
+	}
+
+	// band by mode with totals down the right
+	bands, modes, cells := r.Matrix()
+	matrix := table{
+		title: "Band by Mode",
+		rows:  [][]string{append(append([]string{"Band"}, modes...), "Total")},
+	}
+	for i, b := range bands {
+		row := []string{b}
+		for _, n := range cells[i] {
+			row = append(row, strconv.Itoa(n))
+		}
+		row = append(row, strconv.Itoa(r.Bands[i].QSOs))
+		matrix.rows = append(matrix.rows, row)
+	}
+	tables = append(tables, matrix)
+
+	tables = append(tables,
+		countTable("Years", "Year", r.Years),
+		countTable("Months", "Month", r.Months),
+		countTable("Days", "Day", r.Days),
+		countTable("Hours (UTC)", "Hour", r.Hours),
+	)
+
+	qsls := table{
+		title: "QSLs",
+		rows:  [][]string{{"Service", "Uploaded", "Upload Rate", "Confirmed", "Confirm Rate"}},
+	}
+	for _, q := range r.QSLs {
+		qsls.rows = append(qsls.rows, []string{q.Service, strconv.Itoa(q.Uploaded), percent(q.UploadRate), strconv.Itoa(q.Confirmed), percent(q.ConfirmRate)})
+	}
+	tables = append(tables, qsls)
+
+	return append(tables, countTable("Top Calls", "Call", r.TopCalls))
+}
+
+// WriteText writes the report as aligned text for people
+func (r *Report) WriteText(w io.Writer) error {
+	for i, t := range r.tables() {
+		if i > 0 {
+			_, err := fmt.Fprintln(w)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := fmt.Fprintln(w, t.title)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		for _, row := range t.rows {
+			for _, col := range row {
+				_, err = fmt.Fprint(tw, col, "\t")
+				if err != nil {
+					return err
+				}
+			}
+			_, err = fmt.Fprintln(tw)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tw.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteCSV writes each section of the report as a CSV table with a heading row, sections are separated by a title row
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	for i, t := range r.tables() {
+		if i > 0 {
+			err := cw.Write([]string{})
+			if err != nil {
+				return err
+			}
+		}
+
+		err := cw.Write([]string{t.title})
+		if err != nil {
+			return err
+		}
+
+		err = cw.WriteAll(t.rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
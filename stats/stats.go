@@ -0,0 +1,286 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bbathe/golog/models/qso"
+	"github.com/jmoiron/sqlx"
+)
+
+var errNoConnection = fmt.Errorf("no database connection")
+
+// how many of the most worked calls are in a report when not asked for
+const defaultTopCalls = 10
+
+// Options narrow down which QSOs a report covers
+type Options struct {
+	// dates are yyyy-mm-dd and inclusive, empty for no limit
+	From string
+	To   string
+
+	// how many of the most worked calls to include, zero for the default
+	TopCalls int
+}
+
+// Count is how many QSOs there are for a key
+type Count struct {
+	Key  string `db:"key" json:"key"`
+	QSOs int    `db:"qsos" json:"qsos"`
+}
+
+// BandMode is how many QSOs there are for a band & mode, a cell of the band by mode matrix
+type BandMode struct {
+	Band string `db:"band" json:"band"`
+	Mode string `db:"mode" json:"mode"`
+	QSOs int    `db:"qsos" json:"qsos"`
+}
+
+// QSLRate is how many QSOs were uploaded to (or sent for paper cards) and confirmed by a service
+// UploadRate is the fraction of all QSOs uploaded, ConfirmRate the fraction of uploaded QSOs confirmed
+type QSLRate struct {
+	Service     string  `json:"service"`
+	Uploaded    int     `db:"uploaded" json:"uploaded"`
+	Confirmed   int     `db:"confirmed" json:"confirmed"`
+	UploadRate  float64 `json:"uploadRate"`
+	ConfirmRate float64 `json:"confirmRate"`
+}
+
+// Report is the summary of the QSOs in the log
+// counts by band, mode & call are most QSOs first, by date & hour in order
+type Report struct {
+	// dates of the first & last QSOs
+	From        string     `json:"from"`
+	To          string     `json:"to"`
+	QSOs        int        `json:"qsos"`
+	UniqueCalls int        `json:"uniqueCalls"`
+	Bands       []Count    `json:"bands"`
+	Modes       []Count    `json:"modes"`
+	BandModes   []BandMode `json:"bandModes"`
+	Years       []Count    `json:"years"`
+	Months      []Count    `json:"months"`
+	Days        []Count    `json:"days"`
+	Hours       []Count    `json:"hours"`
+	QSLs        []QSLRate  `json:"qsls"`
+	TopCalls    []Count    `json:"topCalls"`
+}
+
+// services are the qsl services in the order they're reported
+var services = []struct {
+	name    string
+	service qso.QSLService
+}{
+	{"LoTW", qso.QSLLotw},
+	{"QRZ", qso.QSLQrz},
+	{"Club Log", qso.QSLClublog},
+	{"eQSL", qso.QSLEqsl},
+	{"Card", qso.QSLCard},
+}
+
+// summary queries the report is built from, %s is the where clause
+const (
+	stmtTotals = `
+		select
+			count(*) as qsos,
+			count(distinct upper(call)) as calls,
+			coalesce(min(qso_date), '') as first,
+			coalesce(max(qso_date), '') as last
+		from qsos
+		%s`
+
+	stmtCounts = `
+		select
+			%s as key,
+			count(*) as qsos
+		from qsos
+		%s
+		group by key
+		order by %s`
+
+	stmtBandModes = `
+		select
+			band,
+			mode,
+			count(*) as qsos
+		from qsos
+		%s
+		group by band, mode
+		order by band, mode`
+
+	stmtQSLs = `
+		select
+			coalesce(sum(%[1]s = 1), 0) as uploaded,
+			coalesce(sum(%[1]s_rcvd in ('Y', 'V')), 0) as confirmed
+		from qsos
+		%[2]s`
+)
+
+// orders for counts
+const (
+	byQSOs = "qsos desc, key"
+	byKey  = "key"
+)
+
+// Summarize builds the report for the QSOs in the qso database d, usually db.QSODb
+func Summarize(ctx context.Context, d *sqlx.DB, opts Options) (*Report, error) {
+	var err error
+
+	if d == nil {
+		err = errNoConnection
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	// limit to the dates asked for
+	params := map[string]interface{}{
+		"from": opts.From,
+		"to":   opts.To,
+	}
+
+	var where []string
+	if opts.From != "" {
+		where = append(where, "qso_date >= :from")
+	}
+	if opts.To != "" {
+		where = append(where, "qso_date <= :to")
+	}
+
+	var clause string
+	if len(where) > 0 {
+		clause = "where " + strings.Join(where, " and ")
+	}
+
+	r := &Report{}
+
+	var totals struct {
+		QSOs  int    `db:"qsos"`
+		Calls int    `db:"calls"`
+		First string `db:"first"`
+		Last  string `db:"last"`
+	}
+	err = get(ctx, d, &totals, fmt.Sprintf(stmtTotals, clause), params)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+	r.QSOs = totals.QSOs
+	r.UniqueCalls = totals.Calls
+	r.From = totals.First
+	r.To = totals.Last
+
+	counts := []struct {
+		into  *[]Count
+		key   string
+		order string
+		limit int
+	}{
+		{&r.Bands, "band", byQSOs, 0},
+		{&r.Modes, "mode", byQSOs, 0},
+		{&r.Years, "substr(qso_date, 1, 4)", byKey, 0},
+		{&r.Months, "substr(qso_date, 1, 7)", byKey, 0},
+		{&r.Days, "qso_date", byKey, 0},
+		{&r.Hours, "substr(qso_time, 1, 2)", byKey, 0},
+		{&r.TopCalls, "upper(call)", byQSOs, opts.TopCalls},
+	}
+	if counts[len(counts)-1].limit <= 0 {
+		counts[len(counts)-1].limit = defaultTopCalls
+	}
+
+	for _, c := range counts {
+		stmt := fmt.Sprintf(stmtCounts, c.key, clause, c.order)
+		if c.limit > 0 {
+			stmt += fmt.Sprintf(" limit %d", c.limit)
+		}
+
+		*c.into = []Count{}
+		err = selectRows(ctx, d, c.into, stmt, params)
+		if err != nil {
+			log.Printf("%+v", err)
+			return nil, err
+		}
+	}
+
+	// every hour of the day, even the ones without qsos
+	r.Hours = allHours(r.Hours)
+
+	r.BandModes = []BandMode{}
+	err = selectRows(ctx, d, &r.BandModes, fmt.Sprintf(stmtBandModes, clause), params)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	for _, s := range services {
+		rate := QSLRate{Service: s.name}
+		err = get(ctx, d, &rate, fmt.Sprintf(stmtQSLs, s.service, clause), params)
+		if err != nil {
+			log.Printf("%+v", err)
+			return nil, err
+		}
+
+		if r.QSOs > 0 {
+			rate.UploadRate = float64(rate.Uploaded) / float64(r.QSOs)
+		}
+		if rate.Uploaded > 0 {
+			rate.ConfirmRate = float64(rate.Confirmed) / float64(rate.Uploaded)
+		}
+
+		r.QSLs = append(r.QSLs, rate)
+	}
+
+	return r, nil
+}
+
+// allHours returns counts for all 24 hours from the hours that had qsos
+func allHours(hours []Count) []Count {
+	found := make(map[string]int, len(hours))
+	for _, h := range hours {
+		found[h.Key] = h.QSOs
+	}
+
+	all := make([]Count, 0, 24)
+	for h := 0; h < 24; h++ {
+		key := fmt.Sprintf("%02d", h)
+		all = append(all, Count{Key: key, QSOs: found[key]})
+	}
+
+	return all
+}
+
+// get runs the named statement stmt returning a single row into dest
+func get(ctx context.Context, d *sqlx.DB, dest interface{}, stmt string, params map[string]interface{}) error {
+	q, err := d.PrepareNamedContext(ctx, stmt)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer q.Close()
+
+	err = q.GetContext(ctx, dest, params)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// selectRows runs the named statement stmt returning all rows into dest
+func selectRows(ctx context.Context, d *sqlx.DB, dest interface{}, stmt string, params map[string]interface{}) error {
+	q, err := d.PrepareNamedContext(ctx, stmt)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	defer q.Close()
+
+	err = q.SelectContext(ctx, dest, params)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
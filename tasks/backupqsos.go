@@ -1,6 +1,7 @@
 package tasks
 
 import (
+	"context"
 	"log"
 	"path/filepath"
 	"sync"
@@ -8,7 +9,6 @@ import (
 
 	"github.com/bbathe/golog/adif"
 	"github.com/bbathe/golog/config"
-	"github.com/bbathe/golog/models/qso"
 	"github.com/bbathe/golog/util"
 )
 
@@ -18,23 +18,29 @@ func BackupQSOs() {
 	muxBackupQSOs.Lock()
 	defer muxBackupQSOs.Unlock()
 
+	// backup format is picked by file extension
+	ext := ".adif"
+	if config.BackupFormat == "adx" {
+		ext = ".adx"
+	}
+
 	// form backup file name for QSOs
-	fname := filepath.Join(config.BackupDirectory, "BackupQSOs-"+time.Now().UTC().Format("2006-Jan-02_15-04-05")+".adif")
+	fname := filepath.Join(config.BackupDirectory, "BackupQSOs-"+time.Now().UTC().Format("2006-Jan-02_15-04-05")+ext)
 
 	// get all qsos & write them out to file
-	qs, err := qso.All()
+	qs, err := store.All(context.Background())
 	if err != nil {
 		log.Printf("%+v", err)
 		return
 	}
-	err = adif.WriteToFile(qs, fname)
+	err = adif.WriteToFile(store, qs, fname, adif.WriteOptions{Comment: "Backup of all QSOs"})
 	if err != nil {
 		log.Printf("%+v", err)
 		return
 	}
 
-	// only keep the last 5 backups
-	err = util.DeleteHistoricalFiles(5, config.BackupDirectory, "BackupQSOs-", ".adif")
+	// only keep the last 5 backups, regardless of format
+	err = util.DeleteHistoricalFiles(5, config.BackupDirectory, "BackupQSOs-", "")
 	if err != nil {
 		log.Printf("%+v", err)
 		return
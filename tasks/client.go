@@ -0,0 +1,174 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bbathe/golog/adif"
+	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/models/qso"
+)
+
+// how long requests can take when the client doesn't say
+const defaultClientTimeout = 5 * time.Minute
+
+// Client makes the http requests to a logbook service
+// fields other than BaseURL can be left empty to use the defaults
+type Client struct {
+	// scheme, host and any path prefix, the service's endpoints are relative to it
+	BaseURL string
+
+	// nil uses http.DefaultTransport
+	Transport http.RoundTripper
+
+	Timeout   time.Duration
+	UserAgent string
+}
+
+// ServiceError is a failed request to a logbook service, Class says if trying again can help
+type ServiceError struct {
+	Class qso.UploadErrorClass
+
+	// zero when there was no response
+	StatusCode int
+	Response   string
+
+	Err error
+}
+
+func (e *ServiceError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// newClient returns a client for the service at baseURL using the http settings in the configuration
+func newClient(baseURL string) Client {
+	c := Client{
+		BaseURL:   baseURL,
+		Timeout:   time.Duration(config.LogbookServices.HTTP.Timeout) * time.Second,
+		UserAgent: config.LogbookServices.HTTP.UserAgent,
+	}
+
+	if config.LogbookServices.HTTP.Proxy != "" {
+		proxy, err := url.Parse(config.LogbookServices.HTTP.Proxy)
+		if err != nil {
+			// config validation should have caught this
+			log.Printf("%+v", err)
+			return c
+		}
+
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.Proxy = http.ProxyURL(proxy)
+		c.Transport = t
+	}
+
+	return c
+}
+
+// httpErrorClass returns why a request that didn't return http.StatusOK failed
+// the service being unavailable or busy is a transport problem, otherwise it refused the request
+func httpErrorClass(statusCode int) qso.UploadErrorClass {
+	if statusCode >= 500 || statusCode == http.StatusTooManyRequests || statusCode == http.StatusRequestTimeout {
+		return qso.ErrorTransport
+	}
+	return qso.ErrorService
+}
+
+// endpoint returns the URL of the endpoint at path
+func (c *Client) endpoint(path string) string {
+	return strings.TrimSuffix(c.BaseURL, "/") + path
+}
+
+// redactURL removes the query from the URL in err, some services take credentials in the query
+// and the url.Errors returned by net/http include the whole URL
+func redactURL(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		if i := strings.IndexByte(ue.URL, '?'); i >= 0 {
+			ue.URL = ue.URL[:i] + "?REDACTED"
+		}
+	}
+	return err
+}
+
+// do sends the request and returns the body of the response, responses other than http.StatusOK are a *ServiceError
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	userAgent := c.UserAgent
+	if userAgent == "" {
+		userAgent = adif.ProgramID + "/" + adif.ProgramVersion
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultClientTimeout
+	}
+	client := http.Client{
+		Transport: c.Transport,
+		Timeout:   timeout,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		err = redactURL(err)
+		log.Printf("%+v", err)
+		return nil, &ServiceError{Class: qso.ErrorTransport, Response: err.Error(), Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		err = redactURL(err)
+		log.Printf("%+v", err)
+		return nil, &ServiceError{Class: qso.ErrorTransport, Response: err.Error(), Err: err}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("returned bad statuscode")
+		log.Printf("%+v", err)
+		log.Printf("StatusCode: %d", resp.StatusCode)
+		log.Printf("Header: %s", resp.Header)
+		log.Printf("Body: %s", string(body))
+		return body, &ServiceError{Class: httpErrorClass(resp.StatusCode), StatusCode: resp.StatusCode, Response: string(body), Err: err}
+	}
+
+	return body, nil
+}
+
+// get requests u, which is usually an endpoint with a query
+func (c *Client) get(u string) ([]byte, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		err = redactURL(err)
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return c.do(req)
+}
+
+// post sends body to the endpoint at path
+func (c *Client) post(path string, contentType string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest("POST", c.endpoint(path), body)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	return c.do(req)
+}
+
+// postForm sends the form values to the endpoint at path
+func (c *Client) postForm(path string, values url.Values) ([]byte, error) {
+	return c.post(path, "application/x-www-form-urlencoded", strings.NewReader(values.Encode()))
+}
@@ -2,18 +2,17 @@ package tasks
 
 import (
 	"bytes"
-	"fmt"
-	"io"
+	"context"
+	"errors"
 	"log"
 	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/bbathe/golog/adif"
-	"github.com/bbathe/golog/util"
 
 	"github.com/bbathe/golog/config"
 	"github.com/bbathe/golog/models/qso"
@@ -21,19 +20,50 @@ import (
 
 var muxClublogUpload sync.Mutex
 
+// ClublogClient uploads QSOs to Club Log and deletes them
+type ClublogClient struct {
+	Client
+
+	Email    string
+	Password string
+	Callsign string
+	APIKey   string
+}
+
+// NewClublogClient returns a Club Log client using the configuration
+func NewClublogClient() *ClublogClient {
+	return &ClublogClient{
+		Client:   newClient(config.LogbookServices.ClubLog.BaseURL()),
+		Email:    config.LogbookServices.ClubLog.Email,
+		Password: config.LogbookServices.ClubLog.Password,
+		Callsign: config.LogbookServices.ClubLog.Callsign,
+		APIKey:   config.LogbookServices.ClubLog.APIKey,
+	}
+}
+
 // QSLClublog uploads all QSOs to Club Log that are older than config.QSLDelay minutes old
 func QSLClublog() error {
 	muxClublogUpload.Lock()
 	defer muxClublogUpload.Unlock()
 
-	qsos, err := qso.FindQSLsToSend(qso.QSLClublog, config.LogbookServices.QSLDelay)
+	c := NewClublogClient()
+
+	// corrections come first, a qso waiting on one isn't uploaded again until it's made
+	waiting, err := correctClublog(c)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	qsos, err := store.FindQSLsToSend(context.Background(), qso.QSLClublog, config.LogbookServices.QSLDelay)
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
 	}
+	qsos = withoutWaiting(qsos, waiting)
 
 	if len(qsos) > 0 {
-		err = uploadQSOsToClublog(qsos)
+		err = uploadQSOsToClublog(c, qsos)
 		if err != nil {
 			log.Printf("%+v", err)
 			return err
@@ -48,14 +78,24 @@ func QSLClublogFinal() {
 	muxClublogUpload.Lock()
 	defer muxClublogUpload.Unlock()
 
-	qsos, err := qso.FindQSLsToSend(qso.QSLClublog, 0)
+	c := NewClublogClient()
+
+	// corrections come first, a qso waiting on one isn't uploaded again until it's made
+	waiting, err := correctClublog(c)
+	if err != nil {
+		log.Printf("%+v", err)
+		return
+	}
+
+	qsos, err := store.FindQSLsToSend(context.Background(), qso.QSLClublog, 0)
 	if err != nil {
 		log.Printf("%+v", err)
 		return
 	}
+	qsos = withoutWaiting(qsos, waiting)
 
 	if len(qsos) > 0 {
-		err = uploadQSOsToClublog(qsos)
+		err = uploadQSOsToClublog(c, qsos)
 		if err != nil {
 			log.Printf("%+v", err)
 			return
@@ -63,141 +103,138 @@ func QSLClublogFinal() {
 	}
 }
 
-// uploadQSOsToClublog uploads qsos to Club Log
-func uploadQSOsToClublog(qsos []qso.QSO) error {
-	// form working file name
-	fname := filepath.Join(config.WorkingDirectory, "Clublog-"+time.Now().UTC().Format("2006-Jan-02_15-04-05")+".adif")
+// uploadQSOsToClublog uploads qsos to Club Log and records how it went
+func uploadQSOsToClublog(c *ClublogClient, qsos []qso.QSO) error {
+	response, err := c.Upload(qsos)
+	if err != nil {
+		log.Printf("%+v", err)
+		uploadFailed(qsos, qso.QSLClublog, err)
+		return err
+	}
 
-	// write qsos as adif to file
-	err := adif.WriteToFile(qsos, fname)
+	// set as sent in db
+	err = store.UploadSucceeded(qso.WithSource(context.Background(), qso.SourceSync), qsos, qso.QSLClublog, response)
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
 	}
 
+	return nil
+}
+
+// Upload sends qsos to Club Log, returning the response
+// a single qso uses the realtime api, where a bad request means the qso was rejected
+func (c *ClublogClient) Upload(qsos []qso.QSO) (string, error) {
 	reqBody := &bytes.Buffer{}
 	w := multipart.NewWriter(reqBody)
-	var url string
+	var path string
 
 	// handle with bulk call or realtime call?
 	if len(qsos) > 1 {
-		// open file with qso data
-		f, err := os.Open(fname)
+		// create form part for file data
+		fname := "Clublog-" + time.Now().UTC().Format("2006-Jan-02_15-04-05") + ".adif"
+		p, err := w.CreateFormFile("file", fname)
 		if err != nil {
 			log.Printf("%+v", err)
-			return err
+			return "", err
 		}
-		defer f.Close()
 
-		// create & populate form part with file data
-		p, err := w.CreateFormFile("file", filepath.Base(fname))
-		if err != nil {
-			log.Printf("%+v", err)
-			return err
-		}
-		_, err = io.Copy(p, f)
+		// write qsos as adif directly into the form part
+		err = adif.WriteQSOs(adif.NewWriter(p), adif.NewHeader(adif.WriteOptions{Comment: "Upload to Club Log"}, nil), qsos)
 		if err != nil {
 			log.Printf("%+v", err)
-			return err
+			return "", err
 		}
 
-		url = "https://clublog.org/putlogs.php"
+		path = "/putlogs.php"
 	} else {
 		// single adif record upload
-		qsos = qsos[0:1]
-
 		s, err := adif.QSOToADIFRecord(qsos[0])
 		if err != nil {
 			log.Printf("%+v", err)
-			return err
+			return "", err
 		}
 
 		err = w.WriteField("adif", s)
 		if err != nil {
 			log.Printf("%+v", err)
-			return err
+			return "", err
 		}
 
-		url = "https://clublog.org/realtime.php"
+		path = "/realtime.php"
 	}
 
 	// set the other form fields required
-	err = w.WriteField("email", config.LogbookServices.ClubLog.Email)
+	err := w.WriteField("email", c.Email)
 	if err != nil {
 		log.Printf("%+v", err)
-		return err
+		return "", err
 	}
-	err = w.WriteField("password", config.LogbookServices.ClubLog.Password)
+	err = w.WriteField("password", c.Password)
 	if err != nil {
 		log.Printf("%+v", err)
-		return err
+		return "", err
 	}
-	err = w.WriteField("callsign", config.LogbookServices.ClubLog.Callsign)
+	err = w.WriteField("callsign", c.Callsign)
 	if err != nil {
 		log.Printf("%+v", err)
-		return err
+		return "", err
 	}
-	err = w.WriteField("api", config.LogbookServices.ClubLog.APIKey)
+	err = w.WriteField("api", c.APIKey)
 	if err != nil {
 		log.Printf("%+v", err)
-		return err
+		return "", err
 	}
 
 	// done forming request body
 	err = w.Close()
 	if err != nil {
 		log.Printf("%+v", err)
-		return err
-	}
-
-	// setup request
-	req, err := http.NewRequest("POST", url, reqBody)
-	if err != nil {
-		log.Printf("%+v", err)
-		return err
+		return "", err
 	}
-	req.Header.Set("Content-Type", w.FormDataContentType())
 
-	// do POST
-	client := http.Client{
-		Timeout: 5 * time.Minute,
-	}
-	resp, err := client.Do(req)
+	respBody, err := c.post(path, w.FormDataContentType(), reqBody)
 	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
-	defer resp.Body.Close()
+		// realtime uploads are one qso, so a bad request is that qso
+		var se *ServiceError
+		if len(qsos) == 1 && errors.As(err, &se) && se.StatusCode == http.StatusBadRequest {
+			se.Class = qso.ErrorRejected
+		}
 
-	// get response body
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
 		log.Printf("%+v", err)
-		return err
+		return "", err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf("returned bad statuscode")
-		log.Printf("%+v", err)
-		log.Printf("StatusCode: %d", resp.StatusCode)
-		log.Printf("Header: %s", resp.Header)
-		log.Printf("Body: %s", string(respBody))
-		return err
-	}
+	return string(respBody), nil
+}
 
-	// set as sent in db
-	err = qso.UpdateQSL(qsos, qso.QSLClublog, qso.Sent)
+// Delete removes the qso with call on band at datetime, "yyyy-mm-dd hh:mm:ss", from Club Log
+func (c *ClublogClient) Delete(call, band, datetime string) error {
+	formData := url.Values{}
+	formData.Set("email", c.Email)
+	formData.Set("password", c.Password)
+	formData.Set("callsign", c.Callsign)
+	formData.Set("api", c.APIKey)
+	formData.Set("dxcall", call)
+	formData.Set("datetime", datetime)
+	formData.Set("bandid", clublogBandID(band))
+
+	_, err := c.postForm("/delete.php", formData)
 	if err != nil {
-		log.Printf("%+v", err)
-		return err
-	}
+		// a bad request is a qso Club Log doesn't have, so there's nothing to delete
+		var se *ServiceError
+		if errors.As(err, &se) && (se.StatusCode == http.StatusBadRequest || se.StatusCode == http.StatusNotFound) {
+			se.Class = qso.ErrorRejected
+		}
 
-	// only keep the last 5 files of ours in the working directory
-	err = util.DeleteHistoricalFiles(5, config.WorkingDirectory, "Clublog-", ".adif")
-	if err != nil {
 		log.Printf("%+v", err)
 		return err
 	}
 
 	return nil
 }
+
+// clublogBandID returns the Club Log band id for the band, its wavelength without the unit
+func clublogBandID(band string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(band), "m"), "c")
+}
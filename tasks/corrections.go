@@ -0,0 +1,111 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/bbathe/golog/models/qso"
+)
+
+// correctClublog deletes the QSOs that were edited or deleted after being uploaded to Club Log
+// returns the QSOs still waiting on a correction, they can't be uploaded again until it's made
+func correctClublog(c *ClublogClient) (map[int64]bool, error) {
+	corrections, err := store.PendingCorrections(context.Background(), qso.QSLClublog)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return makeCorrections(corrections, func(correction qso.Correction) error {
+		return c.Delete(correction.Call, correction.Band, correction.Date+" "+correction.Time)
+	}), nil
+}
+
+// correctQRZ deletes the QSOs that were edited or deleted after being uploaded to QRZ.com
+// returns the QSOs still waiting on a correction, they can't be uploaded again until it's made
+func correctQRZ(c *QRZClient) (map[int64]bool, error) {
+	corrections, err := store.PendingCorrections(context.Background(), qso.QSLQrz)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return makeCorrections(corrections, func(correction qso.Correction) error {
+		return c.Delete(correction.QRZLogID)
+	}), nil
+}
+
+// makeCorrections makes the corrections that are due with correct, a failure doesn't stop the others being made
+// returns the QSOs with corrections that haven't been made
+func makeCorrections(corrections []qso.Correction, correct func(qso.Correction) error) map[int64]bool {
+	waiting := map[int64]bool{}
+	now := time.Now().Unix()
+
+	for _, correction := range corrections {
+		if correction.NextAttemptAt > now {
+			waiting[correction.QSOID] = true
+			continue
+		}
+
+		err := correct(correction)
+		if err != nil {
+			log.Printf("%+v", err)
+
+			// rejected corrections are dropped, there's nothing left to wait for
+			if correctionFailed(correction, err) != qso.ErrorRejected {
+				waiting[correction.QSOID] = true
+			}
+		} else {
+			err = store.CorrectionDone(context.Background(), correction)
+			if err != nil {
+				log.Printf("%+v", err)
+				waiting[correction.QSOID] = true
+			}
+		}
+
+		// pause between requests
+		time.Sleep(1 * time.Second)
+	}
+
+	return waiting
+}
+
+// correctionFailed records the failed correction because of err and returns why it failed
+// errors that aren't a *ServiceError are treated as the service refusing the request
+func correctionFailed(correction qso.Correction, err error) qso.UploadErrorClass {
+	class := qso.ErrorService
+	response := err.Error()
+
+	var se *ServiceError
+	if errors.As(err, &se) {
+		class = se.Class
+		if se.Response != "" {
+			response = se.Response
+		}
+	}
+
+	err = store.CorrectionFailed(context.Background(), correction, class, response)
+	if err != nil {
+		log.Printf("%+v", err)
+	}
+
+	return class
+}
+
+// withoutWaiting returns the qsos that aren't waiting on a correction
+func withoutWaiting(qsos []qso.QSO, waiting map[int64]bool) []qso.QSO {
+	if len(waiting) == 0 {
+		return qsos
+	}
+
+	upload := make([]qso.QSO, 0, len(qsos))
+	for _, q := range qsos {
+		if !waiting[q.ID] {
+			upload = append(upload, q)
+		}
+	}
+
+	return upload
+}
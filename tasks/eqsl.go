@@ -0,0 +1,382 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbathe/golog/adif"
+	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/models/qso"
+)
+
+var (
+	muxEqslUpload sync.Mutex
+
+	muxEqslInbox     sync.Mutex
+	lastEqslDownload time.Time
+
+	// the inbox response is a html page linking to the adif file
+	reEqslInboxLink = regexp.MustCompile(`(?i)href="([^"]+\.adi)"`)
+)
+
+const (
+	// how often the inbox is downloaded
+	eqslInboxInterval = 1 * time.Hour
+
+	// how far apart our time and the other station's time can be and still match
+	eqslMatchWindow = 30 * time.Minute
+)
+
+// EqslClient uploads QSOs to eQSL.cc and downloads the inbox
+type EqslClient struct {
+	Client
+
+	Username    string
+	Password    string
+	QTHNickname string
+}
+
+// NewEqslClient returns an eQSL.cc client using the configuration
+func NewEqslClient() *EqslClient {
+	return &EqslClient{
+		Client:      newClient(config.LogbookServices.Eqsl.BaseURL()),
+		Username:    config.LogbookServices.Eqsl.Username,
+		Password:    config.LogbookServices.Eqsl.Password,
+		QTHNickname: config.LogbookServices.Eqsl.QTHNickname,
+	}
+}
+
+// QSLEqsl uploads all QSOs to eQSL.cc that are older than config.QSLDelay minutes old
+func QSLEqsl() error {
+	muxEqslUpload.Lock()
+	defer muxEqslUpload.Unlock()
+
+	qsos, err := store.FindQSLsToSend(context.Background(), qso.QSLEqsl, config.LogbookServices.QSLDelay)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	if len(qsos) > 0 {
+		err = uploadQSOsToEqsl(NewEqslClient(), qsos)
+		if err != nil {
+			log.Printf("%+v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QSLEqslFinal uploads all QSOs to eQSL.cc regardless of how old they are
+func QSLEqslFinal() {
+	muxEqslUpload.Lock()
+	defer muxEqslUpload.Unlock()
+
+	qsos, err := store.FindQSLsToSend(context.Background(), qso.QSLEqsl, 0)
+	if err != nil {
+		log.Printf("%+v", err)
+		return
+	}
+
+	if len(qsos) > 0 {
+		err = uploadQSOsToEqsl(NewEqslClient(), qsos)
+		if err != nil {
+			log.Printf("%+v", err)
+			return
+		}
+	}
+}
+
+// uploadQSOsToEqsl uploads qsos to eQSL.cc and records how it went
+func uploadQSOsToEqsl(c *EqslClient, qsos []qso.QSO) error {
+	response, err := c.Upload(qsos)
+	if err != nil {
+		log.Printf("%+v", err)
+		uploadFailed(qsos, qso.QSLEqsl, err)
+		return err
+	}
+
+	// set as sent in db
+	err = store.UploadSucceeded(qso.WithSource(context.Background(), qso.SourceSync), qsos, qso.QSLEqsl, response)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Upload sends qsos to eQSL.cc, returning the response
+func (c *EqslClient) Upload(qsos []qso.QSO) (string, error) {
+	reqBody := &bytes.Buffer{}
+	w := multipart.NewWriter(reqBody)
+
+	err := w.WriteField("EQSL_USER", c.Username)
+	if err != nil {
+		log.Printf("%+v", err)
+		return "", err
+	}
+	err = w.WriteField("EQSL_PSWD", c.Password)
+	if err != nil {
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	// create form part for file data
+	fname := "eQSL-" + time.Now().UTC().Format("2006-Jan-02_15-04-05") + ".adif"
+	p, err := w.CreateFormFile("Filename", fname)
+	if err != nil {
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	// the qth is picked per record
+	upload := qsos
+	if c.QTHNickname != "" {
+		upload = make([]qso.QSO, len(qsos))
+		for i, q := range qsos {
+			q.Extra = append(qso.ExtraFields{}, q.Extra...)
+			q.Extra = append(q.Extra, qso.ExtraField{Name: "APP_EQSL_QTH_NICKNAME", Value: c.QTHNickname})
+			upload[i] = q
+		}
+	}
+
+	// write qsos as adif directly into the form part
+	err = adif.WriteQSOs(adif.NewWriter(p), adif.NewHeader(adif.WriteOptions{Comment: "Upload to eQSL"}, nil), upload)
+	if err != nil {
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	// done forming request body
+	err = w.Close()
+	if err != nil {
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	respBody, err := c.post("/ImportADIF.cfm", w.FormDataContentType(), reqBody)
+	if err != nil {
+		log.Printf("%+v", err)
+		return "", err
+	}
+
+	// errors come back as a page with 200 status, duplicates are only warnings
+	body := string(respBody)
+	if strings.Contains(body, "Error:") || !strings.Contains(body, "Result:") {
+		err := fmt.Errorf("eQSL upload failed")
+		log.Printf("%+v", err)
+		log.Printf("Body: %s", body)
+
+		// without a result the upload as a whole failed, ie bad login
+		// otherwise records were bad but only a single qso can be blamed
+		class := qso.ErrorService
+		if len(qsos) == 1 && strings.Contains(body, "Result:") {
+			class = qso.ErrorRejected
+		}
+		return "", &ServiceError{Class: class, StatusCode: http.StatusOK, Response: body, Err: err}
+	}
+
+	return body, nil
+}
+
+// EqslInbox downloads confirmations from the eQSL.cc inbox and marks the matching QSOs as confirmed
+func EqslInbox() error {
+	muxEqslInbox.Lock()
+	defer muxEqslInbox.Unlock()
+
+	if time.Since(lastEqslDownload) < eqslInboxInterval {
+		return nil
+	}
+
+	// failed attempts count too so eQSL.cc isn't asked again until the next interval
+	lastEqslDownload = time.Now()
+
+	// only ask for what's new since the last confirmation we have
+	since, err := store.LastQSLReceived(context.Background(), qso.QSLEqsl)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	body, err := NewEqslClient().Inbox(since)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	matched, unmatched, err := applyEqslConfirmations(adif.NewReader(bytes.NewReader(body)))
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	log.Printf("eQSL confirmations since %q: %d matched, %d unmatched", since, matched, unmatched)
+
+	return nil
+}
+
+// Inbox returns the adif of eQSLs received since date, all of them if date is empty
+// the inbox page links to a generated file which has the adif
+func (c *EqslClient) Inbox(since string) ([]byte, error) {
+	params := url.Values{}
+	params.Set("UserName", c.Username)
+	params.Set("Password", c.Password)
+	if c.QTHNickname != "" {
+		params.Set("QTHNickname", c.QTHNickname)
+	}
+	if since != "" {
+		params.Set("RcvdSince", strings.ReplaceAll(since, "-", ""))
+	}
+
+	inboxURL, err := url.Parse(c.endpoint("/DownloadInBox.cfm"))
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+	inboxURL.RawQuery = params.Encode()
+
+	page, err := c.get(inboxURL.String())
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	if strings.Contains(string(page), "Error:") {
+		err = fmt.Errorf("eQSL inbox failed, check eQSL username & password")
+		log.Printf("%+v", err)
+		log.Printf("Body: %s", string(page))
+		return nil, err
+	}
+
+	m := reEqslInboxLink.FindSubmatch(page)
+	if m == nil {
+		// nothing new
+		if strings.Contains(strings.ToLower(string(page)), "no log entries") {
+			return []byte{}, nil
+		}
+
+		err = fmt.Errorf("eQSL inbox has no link to the ADIF file")
+		log.Printf("%+v", err)
+		log.Printf("Body: %s", string(page))
+		return nil, err
+	}
+
+	// link is relative to the inbox page
+	link, err := inboxURL.Parse(string(m[1]))
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	body, err := c.get(link.String())
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// applyEqslConfirmations marks the QSOs matching the eQSLs read from rr as confirmed
+// returns how many eQSLs matched a QSO and how many didn't
+func applyEqslConfirmations(rr adif.RecordReader) (int, int, error) {
+	matched := 0
+	unmatched := 0
+
+	// matched qsos grouped by the date they were received
+	confirmed := map[string][]qso.QSO{}
+
+	for {
+		r, err := rr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// reader skips malformed records
+			var pe *adif.ParseError
+			if errors.As(err, &pe) {
+				log.Printf("%+v", err)
+				unmatched++
+				continue
+			}
+			log.Printf("%+v", err)
+			return matched, unmatched, err
+		}
+
+		q, rdate, err := matchEqslConfirmation(r)
+		if err != nil {
+			log.Printf("%+v", err)
+			return matched, unmatched, err
+		}
+		if q == nil {
+			unmatched++
+			continue
+		}
+
+		confirmed[rdate] = append(confirmed[rdate], *q)
+		matched++
+	}
+
+	for rdate, qsos := range confirmed {
+		err := store.UpdateQSLReceived(qso.WithSource(context.Background(), qso.SourceSync), qsos, qso.QSLEqsl, qso.Received, rdate)
+		if err != nil {
+			log.Printf("%+v", err)
+			return matched, unmatched, err
+		}
+	}
+
+	return matched, unmatched, nil
+}
+
+// matchEqslConfirmation returns the QSO matching the eQSL record and the date it was received, empty if not known
+func matchEqslConfirmation(r adif.Record) (*qso.QSO, string, error) {
+	c, err := adif.QSOFromRecord(r)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, "", nil
+	}
+
+	at, err := time.Parse("2006-01-02 15:04:05", c.Date+" "+c.Time)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, "", nil
+	}
+
+	qsos, err := store.Near(context.Background(), c.Call, c.Band, at, eqslMatchWindow)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, "", err
+	}
+
+	// closest qso in the same mode
+	for i := range qsos {
+		if qsos[i].Mode == c.Mode {
+			q := &qsos[i]
+
+			// received date is in the card fields of the inbox, which we map to the card
+			rdate := c.QSLCardRcvdDate
+
+			// eQSLs seen again keep the date they were first received
+			if rdate == "" && q.QSLEqslRcvd.Confirmed() {
+				rdate = q.QSLEqslRcvdDate
+			}
+
+			return q, rdate, nil
+		}
+	}
+
+	log.Printf("no QSO matches eQSL %s %s %s %s %s", c.Call, c.Band, c.Mode, c.Date, c.Time)
+	return nil, "", nil
+}
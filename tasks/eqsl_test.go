@@ -0,0 +1,78 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEqslInbox(t *testing.T) {
+	const adi = "<eoh>\n<call:4>W1AW<qsl_rcvd:1>Y<eor>\n"
+
+	tests := []struct {
+		name    string
+		since   string
+		page    string
+		want    string
+		wantErr bool
+	}{
+		{name: "link", page: `<a href="downloadedfiles/k0abc123.adi">.ADI file</a>`, want: adi},
+		{name: "since", since: "2024-03-01", page: `<A HREF="downloadedfiles/k0abc123.adi">.ADI file</A>`, want: adi},
+		{name: "nothing new", page: "<p>You have no log entries</p>"},
+		{name: "bad login", page: "Error: No such Username/Password found", wantErr: true},
+		{name: "no link", page: "<p>try again later</p>", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/qslcard/DownloadInBox.cfm":
+					q := r.URL.Query()
+					want := ""
+					if tt.since != "" {
+						want = "20240301"
+					}
+					if q.Get("UserName") != "k0abc" || q.Get("Password") != "s3cret!" || q.Get("RcvdSince") != want {
+						t.Errorf("unexpected request %s", r.URL)
+					}
+					fmt.Fprint(w, tt.page)
+				case "/qslcard/downloadedfiles/k0abc123.adi":
+					fmt.Fprint(w, adi)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			c := &EqslClient{Client: Client{BaseURL: srv.URL + "/qslcard"}, Username: "k0abc", Password: "s3cret!"}
+			body, err := c.Inbox(tt.since)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Inbox() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(body) != tt.want {
+				t.Errorf("Inbox() = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestEqslInboxRedactsPassword(t *testing.T) {
+	logged := captureLog(t)
+
+	c := &EqslClient{
+		Client: Client{
+			BaseURL: "https://eqsl.example/qslcard",
+			Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+				return nil, errors.New("connection reset by peer")
+			}),
+		},
+		Username: "k0abc",
+		Password: "s3cret!",
+	}
+
+	_, err := c.Inbox("2024-03-01")
+	requireNoSecret(t, "s3cret", err, logged)
+}
@@ -2,6 +2,8 @@ package tasks
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -24,7 +26,7 @@ func QSLLotw() error {
 	muxLotwUpload.Lock()
 	defer muxLotwUpload.Unlock()
 
-	qsos, err := qso.FindQSLsToSend(qso.QSLLotw, config.LogbookServices.QSLDelay)
+	qsos, err := store.FindQSLsToSend(context.Background(), qso.QSLLotw, config.LogbookServices.QSLDelay)
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
@@ -60,7 +62,7 @@ func QSLLotwFinal() {
 	muxLotwUpload.Lock()
 	defer muxLotwUpload.Unlock()
 
-	qsos, err := qso.FindQSLsToSend(qso.QSLLotw, 0)
+	qsos, err := store.FindQSLsToSend(context.Background(), qso.QSLLotw, 0)
 	if err != nil {
 		log.Printf("%+v", err)
 		return
@@ -75,13 +77,38 @@ func QSLLotwFinal() {
 	}
 }
 
+// tqsl exit codes we handle, see the tqsl documentation for the rest
+const (
+	tqslAllDuplicates  = 8
+	tqslSomeDuplicates = 9
+	tqslConnection     = 11
+)
+
+// tqslUploaded returns true if tqsl failing means the qsos are already on LoTW or can never be, so there's no point retrying
+func tqslUploaded(err error) bool {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode() == tqslAllDuplicates || exitErr.ExitCode() == tqslSomeDuplicates
+	}
+	return false
+}
+
+// tqslErrorClass returns why tqsl failed, every problem it has is with the upload as a whole
+func tqslErrorClass(err error) qso.UploadErrorClass {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == tqslConnection {
+		return qso.ErrorTransport
+	}
+	return qso.ErrorService
+}
+
 // uploadQSOsToLoTW leverages tqsl to upload qsos to LoTW
 func uploadQSOsToLoTW(qsos []qso.QSO, _ bool) error {
 	// form working file name
 	fname := filepath.Join(config.WorkingDirectory, "LoTW-"+time.Now().UTC().Format("2006-Jan-02_15-04-05")+".adif")
 
 	// write qsos as adif to file
-	err := adif.WriteToFile(qsos, fname)
+	err := adif.WriteToFile(store, qsos, fname, adif.WriteOptions{Comment: "Upload to LoTW"})
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
@@ -106,15 +133,16 @@ func uploadQSOsToLoTW(qsos []qso.QSO, _ bool) error {
 
 	// doit!
 	err = cmd.Run()
-	if err != nil {
+	if err != nil && !tqslUploaded(err) {
 		log.Printf("error: %+v", err)
 		log.Printf("stdout: %s", stdout.String())
 		log.Printf("stderr: %s", stderr.String())
+		uploadFailed(qsos, qso.QSLLotw, &ServiceError{Class: tqslErrorClass(err), Response: err.Error() + "\n" + stderr.String(), Err: err})
 		return err
 	}
 
 	// set as sent in db
-	err = qso.UpdateQSL(qsos, qso.QSLLotw, qso.Sent)
+	err = store.UploadSucceeded(qso.WithSource(context.Background(), qso.SourceSync), qsos, qso.QSLLotw, stderr.String())
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
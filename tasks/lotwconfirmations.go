@@ -0,0 +1,241 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbathe/golog/adif"
+	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/models/qso"
+)
+
+var (
+	muxLotwConfirmations sync.Mutex
+	lastLotwDownload     time.Time
+)
+
+const (
+	// how often confirmations are downloaded, LoTW asks that it isn't polled
+	lotwDownloadInterval = 1 * time.Hour
+
+	// how far apart our time and the other station's time can be and still match, same as LoTW uses
+	lotwMatchWindow = 30 * time.Minute
+)
+
+// LoTWClient downloads confirmations from LoTW, uploads are done by tqsl
+type LoTWClient struct {
+	Client
+
+	Username string
+	Password string
+}
+
+// NewLoTWClient returns a LoTW client using the configuration
+func NewLoTWClient() *LoTWClient {
+	return &LoTWClient{
+		Client:   newClient(config.LogbookServices.LoTW.BaseURL()),
+		Username: config.LogbookServices.LoTW.Username,
+		Password: config.LogbookServices.LoTW.Password,
+	}
+}
+
+// LoTWConfirmations downloads confirmations from LoTW and marks the matching QSOs as confirmed
+func LoTWConfirmations() error {
+	muxLotwConfirmations.Lock()
+	defer muxLotwConfirmations.Unlock()
+
+	if time.Since(lastLotwDownload) < lotwDownloadInterval {
+		return nil
+	}
+
+	// failed attempts count too so LoTW isn't asked again until the next interval
+	lastLotwDownload = time.Now()
+
+	// only ask for what's new since the last confirmation we have
+	since, err := store.LastQSLReceived(context.Background(), qso.QSLLotw)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	body, err := NewLoTWClient().Report(since)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	matched, unmatched, err := applyLoTWConfirmations(adif.NewReader(bytes.NewReader(body)))
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	log.Printf("LoTW confirmations since %q: %d matched, %d unmatched", since, matched, unmatched)
+
+	return nil
+}
+
+// Report returns the adif of QSOs confirmed since date, all of them if date is empty
+func (c *LoTWClient) Report(since string) ([]byte, error) {
+	params := url.Values{}
+	params.Set("login", c.Username)
+	params.Set("password", c.Password)
+	params.Set("qso_query", "1")
+	params.Set("qso_qsl", "yes")
+	params.Set("qso_qsldetail", "yes")
+	if since != "" {
+		params.Set("qso_qslsince", since)
+	}
+
+	body, err := c.get(c.endpoint("/lotwreport.adi") + "?" + params.Encode())
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	// bad logins get a html page instead of adif
+	if !strings.Contains(strings.ToLower(string(body)), "<eoh>") {
+		err = fmt.Errorf("LoTW report is not ADIF, check LoTW username & password")
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// applyLoTWConfirmations marks the QSOs matching the confirmations read from rr as confirmed
+// returns how many confirmations matched a QSO and how many didn't
+func applyLoTWConfirmations(rr adif.RecordReader) (int, int, error) {
+	unmatched := 0
+
+	// matched qsos grouped by the date they were confirmed
+	confirmed := map[string][]qso.QSO{}
+	var located []qso.QSO
+
+	for {
+		r, err := rr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// reader skips malformed records
+			var pe *adif.ParseError
+			if errors.As(err, &pe) {
+				log.Printf("%+v", err)
+				unmatched++
+				continue
+			}
+			log.Printf("%+v", err)
+			return len(located), unmatched, err
+		}
+
+		q, rdate, err := matchLoTWConfirmation(r)
+		if err != nil {
+			log.Printf("%+v", err)
+			return len(located), unmatched, err
+		}
+		if q == nil {
+			unmatched++
+			continue
+		}
+
+		confirmed[rdate] = append(confirmed[rdate], *q)
+		located = append(located, *q)
+	}
+
+	for rdate, qsos := range confirmed {
+		err := store.UpdateQSLReceived(qso.WithSource(context.Background(), qso.SourceSync), qsos, qso.QSLLotw, qso.Received, rdate)
+		if err != nil {
+			log.Printf("%+v", err)
+			return len(located), unmatched, err
+		}
+	}
+
+	if len(located) > 0 {
+		err := store.UpdateLocation(qso.WithSource(context.Background(), qso.SourceSync), located)
+		if err != nil {
+			log.Printf("%+v", err)
+			return len(located), unmatched, err
+		}
+	}
+
+	return len(located), unmatched, nil
+}
+
+// matchLoTWConfirmation returns the QSO matching the confirmation record and the date it was confirmed
+// the QSO has the dxcc entity, grid & zones LoTW has for the other station
+func matchLoTWConfirmation(r adif.Record) (*qso.QSO, string, error) {
+	if rcvd, _ := r.Get("QSL_RCVD"); !qso.QSLRcvd(strings.ToUpper(rcvd)).Confirmed() {
+		return nil, "", nil
+	}
+
+	c, err := adif.QSOFromRecord(r)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, "", nil
+	}
+
+	at, err := time.Parse("2006-01-02 15:04:05", c.Date+" "+c.Time)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, "", nil
+	}
+
+	qsos, err := store.Near(context.Background(), c.Call, c.Band, at, lotwMatchWindow)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, "", err
+	}
+
+	// closest qso in the same mode
+	var q *qso.QSO
+	for i := range qsos {
+		if qsos[i].Mode == c.Mode {
+			q = &qsos[i]
+			break
+		}
+	}
+	if q == nil {
+		log.Printf("no QSO matches LoTW confirmation %s %s %s %s %s", c.Call, c.Band, c.Mode, c.Date, c.Time)
+		return nil, "", nil
+	}
+
+	rdate := lotwRcvdDate(r)
+
+	// keep what we know if LoTW doesn't have it
+	if c.DXCC != 0 {
+		q.DXCC = c.DXCC
+	}
+	if c.GridSquare != "" {
+		q.GridSquare = c.GridSquare
+	}
+	if c.CQZone != 0 {
+		q.CQZone = c.CQZone
+	}
+	if c.ITUZone != 0 {
+		q.ITUZone = c.ITUZone
+	}
+
+	return q, rdate, nil
+}
+
+// lotwRcvdDate returns the date LoTW confirmed the record as yyyy-mm-dd, empty if it isn't there
+// lotwreport.adi puts it in QSLRDATE, which is the card's field everywhere else
+func lotwRcvdDate(r adif.Record) string {
+	for _, name := range []string{"LOTW_QSLRDATE", "QSLRDATE"} {
+		v, _ := r.Get(name)
+		t, err := time.Parse("20060102", strings.TrimSpace(v))
+		if err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,136 @@
+package tasks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bbathe/golog/models/qso"
+)
+
+// roundTripFunc is a transport that calls the function instead of making a request
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// captureLog sends the standard logger to the buffer returned until the test is done
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(w) })
+
+	return &buf
+}
+
+// requireNoSecret fails the test if secret is in the error, its response or what was logged
+func requireNoSecret(t *testing.T, secret string, err error, logged *bytes.Buffer) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	if strings.Contains(err.Error(), secret) {
+		t.Errorf("error has the secret: %v", err)
+	}
+	var se *ServiceError
+	if errors.As(err, &se) && strings.Contains(se.Response, secret) {
+		t.Errorf("response has the secret: %s", se.Response)
+	}
+	if strings.Contains(logged.String(), secret) {
+		t.Errorf("log has the secret: %s", logged)
+	}
+}
+
+func TestLoTWReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		since   string
+		body    string
+		wantErr bool
+	}{
+		{name: "all", body: "ARRL Logbook of the World Status Report\n<eoh>\n<call:4>W1AW<qsl_rcvd:1>Y<eor>\n"},
+		{name: "since", since: "2024-03-01", body: "<EOH>\n"},
+		{name: "bad login", body: "<html><body>Username/password incorrect</body></html>", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				q := r.URL.Query()
+				if r.URL.Path != "/lotwreport.adi" || q.Get("login") != "k0abc" || q.Get("password") != "s3cret!" || q.Get("qso_qslsince") != tt.since {
+					t.Errorf("unexpected request %s", r.URL)
+				}
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			c := &LoTWClient{Client: Client{BaseURL: srv.URL}, Username: "k0abc", Password: "s3cret!"}
+			body, err := c.Report(tt.since)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Report() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && string(body) != tt.body {
+				t.Errorf("Report() = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestLoTWReportRedactsPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+	}{
+		{name: "transport", baseURL: "https://lotw.example"},
+		{name: "bad url", baseURL: "https://lotw.example\x7f"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logged := captureLog(t)
+
+			c := &LoTWClient{
+				Client: Client{
+					BaseURL: tt.baseURL,
+					Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+						return nil, errors.New("connection reset by peer")
+					}),
+				},
+				Username: "k0abc",
+				Password: "s3cret!",
+			}
+
+			_, err := c.Report("2024-03-01")
+			requireNoSecret(t, "s3cret", err, logged)
+		})
+	}
+}
+
+func TestLoTWReportTransportIsRetried(t *testing.T) {
+	c := &LoTWClient{
+		Client: Client{
+			BaseURL: "https://lotw.example",
+			Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+				return nil, errors.New("connection reset by peer")
+			}),
+		},
+		Username: "k0abc",
+		Password: "s3cret!",
+	}
+
+	_, err := c.Report("")
+	var se *ServiceError
+	if !errors.As(err, &se) || se.Class != qso.ErrorTransport {
+		t.Errorf("Report() error = %v, want a transport error", err)
+	}
+}
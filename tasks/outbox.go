@@ -0,0 +1,29 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/bbathe/golog/models/qso"
+)
+
+// uploadFailed puts the qsos in the outbox because of err, the upload error is what gets returned so this only logs
+// errors that aren't a *ServiceError are treated as the service refusing the upload
+func uploadFailed(qsos []qso.QSO, service qso.QSLService, err error) {
+	class := qso.ErrorService
+	response := err.Error()
+
+	var se *ServiceError
+	if errors.As(err, &se) {
+		class = se.Class
+		if se.Response != "" {
+			response = se.Response
+		}
+	}
+
+	err = store.UploadFailed(qso.WithSource(context.Background(), qso.SourceSync), qsos, service, class, response)
+	if err != nil {
+		log.Printf("%+v", err)
+	}
+}
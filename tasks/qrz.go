@@ -1,8 +1,10 @@
 package tasks
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"html"
 	"log"
 	"net/http"
 	"net/url"
@@ -21,19 +23,44 @@ import (
 
 var muxQrzUpload sync.Mutex
 
+// QRZClient calls the QRZ.com logbook api
+type QRZClient struct {
+	Client
+
+	APIKey string
+}
+
+// NewQRZClient returns a QRZ.com logbook client using the configuration
+func NewQRZClient() *QRZClient {
+	return &QRZClient{
+		Client: newClient(config.LogbookServices.QRZ.BaseURL()),
+		APIKey: config.LogbookServices.QRZ.APIKey,
+	}
+}
+
 // QSLQrz uploads all QSOs to QRZ.com that are older than config.QSLDelay minutes old
 func QSLQrz() error {
 	muxQrzUpload.Lock()
 	defer muxQrzUpload.Unlock()
 
-	qsos, err := qso.FindQSLsToSend(qso.QSLQrz, config.LogbookServices.QSLDelay)
+	c := NewQRZClient()
+
+	// corrections come first, a qso waiting on one isn't uploaded again until it's made
+	waiting, err := correctQRZ(c)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	qsos, err := store.FindQSLsToSend(context.Background(), qso.QSLQrz, config.LogbookServices.QSLDelay)
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
 	}
+	qsos = withoutWaiting(qsos, waiting)
 
 	if len(qsos) > 0 {
-		err = uploadQSOsToQRZ(qsos)
+		err = uploadQSOsToQRZ(c, qsos)
 		if err != nil {
 			log.Printf("%+v", err)
 			return err
@@ -48,14 +75,24 @@ func QSLQrzFinal() {
 	muxQrzUpload.Lock()
 	defer muxQrzUpload.Unlock()
 
-	qsos, err := qso.FindQSLsToSend(qso.QSLQrz, 0)
+	c := NewQRZClient()
+
+	// corrections come first, a qso waiting on one isn't uploaded again until it's made
+	waiting, err := correctQRZ(c)
+	if err != nil {
+		log.Printf("%+v", err)
+		return
+	}
+
+	qsos, err := store.FindQSLsToSend(context.Background(), qso.QSLQrz, 0)
 	if err != nil {
 		log.Printf("%+v", err)
 		return
 	}
+	qsos = withoutWaiting(qsos, waiting)
 
 	if len(qsos) > 0 {
-		err = uploadQSOsToQRZ(qsos)
+		err = uploadQSOsToQRZ(c, qsos)
 		if err != nil {
 			log.Printf("%+v", err)
 			return
@@ -63,96 +100,54 @@ func QSLQrzFinal() {
 	}
 }
 
-// uploadQSOsToQRZ uploads qsos to QRZ.com
-func uploadQSOsToQRZ(qsos []qso.QSO) error {
+// uploadQSOsToQRZ uploads qsos to QRZ.com and records how it went
+func uploadQSOsToQRZ(c *QRZClient, qsos []qso.QSO) error {
 	// save all the qsos we are uploading to file
 	fname := filepath.Join(config.WorkingDirectory, "QRZ-"+time.Now().UTC().Format("2006-Jan-02_15-04-05")+".adif")
 
 	// write qsos as adif to file
-	err := adif.WriteToFile(qsos, fname)
+	err := adif.WriteToFile(store, qsos, fname, adif.WriteOptions{Comment: "Upload to QRZ.com"})
 	if err != nil {
 		log.Printf("%+v", err)
 		return err
 	}
 
-	i := 0
-	for _, q := range qsos {
+	// qsos the service rejected don't stop the rest from being uploaded
+	rejected := 0
+
+	for i, q := range qsos {
 		if i > 0 {
 			// pause between uploads
 			time.Sleep(1 * time.Second)
 		}
-		i++
 
-		// set the required form fields
-		formData := url.Values{}
-		s, err := adif.QSOToADIFRecord(q)
-		if err != nil {
-			log.Printf("%+v", err)
-			return err
-		}
-		formData.Set("ADIF", s)
-		formData.Set("KEY", config.LogbookServices.QRZ.APIKey)
-		formData.Set("ACTION", "INSERT")
-		formData.Set("OPTION", "REPLACE")
-
-		// setup request
-		reqBody := formData.Encode()
-		req, err := http.NewRequest("POST", "https://logbook.qrz.com/api", strings.NewReader(reqBody))
+		logID, response, err := c.Insert(q)
 		if err != nil {
 			log.Printf("%+v", err)
-			return err
-		}
-		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-		req.Header.Add("Content-Length", strconv.Itoa(len(reqBody)))
-
-		// do POST
-		client := http.Client{
-			Timeout: 5 * time.Minute,
-		}
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Printf("%+v", err)
-			return err
-		}
-		defer resp.Body.Close()
-
-		// get response body
-		respBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Printf("%+v", err)
-			return err
-		}
+			uploadFailed([]qso.QSO{q}, qso.QSLQrz, err)
 
-		if resp.StatusCode != http.StatusOK {
-			err := fmt.Errorf("returned bad statuscode")
-			log.Printf("%+v", err)
-			log.Printf("StatusCode: %d", resp.StatusCode)
-			log.Printf("Header: %s", resp.Header)
-			log.Printf("Body: %s", string(respBody))
+			var se *ServiceError
+			if errors.As(err, &se) && se.Class == qso.ErrorRejected {
+				rejected++
+				continue
+			}
 			return err
 		}
 
-		// parse response to see if it was really a success
-		m, err := url.ParseQuery(string(respBody))
+		// set as sent in db
+		err = store.UploadSucceeded(qso.WithSource(context.Background(), qso.SourceSync), []qso.QSO{q}, qso.QSLQrz, response)
 		if err != nil {
 			log.Printf("%+v", err)
 			return err
 		}
-		if m.Get("RESULT") == "FAIL" {
-			err := fmt.Errorf("api returned bad status")
-			log.Printf("%+v", err)
-			log.Printf("StatusCode: %d", resp.StatusCode)
-			log.Printf("Header: %s", resp.Header)
-			log.Printf("Return Values: %+v", m)
-			log.Printf("Body: %s", string(respBody))
-			return err
-		}
 
-		// set as sent in db
-		err = qso.UpdateQSL([]qso.QSO{q}, qso.QSLQrz, qso.Sent)
-		if err != nil {
-			log.Printf("%+v", err)
-			return err
+		// keep the logid so the qso can be corrected later
+		if logID != 0 {
+			err = store.UpdateQRZLogID(context.Background(), q.ID, logID)
+			if err != nil {
+				log.Printf("%+v", err)
+				return err
+			}
 		}
 	}
 
@@ -163,5 +158,103 @@ func uploadQSOsToQRZ(qsos []qso.QSO) error {
 		return err
 	}
 
+	if rejected > 0 {
+		err = fmt.Errorf("QRZ.com rejected %d QSOs", rejected)
+		log.Printf("%+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// qrzFailClass returns why QRZ.com failed a request from the result and reason it gave
+// problems with the api key fail every qso, anything else is the qso
+func qrzFailClass(result, reason string) qso.UploadErrorClass {
+	if result == "AUTH" {
+		return qso.ErrorService
+	}
+
+	r := strings.ToLower(reason)
+	if strings.Contains(r, "key") || strings.Contains(r, "access") || strings.Contains(r, "authoriz") {
+		return qso.ErrorService
+	}
+	return qso.ErrorRejected
+}
+
+// Request calls the api with the action in params, returning the response values
+// the ADIF value is returned as-is and unescaped
+func (c *QRZClient) Request(params url.Values) (url.Values, error) {
+	params.Set("KEY", c.APIKey)
+
+	respBody, err := c.postForm("/api", params)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	// adif is last and can have characters that would break parsing it as a query
+	body := string(respBody)
+	var adifValue string
+	if i := strings.Index(body, "ADIF="); i >= 0 {
+		adifValue = html.UnescapeString(body[i+len("ADIF="):])
+		body = strings.TrimSuffix(body[:i], "&")
+	}
+
+	m, err := url.ParseQuery(body)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, &ServiceError{Class: qso.ErrorTransport, StatusCode: http.StatusOK, Response: string(respBody), Err: err}
+	}
+	if adifValue != "" {
+		m.Set("ADIF", adifValue)
+	}
+
+	// REPLACE is an insert that overwrote a qso already in the logbook
+	result := m.Get("RESULT")
+	if result != "OK" && result != "REPLACE" && !(params.Get("ACTION") == "FETCH" && m.Get("COUNT") == "0") {
+		err := fmt.Errorf("QRZ.com %s failed: %s %s", params.Get("ACTION"), result, m.Get("REASON"))
+		log.Printf("%+v", err)
+		log.Printf("Body: %s", string(respBody))
+		return nil, &ServiceError{Class: qrzFailClass(result, m.Get("REASON")), StatusCode: http.StatusOK, Response: string(respBody), Err: err}
+	}
+
+	return m, nil
+}
+
+// Insert uploads the qso, replacing it if it's already there
+// returns the logid QRZ.com gave it, zero if it didn't, and the response
+func (c *QRZClient) Insert(q qso.QSO) (int64, string, error) {
+	s, err := adif.QSOToADIFRecord(q)
+	if err != nil {
+		log.Printf("%+v", err)
+		return 0, "", err
+	}
+
+	m, err := c.Request(url.Values{
+		"ACTION": {"INSERT"},
+		"OPTION": {"REPLACE"},
+		"ADIF":   {s},
+	})
+	if err != nil {
+		log.Printf("%+v", err)
+		return 0, "", err
+	}
+
+	logID, _ := strconv.ParseInt(m.Get("LOGID"), 10, 64)
+
+	return logID, m.Encode(), nil
+}
+
+// Delete removes the qso with logID from the logbook
+func (c *QRZClient) Delete(logID int64) error {
+	_, err := c.Request(url.Values{
+		"ACTION": {"DELETE"},
+		"LOGIDS": {strconv.FormatInt(logID, 10)},
+	})
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
 	return nil
 }
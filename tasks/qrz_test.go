@@ -0,0 +1,157 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/bbathe/golog/models/qso"
+)
+
+// qrzServer returns a client for a QRZ.com api that answers every request with body
+// the form values of the last request are put in got
+func qrzServer(t *testing.T, body string, got *url.Values) *QRZClient {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api" {
+			t.Errorf("request to %s, want /api", r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm: %v", err)
+		}
+		if got != nil {
+			*got = r.PostForm
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	return &QRZClient{
+		Client: Client{BaseURL: srv.URL},
+		APIKey: "ABCD-1234",
+	}
+}
+
+func TestQRZInsert(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantLogID int64
+		wantErr   bool
+		wantClass qso.UploadErrorClass
+	}{
+		{name: "ok", body: "RESULT=OK&LOGID=130877825&COUNT=1", wantLogID: 130877825},
+		{name: "replace", body: "RESULT=REPLACE&LOGID=130877825&COUNT=1", wantLogID: 130877825},
+		{name: "replace without logid", body: "RESULT=REPLACE&COUNT=1"},
+		{name: "auth", body: "RESULT=AUTH&REASON=invalid api key ABCD-1234", wantErr: true, wantClass: qso.ErrorService},
+		{name: "auth without reason", body: "RESULT=AUTH", wantErr: true, wantClass: qso.ErrorService},
+		{name: "fail key", body: "RESULT=FAIL&REASON=wrong station_callsign for this logbook: api key", wantErr: true, wantClass: qso.ErrorService},
+		{name: "fail qso", body: "RESULT=FAIL&REASON=Unable to add QSO to database: duplicate", wantErr: true, wantClass: qso.ErrorRejected},
+		{name: "fail without reason", body: "RESULT=FAIL", wantErr: true, wantClass: qso.ErrorRejected},
+		{name: "unparsable", body: "RESULT=OK;%zz", wantErr: true, wantClass: qso.ErrorTransport},
+	}
+
+	q := qso.QSO{
+		StationCallsign: "K0ABC",
+		Call:            "W1AW",
+		Band:            "20m",
+		Mode:            "CW",
+		Date:            "2024-03-01",
+		Time:            "12:34:56",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var form url.Values
+			c := qrzServer(t, tt.body, &form)
+
+			logID, _, err := c.Insert(q)
+			if tt.wantErr {
+				var se *ServiceError
+				if !errors.As(err, &se) {
+					t.Fatalf("Insert() error = %v, want *ServiceError", err)
+				}
+				if se.Class != tt.wantClass {
+					t.Errorf("Insert() error class = %v, want %v", se.Class, tt.wantClass)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+			if logID != tt.wantLogID {
+				t.Errorf("Insert() logID = %d, want %d", logID, tt.wantLogID)
+			}
+
+			if form.Get("KEY") != "ABCD-1234" || form.Get("ACTION") != "INSERT" || form.Get("OPTION") != "REPLACE" {
+				t.Errorf("Insert() sent %v", form)
+			}
+		})
+	}
+}
+
+func TestQRZFetch(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantADIF string
+		wantErr  bool
+	}{
+		{name: "records", body: "RESULT=OK&COUNT=1&ADIF=<call:4>W1AW&lt;eor&gt;", wantADIF: "<call:4>W1AW<eor>"},
+		{name: "adif with separators", body: "RESULT=OK&COUNT=1&ADIF=<comment:5>a&b=c<eor>", wantADIF: "<comment:5>a&b=c<eor>"},
+		{name: "none", body: "RESULT=FAIL&COUNT=0"},
+		{name: "fail", body: "RESULT=FAIL&REASON=invalid option", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qrzServer(t, tt.body, nil)
+
+			m, err := c.Request(url.Values{"ACTION": {"FETCH"}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Request() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := m.Get("ADIF"); got != tt.wantADIF {
+				t.Errorf("Request() ADIF = %q, want %q", got, tt.wantADIF)
+			}
+		})
+	}
+}
+
+func TestQRZHTTPError(t *testing.T) {
+	tests := []struct {
+		status    int
+		wantClass qso.UploadErrorClass
+	}{
+		{status: http.StatusServiceUnavailable, wantClass: qso.ErrorTransport},
+		{status: http.StatusTooManyRequests, wantClass: qso.ErrorTransport},
+		{status: http.StatusForbidden, wantClass: qso.ErrorService},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			c := &QRZClient{Client: Client{BaseURL: srv.URL}, APIKey: "ABCD-1234"}
+			_, err := c.Request(url.Values{"ACTION": {"STATUS"}})
+
+			var se *ServiceError
+			if !errors.As(err, &se) {
+				t.Fatalf("Request() error = %v, want *ServiceError", err)
+			}
+			if se.Class != tt.wantClass || se.StatusCode != tt.status {
+				t.Errorf("Request() error = %v %d, want %v %d", se.Class, se.StatusCode, tt.wantClass, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbathe/golog/adif"
+	"github.com/bbathe/golog/models/qso"
+)
+
+var (
+	muxQrzSync  sync.Mutex
+	lastQrzSync time.Time
+)
+
+const (
+	// how often the recurring task syncs
+	qrzSyncInterval = 1 * time.Hour
+
+	// how many records are fetched per request
+	qrzFetchPageSize = 250
+
+	// times on QRZ.com are what we uploaded, unless the other station's log was used to add the qso
+	qrzMatchWindow = 15 * time.Minute
+)
+
+// QRZSyncResult is the outcome of comparing our log with the QRZ.com logbook
+type QRZSyncResult struct {
+	Callsign  string `json:"callsign"`
+	Fetched   int    `json:"fetched"`
+	Confirmed int    `json:"confirmed"`
+	Imported  int    `json:"imported"`
+
+	// on QRZ.com but not in our log
+	MissingLocal []qso.QSO `json:"missingLocal"`
+
+	// in our log but not on QRZ.com, not including those waiting to be uploaded
+	MissingRemote []qso.QSO `json:"missingRemote"`
+}
+
+func (r QRZSyncResult) String() string {
+	return fmt.Sprintf("%d QSOs on QRZ.com for %s, %d newly confirmed, %d missing locally, %d missing on QRZ.com, %d imported",
+		r.Fetched, r.Callsign, r.Confirmed, len(r.MissingLocal), len(r.MissingRemote), r.Imported)
+}
+
+// QRZSync marks QSOs confirmed on QRZ.com as confirmed, runs at most every qrzSyncInterval
+func QRZSync() error {
+	muxQrzSync.Lock()
+	if time.Since(lastQrzSync) < qrzSyncInterval {
+		muxQrzSync.Unlock()
+		return nil
+	}
+
+	// failed attempts count too so QRZ.com isn't asked again until the next interval
+	lastQrzSync = time.Now()
+	muxQrzSync.Unlock()
+
+	result, err := SyncQRZ(store, false)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+	log.Printf("QRZ.com sync: %s", result)
+
+	return nil
+}
+
+// SyncQRZ downloads the QRZ.com logbook, marks matching QSOs as confirmed
+// and reports QSOs missing from either log, optionally importing the ones missing locally
+func SyncQRZ(s qso.ServiceStore, importMissing bool) (QRZSyncResult, error) {
+	muxQrzSync.Lock()
+	defer muxQrzSync.Unlock()
+
+	// don't report qsos as missing while they're being uploaded
+	muxQrzUpload.Lock()
+	defer muxQrzUpload.Unlock()
+
+	var result QRZSyncResult
+	c := NewQRZClient()
+
+	status, err := c.Request(url.Values{"ACTION": {"STATUS"}})
+	if err != nil {
+		log.Printf("%+v", err)
+		return result, err
+	}
+	result.Callsign = strings.ToUpper(status.Get("CALLSIGN"))
+
+	records, err := fetchQRZLogbook(c)
+	if err != nil {
+		log.Printf("%+v", err)
+		return result, err
+	}
+	result.Fetched = len(records)
+
+	matched, err := matchQRZRecords(s, records, &result)
+	if err != nil {
+		log.Printf("%+v", err)
+		return result, err
+	}
+
+	result.MissingRemote, err = missingFromQRZ(s, result.Callsign, matched)
+	if err != nil {
+		log.Printf("%+v", err)
+		return result, err
+	}
+
+	if importMissing && len(result.MissingLocal) > 0 {
+		result.Imported, err = ImportQRZQSOs(s, result.MissingLocal)
+		if err != nil {
+			log.Printf("%+v", err)
+			return result, err
+		}
+	}
+
+	lastQrzSync = time.Now()
+
+	return result, nil
+}
+
+// ImportQRZQSOs adds QSOs that came from the QRZ.com logbook
+// they're already on QRZ.com so they're marked sent there with their logid, the other services get them like any new QSO
+func ImportQRZQSOs(s qso.QSOStore, qsos []qso.QSO) (int, error) {
+	loadedAt := time.Now().Unix()
+
+	qs := make([]qso.QSO, 0, len(qsos))
+	for _, q := range qsos {
+		q.LoadedAt = loadedAt
+		q.QSLLotw = qso.NotSent
+		q.QSLQrz = qso.Sent
+		q.QSLClublog = qso.NotSent
+		q.QSLEqsl = qso.NotSent
+
+		if q.Validate(false) != nil {
+			continue
+		}
+		qs = append(qs, q)
+	}
+
+	n, err := s.BulkAdd(qso.WithSource(context.Background(), qso.SourceSync), qs)
+	if err != nil {
+		log.Printf("%+v", err)
+		return n, err
+	}
+
+	return n, nil
+}
+
+// fetchQRZLogbook returns all the records in the QRZ.com logbook
+func fetchQRZLogbook(c *QRZClient) ([]adif.Record, error) {
+	var records []adif.Record
+
+	afterLogID := 0
+	for {
+		m, err := c.Request(url.Values{
+			"ACTION": {"FETCH"},
+			"OPTION": {fmt.Sprintf("MAX:%d,AFTERLOGID:%d", qrzFetchPageSize, afterLogID)},
+		})
+		if err != nil {
+			log.Printf("%+v", err)
+			return nil, err
+		}
+
+		count, _ := strconv.Atoi(m.Get("COUNT"))
+		if count == 0 {
+			return records, nil
+		}
+
+		rr := adif.NewReader(strings.NewReader(m.Get("ADIF")))
+		for {
+			r, err := rr.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				// reader skips malformed records
+				var pe *adif.ParseError
+				if errors.As(err, &pe) {
+					log.Printf("%+v", err)
+					continue
+				}
+				log.Printf("%+v", err)
+				return nil, err
+			}
+			records = append(records, r)
+
+			// next page starts after the highest logid we've seen
+			if v, _ := r.Get("APP_QRZLOG_LOGID"); v != "" {
+				id, err := strconv.Atoi(v)
+				if err == nil && id >= afterLogID {
+					afterLogID = id + 1
+				}
+			}
+		}
+
+		if count < qrzFetchPageSize {
+			return records, nil
+		}
+
+		// pause between requests
+		time.Sleep(1 * time.Second)
+	}
+}
+
+// matchQRZRecords finds our QSO for each record, confirming those QRZ.com has as confirmed
+// records without a match are added to result.MissingLocal, returns the ids of the QSOs that matched
+func matchQRZRecords(s qso.ServiceStore, records []adif.Record, result *QRZSyncResult) (map[int64]bool, error) {
+	matched := map[int64]bool{}
+	var confirmed []qso.QSO
+
+	for _, r := range records {
+		c, err := adif.QSOFromRecord(r)
+		if err != nil {
+			log.Printf("%+v", err)
+			continue
+		}
+		if c.StationCallsign == "" {
+			c.StationCallsign = result.Callsign
+		}
+
+		v, _ := r.Get("APP_QRZLOG_LOGID")
+		logID, _ := strconv.ParseInt(v, 10, 64)
+		c.QRZLogID = logID
+
+		isConfirmed := false
+		if v, _ := r.Get("APP_QRZLOG_STATUS"); strings.EqualFold(v, "C") {
+			isConfirmed = true
+			c.QSLQrzRcvd = qso.Received
+		}
+
+		q, err := matchQRZRecord(s, *c, matched)
+		if err != nil {
+			log.Printf("%+v", err)
+			return nil, err
+		}
+		if q == nil {
+			result.MissingLocal = append(result.MissingLocal, *c)
+			continue
+		}
+
+		matched[q.ID] = true
+
+		// logid lets edits and deletes be corrected on QRZ.com
+		if q.QRZLogID == 0 && logID != 0 {
+			err = s.UpdateQRZLogID(context.Background(), q.ID, logID)
+			if err != nil {
+				log.Printf("%+v", err)
+				return nil, err
+			}
+		}
+
+		if isConfirmed && !q.QSLQrzRcvd.Confirmed() {
+			confirmed = append(confirmed, *q)
+		}
+	}
+
+	if len(confirmed) > 0 {
+		err := s.UpdateQSLReceived(qso.WithSource(context.Background(), qso.SourceSync), confirmed, qso.QSLQrz, qso.Received, "")
+		if err != nil {
+			log.Printf("%+v", err)
+			return nil, err
+		}
+		result.Confirmed = len(confirmed)
+	}
+
+	return matched, nil
+}
+
+// matchQRZRecord returns the closest QSO in the same mode that hasn't already been matched, nil if there isn't one
+func matchQRZRecord(s qso.QSOStore, c qso.QSO, matched map[int64]bool) (*qso.QSO, error) {
+	at, err := time.Parse("2006-01-02 15:04:05", c.Date+" "+c.Time)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, nil
+	}
+
+	qsos, err := s.Near(context.Background(), c.Call, c.Band, at, qrzMatchWindow)
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	for i := range qsos {
+		if qsos[i].Mode == c.Mode && !matched[qsos[i].ID] {
+			return &qsos[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// missingFromQRZ returns our QSOs for callsign that didn't match a record on QRZ.com
+// QSOs still waiting to be uploaded aren't missing
+func missingFromQRZ(s qso.QSOStore, callsign string, matched map[int64]bool) ([]qso.QSO, error) {
+	qsos, err := s.All(context.Background())
+	if err != nil {
+		log.Printf("%+v", err)
+		return nil, err
+	}
+
+	var missing []qso.QSO
+	for _, q := range qsos {
+		if !strings.EqualFold(q.StationCallsign, callsign) || q.QSLQrz == qso.NotSent || matched[q.ID] {
+			continue
+		}
+		missing = append(missing, q)
+	}
+
+	return missing, nil
+}
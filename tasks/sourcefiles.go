@@ -1,14 +1,16 @@
 package tasks
 
 import (
+	"context"
+	"errors"
 	"io"
 	"log"
 	"os"
-	"strings"
 	"sync"
 
 	"github.com/bbathe/golog/adif"
 	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/models/qso"
 )
 
 var muxSourceFiles sync.Mutex
@@ -46,52 +48,48 @@ func SourceFiles() error {
 
 		// be sure to process only whole adif records
 		var nprocessed int64
-		var record string
-		var lowerRecord string
-		c := []byte{0}
+		r := adif.NewReader(f)
 		for {
-			_, err := f.Read(c)
+			record, err := r.Read()
 			if err != nil {
-				if err == io.EOF {
+				if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+					// rest of the record hasn't been written yet
 					break
-				} else {
-					log.Printf("%+v", err)
-					return err
 				}
-			} else {
-				// accumulate characters to test for whole record
-				record += string(c)
-				lowerRecord += strings.ToLower(string(c))
-
-				// got to the end of a record?
-				if strings.HasSuffix(lowerRecord, "<eor>") {
-					// parse record to QSO
-					qso, err := adif.QSOFromADIFRecord(record)
-					if err != nil {
-						log.Printf("%+v", err)
-						return err
-					}
-
-					// if not set, assume current station callsign
-					if qso.StationCallsign == "" {
-						qso.StationCallsign = config.Station.Callsign
-					}
-
-					// persist to database
-					err = qso.Add()
-					if err != nil {
-						log.Printf("%+v", err)
-						return err
-					}
-
-					// accumlate bytes processed
-					nprocessed += int64(len(record))
-
-					// reset record
-					record = ""
-					lowerRecord = ""
+
+				var pe *adif.ParseError
+				if errors.As(err, &pe) {
+					// skip over the malformed record
+					log.Printf("%+v", err)
+					nprocessed = r.Offset()
+					continue
 				}
+
+				log.Printf("%+v", err)
+				return err
+			}
+
+			// parse record to QSO
+			q, err := adif.QSOFromRecord(record)
+			if err != nil {
+				log.Printf("%+v", err)
+				return err
 			}
+
+			// if not set, assume current station callsign
+			if q.StationCallsign == "" {
+				q.StationCallsign = config.Station.Callsign
+			}
+
+			// persist to database
+			err = store.Add(qso.WithSource(context.Background(), qso.SourceSourceFile), q)
+			if err != nil {
+				log.Printf("%+v", err)
+				return err
+			}
+
+			// accumlate bytes processed
+			nprocessed = r.Offset()
 		}
 
 		// anything processed?
@@ -7,6 +7,10 @@ const (
 	TaskQSLLoTW
 	TaskQSLQRZ
 	TaskQSLClubLog
+	TaskQSLEqsl
+	TaskLoTWConfirmations
+	TaskQRZSync
+	TaskEqslInbox
 	TaskHamAlert
 
 	TaskLast // so we can get the number of tasks defined
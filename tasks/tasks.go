@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/models/qso"
 	"github.com/bbathe/golog/util"
 )
 
@@ -13,13 +14,16 @@ var (
 	quitChannels      []chan bool
 	quitStartup       chan bool
 
+	// where the tasks find & change qsos
+	store qso.ServiceStore
+
 	mutexTaskStatuses sync.Mutex
 	statuses          [int(TaskLast)]GoLogTaskStatus
 	statusHandlers    []TaskStatusChangeEventHandler
 )
 
-// Start starts all background tasks
-func Start() {
+// Start starts all background tasks, using s for the qsos
+func Start(s qso.ServiceStore) {
 	mutexQuitChannels.Lock()
 	defer mutexQuitChannels.Unlock()
 
@@ -28,6 +32,8 @@ func Start() {
 		return
 	}
 
+	store = s
+
 	// our quit channel
 	quitStartup = make(chan bool)
 
@@ -47,10 +53,23 @@ func Start() {
 	}
 	if config.LogbookServices.QRZ.Validate() == nil {
 		tasksOneMinute = append(tasksOneMinute, taskWrapper(TaskQSLQRZ, QSLQrz))
+
+		// only syncs every qrzSyncInterval
+		tasksOneMinute = append(tasksOneMinute, taskWrapper(TaskQRZSync, QRZSync))
 	}
 	if config.LogbookServices.ClubLog.Validate() == nil {
 		tasksOneMinute = append(tasksOneMinute, taskWrapper(TaskQSLClubLog, QSLClublog))
 	}
+	if config.LogbookServices.LoTW.Validate() == nil {
+		// only downloads every lotwDownloadInterval
+		tasksOneMinute = append(tasksOneMinute, taskWrapper(TaskLoTWConfirmations, LoTWConfirmations))
+	}
+	if config.LogbookServices.Eqsl.Validate() == nil {
+		tasksOneMinute = append(tasksOneMinute, taskWrapper(TaskQSLEqsl, QSLEqsl))
+
+		// only downloads every eqslInboxInterval
+		tasksOneMinute = append(tasksOneMinute, taskWrapper(TaskEqslInbox, EqslInbox))
+	}
 
 	// create quit channels
 	quitChannels = make([]chan bool, 0, len(tasksOneMinute))
@@ -138,6 +157,9 @@ func Shutdown() {
 	if config.LogbookServices.QRZ.Validate() == nil {
 		tasks = append(tasks, QSLQrzFinal)
 	}
+	if config.LogbookServices.Eqsl.Validate() == nil {
+		tasks = append(tasks, QSLEqslFinal)
+	}
 
 	// spinup all the shutdown tasks
 	for _, t := range tasks {
@@ -1,7 +1,10 @@
 package ui
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/bbathe/golog/adif"
 	"github.com/bbathe/golog/models/qso"
@@ -10,6 +13,39 @@ import (
 	"github.com/lxn/walk/declarative"
 )
 
+// file dialog filter for the formats the adif package reads/writes
+const adifFileFilter = "ADIF Files (*.adi;*.adif;*.adx)|*.adi;*.adif;*.adx|ADX Files (*.adx)|*.adx|All Files (*.*)|*.*"
+
+// most rejected records listed in the import summary, the rest are in the log
+const maxSummaryRejects = 10
+
+// importSummary returns the import result for displaying to the user
+func importSummary(result adif.ImportResult) string {
+	var sb strings.Builder
+
+	sb.WriteString(result.String())
+
+	n := 0
+	for _, r := range result.Records {
+		if !r.Rejected {
+			continue
+		}
+		if n == maxSummaryRejects {
+			sb.WriteString("\n\u2026")
+			break
+		}
+		n++
+
+		where := fmt.Sprintf("offset %d", r.Offset)
+		if r.Line > 0 {
+			where = fmt.Sprintf("line %d", r.Line)
+		}
+		fmt.Fprintf(&sb, "\n%s: %s", where, r.Problems[0])
+	}
+
+	return sb.String()
+}
+
 // importADIF drives the user thru importing QSOs from an ADIF file
 func importADIF(parent walk.Form) error {
 	var adifDlg *walk.Dialog
@@ -17,6 +53,7 @@ func importADIF(parent walk.Form) error {
 	var leADIFFile *walk.LineEdit
 
 	var cbClublog *walk.CheckBox
+	var cbEqsl *walk.CheckBox
 	var cbLoTW *walk.CheckBox
 	var cbQRZ *walk.CheckBox
 
@@ -57,7 +94,7 @@ func importADIF(parent walk.Form) error {
 									PointSize: 9,
 								},
 								OnClicked: func() {
-									fname, err := OpenFilePicker(parent, "Select file to import", "ADIF Files (*.adi;*.adif)|*.adi;*.adif|All Files (*.*)|*.*")
+									fname, err := OpenFilePicker(parent, "Select file to import", adifFileFilter)
 									if err != nil {
 										MsgError(adifDlg, err)
 										log.Printf("%+v", err)
@@ -97,6 +134,10 @@ func importADIF(parent walk.Form) error {
 								Text:     "Club Log",
 								AssignTo: &cbClublog,
 							},
+							declarative.CheckBox{
+								Text:     "eQSL",
+								AssignTo: &cbEqsl,
+							},
 						},
 					},
 				},
@@ -125,19 +166,19 @@ func importADIF(parent walk.Form) error {
 									qslclublog = qso.NotSent
 								}
 
-								qs, err := adif.ReadFromFile(fname, qsllotw, qslqrz, qslclublog)
-								if err != nil {
-									MsgError(nil, err)
-									log.Printf("%+v", err)
-									return
+								qsleqsl := qso.Sent
+								if cbEqsl.Checked() {
+									qsleqsl = qso.NotSent
 								}
 
-								err = qso.BulkAdd(qs)
+								result, err := adif.ImportFromFile(store, fname, qsllotw, qslqrz, qslclublog, qsleqsl)
 								if err != nil {
 									MsgError(nil, err)
 									log.Printf("%+v", err)
 									return
 								}
+
+								MsgInformation(adifDlg, importSummary(result))
 							}
 
 							adifDlg.Accept()
@@ -167,7 +208,7 @@ func importADIF(parent walk.Form) error {
 
 // exportADIF drives the user thru exporting QSOs to an ADIF file
 func exportADIF(parent walk.Form) {
-	fname, err := SaveFilePicker(parent, "Select file to export QSOs", "ADIF Files (*.adi;*.adif)|*.adi;*.adif|All Files (*.*)|*.*")
+	fname, err := SaveFilePicker(parent, "Select file to export QSOs", adifFileFilter)
 	if err != nil {
 		MsgError(nil, err)
 		log.Printf("%+v", err)
@@ -175,14 +216,14 @@ func exportADIF(parent walk.Form) {
 	}
 
 	if fname != nil {
-		qs, err := qso.All()
+		qs, err := store.All(context.Background())
 		if err != nil {
 			MsgError(nil, err)
 			log.Printf("%+v", err)
 			return
 		}
 
-		err = adif.WriteToFile(qs, *fname)
+		err = adif.WriteToFile(store, qs, *fname, adif.WriteOptions{})
 		if err != nil {
 			MsgError(nil, err)
 			log.Printf("%+v", err)
@@ -0,0 +1,338 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bbathe/golog/cabrillo"
+	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/models/qso"
+	"github.com/lxn/walk"
+
+	"github.com/lxn/walk/declarative"
+)
+
+// file dialog filters for contest definitions and cabrillo logs
+const (
+	contestFileFilter  = "Contest Definitions (*.yaml;*.yml)|*.yaml;*.yml|All Files (*.*)|*.*"
+	cabrilloFileFilter = "Cabrillo Files (*.log;*.cbr)|*.log;*.cbr|All Files (*.*)|*.*"
+)
+
+// exportCabrillo drives the user thru exporting contest QSOs to a Cabrillo file
+func exportCabrillo(parent walk.Form) error {
+	var cabrilloDlg *walk.Dialog
+
+	var leContestFile *walk.LineEdit
+	var deFrom *walk.DateEdit
+	var deTo *walk.DateEdit
+
+	// default to the last 48 hours, dates are in utc
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 0, 0, time.Local)
+	from := to.Add(-48 * time.Hour)
+
+	err := declarative.Dialog{
+		AssignTo:  &cabrilloDlg,
+		Title:     appName + " Export Cabrillo",
+		Icon:      appIcon,
+		FixedSize: true,
+		MinSize:   declarative.Size{Width: 600},
+		Font: declarative.Font{
+			Family:    "MS Shell Dlg 2",
+			PointSize: 10,
+		},
+		Layout: declarative.VBox{Alignment: declarative.AlignHNearVNear},
+		Children: []declarative.Widget{
+			filePickerRow(parent, &cabrilloDlg, &leContestFile, "Contest Definition", "Select contest definition", contestFileFilter),
+			declarative.Composite{
+				Layout: declarative.Grid{Columns: 2},
+				Children: []declarative.Widget{
+					declarative.Label{
+						Text: "From (UTC)",
+					},
+					declarative.Label{
+						Text: "To (UTC)",
+					},
+					declarative.DateEdit{
+						AssignTo: &deFrom,
+						Format:   "yyyy-MM-dd HH:mm",
+						Date:     from,
+					},
+					declarative.DateEdit{
+						AssignTo: &deTo,
+						Format:   "yyyy-MM-dd HH:mm",
+						Date:     to,
+					},
+				},
+			},
+			declarative.Composite{
+				Layout: declarative.HBox{},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						Text: "OK",
+						OnClicked: func() {
+							if leContestFile.Text() == "" {
+								MsgError(cabrilloDlg, fmt.Errorf("select a contest definition"))
+								return
+							}
+
+							c, err := cabrillo.ReadContestFromFile(leContestFile.Text())
+							if err != nil {
+								MsgError(cabrilloDlg, err)
+								log.Printf("%+v", err)
+								return
+							}
+
+							fname, err := SaveFilePicker(parent, "Select file to export contest QSOs", cabrilloFileFilter)
+							if err != nil {
+								MsgError(cabrilloDlg, err)
+								log.Printf("%+v", err)
+								return
+							}
+							if fname == nil {
+								return
+							}
+
+							// the edits show utc, so just relabel what they hold
+							f := deFrom.Date()
+							t := deTo.Date()
+							from := time.Date(f.Year(), f.Month(), f.Day(), f.Hour(), f.Minute(), 0, 0, time.UTC)
+							to := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 59, 0, time.UTC)
+
+							err = cabrillo.WriteToFile(store, c, from, to, *fname)
+							if err != nil {
+								MsgError(cabrilloDlg, err)
+								log.Printf("%+v", err)
+								return
+							}
+
+							cabrilloDlg.Accept()
+						},
+					},
+					declarative.PushButton{
+						Text: "Cancel",
+						OnClicked: func() {
+							cabrilloDlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}.Create(parent)
+	if err != nil {
+		MsgError(parent, err)
+		log.Printf("%+v", err)
+		return err
+	}
+
+	// start message loop
+	cabrilloDlg.Run()
+
+	return nil
+}
+
+// filePickerRow returns the widgets for a labeled read-only file name with a button to pick the file
+func filePickerRow(parent walk.Form, dlg **walk.Dialog, le **walk.LineEdit, label, title, filter string) declarative.Composite {
+	return declarative.Composite{
+		Layout: declarative.VBox{},
+		Children: []declarative.Widget{
+			declarative.Label{
+				Text: label,
+			},
+			declarative.Composite{
+				Layout: declarative.HBox{MarginsZero: true},
+				Children: []declarative.Widget{
+					declarative.LineEdit{
+						AssignTo: le,
+						ReadOnly: true,
+					},
+					declarative.PushButton{
+						Text:    "\u2026",
+						MaxSize: declarative.Size{Width: 30},
+						MinSize: declarative.Size{Width: 30},
+						Font: declarative.Font{
+							Family:    "MS Shell Dlg 2",
+							PointSize: 9,
+						},
+						OnClicked: func() {
+							fname, err := OpenFilePicker(parent, title, filter)
+							if err != nil {
+								MsgError(*dlg, err)
+								log.Printf("%+v", err)
+								return
+							}
+
+							if fname != nil {
+								err = (*le).SetText(*fname)
+								if err != nil {
+									MsgError(*dlg, err)
+									log.Printf("%+v", err)
+									return
+								}
+							}
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// importCabrillo drives the user thru importing QSOs from a Cabrillo file
+func importCabrillo(parent walk.Form) error {
+	var cabrilloDlg *walk.Dialog
+
+	var leCabrilloFile *walk.LineEdit
+	var leContestFile *walk.LineEdit
+	var cbDigitalMode *walk.ComboBox
+
+	var cbClublog *walk.CheckBox
+	var cbEqsl *walk.CheckBox
+	var cbLoTW *walk.CheckBox
+	var cbQRZ *walk.CheckBox
+
+	modes := config.ListModeNames()
+
+	err := declarative.Dialog{
+		AssignTo:  &cabrilloDlg,
+		Title:     appName + " Import Cabrillo",
+		Icon:      appIcon,
+		FixedSize: true,
+		MinSize:   declarative.Size{Width: 600},
+		Font: declarative.Font{
+			Family:    "MS Shell Dlg 2",
+			PointSize: 10,
+		},
+		Layout: declarative.VBox{Alignment: declarative.AlignHNearVNear},
+		Children: []declarative.Widget{
+			filePickerRow(parent, &cabrilloDlg, &leCabrilloFile, "Cabrillo File", "Select file to import", cabrilloFileFilter),
+			filePickerRow(parent, &cabrilloDlg, &leContestFile, "Contest Definition (optional)", "Select contest definition", contestFileFilter),
+			declarative.Composite{
+				Layout: declarative.HBox{},
+				Children: []declarative.Widget{
+					declarative.Label{
+						Text: "Mode for DG QSOs",
+					},
+					declarative.ComboBox{
+						AssignTo: &cbDigitalMode,
+						Model:    modes,
+						Editable: false,
+					},
+					declarative.HSpacer{},
+				},
+			},
+			declarative.Composite{
+				Layout: declarative.HBox{},
+				Children: []declarative.Widget{
+					declarative.RadioButtonGroupBox{
+						Title:  "QSL to Logbook Services",
+						Layout: declarative.HBox{},
+						Children: []declarative.Widget{
+							declarative.CheckBox{
+								Text:     "LoTW",
+								AssignTo: &cbLoTW,
+							},
+							declarative.CheckBox{
+								Text:     "QRZ",
+								AssignTo: &cbQRZ,
+							},
+							declarative.CheckBox{
+								Text:     "Club Log",
+								AssignTo: &cbClublog,
+							},
+							declarative.CheckBox{
+								Text:     "eQSL",
+								AssignTo: &cbEqsl,
+							},
+						},
+					},
+				},
+			},
+			declarative.Composite{
+				Layout: declarative.HBox{},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						Text: "OK",
+						OnClicked: func() {
+							fname := leCabrilloFile.Text()
+							if fname == "" {
+								cabrilloDlg.Accept()
+								return
+							}
+
+							// exchange layout is optional
+							var c *cabrillo.Contest
+							if leContestFile.Text() != "" {
+								contest, err := cabrillo.ReadContestFromFile(leContestFile.Text())
+								if err != nil {
+									MsgError(cabrilloDlg, err)
+									log.Printf("%+v", err)
+									return
+								}
+								c = &contest
+							}
+
+							var digitalMode string
+							if idx := cbDigitalMode.CurrentIndex(); idx >= 0 {
+								digitalMode = modes[idx]
+							}
+
+							qsllotw := qso.Sent
+							if cbLoTW.Checked() {
+								qsllotw = qso.NotSent
+							}
+
+							qslqrz := qso.Sent
+							if cbQRZ.Checked() {
+								qslqrz = qso.NotSent
+							}
+
+							qslclublog := qso.Sent
+							if cbClublog.Checked() {
+								qslclublog = qso.NotSent
+							}
+
+							qsleqsl := qso.Sent
+							if cbEqsl.Checked() {
+								qsleqsl = qso.NotSent
+							}
+
+							lineErrors, err := cabrillo.ImportFromFile(store, fname, c, digitalMode, qsllotw, qslqrz, qslclublog, qsleqsl)
+							if err != nil {
+								MsgError(nil, err)
+								log.Printf("%+v", err)
+								return
+							}
+
+							// let the user know what was skipped, details are in the log
+							if len(lineErrors) > 0 {
+								MsgInformation(cabrilloDlg, fmt.Sprintf("%d QSO lines could not be imported, first was %v", len(lineErrors), lineErrors[0]))
+							}
+
+							cabrilloDlg.Accept()
+						},
+					},
+					declarative.PushButton{
+						Text: "Cancel",
+						OnClicked: func() {
+							cabrilloDlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}.Create(parent)
+	if err != nil {
+		MsgError(parent, err)
+		log.Printf("%+v", err)
+		return err
+	}
+
+	// start message loop
+	cabrilloDlg.Run()
+
+	return nil
+}
@@ -199,6 +199,9 @@ func tabConfigGeneral() declarative.TabPage {
 	var neQSOLimit *walk.NumberEdit
 	var leWorkingDirectory *walk.LineEdit
 	var leBackupDirectory *walk.LineEdit
+	var cbBackupFormat *walk.ComboBox
+
+	backupFormats := []string{"adif", "adx"}
 
 	return declarative.TabPage{
 		Title:  "General",
@@ -421,6 +424,31 @@ func tabConfigGeneral() declarative.TabPage {
 					},
 				},
 			},
+			declarative.Composite{
+				Layout: declarative.VBox{},
+				DataBinder: declarative.DataBinder{
+					DataSource:     &newConfig,
+					ErrorPresenter: declarative.ToolTipErrorPresenter{},
+				},
+				Children: []declarative.Widget{
+					declarative.Label{
+						Text:        "Backup Format",
+						ToolTipText: "the file format QSOs are backed up in",
+					},
+					declarative.ComboBox{
+						AssignTo: &cbBackupFormat,
+						Value:    declarative.Bind("BackupFormat"),
+						Model:    backupFormats,
+						Editable: false,
+						OnCurrentIndexChanged: func() {
+							idx := cbBackupFormat.CurrentIndex()
+							if idx >= 0 {
+								newConfig.BackupFormat = backupFormats[idx]
+							}
+						},
+					},
+				},
+			},
 			declarative.HSpacer{},
 		},
 	}
@@ -563,8 +591,15 @@ func tabConfigLogbookServices() declarative.TabPage {
 	var leTQSLExeLocation *walk.LineEdit
 	var leTQSLStationLocationName *walk.LineEdit
 
+	var leLoTWUsername *walk.LineEdit
+	var leLoTWPassword *walk.LineEdit
+
 	var leQRZAPIKey *walk.LineEdit
 
+	var leEqslUsername *walk.LineEdit
+	var leEqslPassword *walk.LineEdit
+	var leEqslQTHNickname *walk.LineEdit
+
 	return declarative.TabPage{
 		Title:  "Logbook Services",
 		Layout: declarative.VBox{Alignment: declarative.AlignHNearVNear},
@@ -667,6 +702,46 @@ func tabConfigLogbookServices() declarative.TabPage {
 					},
 				},
 			},
+			declarative.RadioButtonGroupBox{
+				Title:  "LoTW Confirmations",
+				Layout: declarative.HBox{MarginsZero: true},
+				DataBinder: declarative.DataBinder{
+					DataSource:     &newConfig.LogbookServices.LoTW,
+					ErrorPresenter: declarative.ToolTipErrorPresenter{},
+				},
+				Children: []declarative.Widget{
+					declarative.Composite{
+						Layout: declarative.VBox{},
+						Children: []declarative.Widget{
+							declarative.Label{
+								Text: "Username",
+							},
+							declarative.LineEdit{
+								AssignTo: &leLoTWUsername,
+								Text:     declarative.Bind("Username"),
+								OnTextChanged: func() {
+									newConfig.LogbookServices.LoTW.Username = leLoTWUsername.Text()
+								},
+							},
+						},
+					},
+					declarative.Composite{
+						Layout: declarative.VBox{},
+						Children: []declarative.Widget{
+							declarative.Label{
+								Text: "Password",
+							},
+							declarative.LineEdit{
+								AssignTo: &leLoTWPassword,
+								Text:     declarative.Bind("Password"),
+								OnTextChanged: func() {
+									newConfig.LogbookServices.LoTW.Password = leLoTWPassword.Text()
+								},
+							},
+						},
+					},
+				},
+			},
 			declarative.RadioButtonGroupBox{
 				Title:  "QRZ",
 				Layout: declarative.HBox{MarginsZero: true},
@@ -772,6 +847,61 @@ func tabConfigLogbookServices() declarative.TabPage {
 					},
 				},
 			},
+			declarative.RadioButtonGroupBox{
+				Title:  "eQSL",
+				Layout: declarative.HBox{MarginsZero: true},
+				DataBinder: declarative.DataBinder{
+					DataSource:     &newConfig.LogbookServices.Eqsl,
+					ErrorPresenter: declarative.ToolTipErrorPresenter{},
+				},
+				Children: []declarative.Widget{
+					declarative.Composite{
+						Layout: declarative.VBox{},
+						Children: []declarative.Widget{
+							declarative.Label{
+								Text: "Username",
+							},
+							declarative.LineEdit{
+								AssignTo: &leEqslUsername,
+								Text:     declarative.Bind("Username"),
+								OnTextChanged: func() {
+									newConfig.LogbookServices.Eqsl.Username = leEqslUsername.Text()
+								},
+							},
+						},
+					},
+					declarative.Composite{
+						Layout: declarative.VBox{},
+						Children: []declarative.Widget{
+							declarative.Label{
+								Text: "Password",
+							},
+							declarative.LineEdit{
+								AssignTo: &leEqslPassword,
+								Text:     declarative.Bind("Password"),
+								OnTextChanged: func() {
+									newConfig.LogbookServices.Eqsl.Password = leEqslPassword.Text()
+								},
+							},
+						},
+					},
+					declarative.Composite{
+						Layout: declarative.VBox{},
+						Children: []declarative.Widget{
+							declarative.Label{
+								Text: "QTH Nickname",
+							},
+							declarative.LineEdit{
+								AssignTo: &leEqslQTHNickname,
+								Text:     declarative.Bind("QTHNickname"),
+								OnTextChanged: func() {
+									newConfig.LogbookServices.Eqsl.QTHNickname = leEqslQTHNickname.Text()
+								},
+							},
+						},
+					},
+				},
+			},
 			declarative.HSpacer{},
 		},
 	}
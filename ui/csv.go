@@ -0,0 +1,225 @@
+package ui
+
+import (
+	"log"
+
+	"github.com/bbathe/golog/csvlog"
+	"github.com/bbathe/golog/models/qso"
+	"github.com/lxn/walk"
+
+	"github.com/lxn/walk/declarative"
+)
+
+// file dialog filters for csv files and their column mappings
+const (
+	csvFileFilter        = "CSV Files (*.csv)|*.csv|All Files (*.*)|*.*"
+	csvMappingFileFilter = "Column Mappings (*.yaml;*.yml)|*.yaml;*.yml|All Files (*.*)|*.*"
+)
+
+// readCSVMapping returns the mapping from file fname, or the default mapping if there isn't one
+func readCSVMapping(fname string) (csvlog.Mapping, error) {
+	if fname == "" {
+		return csvlog.DefaultMapping(), nil
+	}
+
+	return csvlog.ReadMappingFromFile(fname)
+}
+
+// importCSV drives the user thru importing QSOs from a CSV file
+func importCSV(parent walk.Form) error {
+	var csvDlg *walk.Dialog
+
+	var leCSVFile *walk.LineEdit
+	var leMappingFile *walk.LineEdit
+
+	var cbClublog *walk.CheckBox
+	var cbEqsl *walk.CheckBox
+	var cbLoTW *walk.CheckBox
+	var cbQRZ *walk.CheckBox
+
+	err := declarative.Dialog{
+		AssignTo:  &csvDlg,
+		Title:     appName + " Import CSV",
+		Icon:      appIcon,
+		FixedSize: true,
+		MinSize:   declarative.Size{Width: 600},
+		Font: declarative.Font{
+			Family:    "MS Shell Dlg 2",
+			PointSize: 10,
+		},
+		Layout: declarative.VBox{Alignment: declarative.AlignHNearVNear},
+		Children: []declarative.Widget{
+			filePickerRow(parent, &csvDlg, &leCSVFile, "CSV File", "Select file to import", csvFileFilter),
+			filePickerRow(parent, &csvDlg, &leMappingFile, "Column Mapping (optional)", "Select column mapping", csvMappingFileFilter),
+			declarative.Composite{
+				Layout: declarative.HBox{},
+				Children: []declarative.Widget{
+					declarative.RadioButtonGroupBox{
+						Title:  "QSL to Logbook Services",
+						Layout: declarative.HBox{},
+						Children: []declarative.Widget{
+							declarative.CheckBox{
+								Text:     "LoTW",
+								AssignTo: &cbLoTW,
+							},
+							declarative.CheckBox{
+								Text:     "QRZ",
+								AssignTo: &cbQRZ,
+							},
+							declarative.CheckBox{
+								Text:     "Club Log",
+								AssignTo: &cbClublog,
+							},
+							declarative.CheckBox{
+								Text:     "eQSL",
+								AssignTo: &cbEqsl,
+							},
+						},
+					},
+				},
+			},
+			declarative.Composite{
+				Layout: declarative.HBox{},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						Text: "OK",
+						OnClicked: func() {
+							fname := leCSVFile.Text()
+							if fname != "" {
+								m, err := readCSVMapping(leMappingFile.Text())
+								if err != nil {
+									MsgError(csvDlg, err)
+									log.Printf("%+v", err)
+									return
+								}
+
+								qsllotw := qso.Sent
+								if cbLoTW.Checked() {
+									qsllotw = qso.NotSent
+								}
+
+								qslqrz := qso.Sent
+								if cbQRZ.Checked() {
+									qslqrz = qso.NotSent
+								}
+
+								qslclublog := qso.Sent
+								if cbClublog.Checked() {
+									qslclublog = qso.NotSent
+								}
+
+								qsleqsl := qso.Sent
+								if cbEqsl.Checked() {
+									qsleqsl = qso.NotSent
+								}
+
+								result, err := csvlog.ImportFromFile(store, fname, m, qsllotw, qslqrz, qslclublog, qsleqsl)
+								if err != nil {
+									MsgError(nil, err)
+									log.Printf("%+v", err)
+									return
+								}
+
+								MsgInformation(csvDlg, importSummary(result))
+							}
+
+							csvDlg.Accept()
+						},
+					},
+					declarative.PushButton{
+						Text: "Cancel",
+						OnClicked: func() {
+							csvDlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}.Create(parent)
+	if err != nil {
+		MsgError(parent, err)
+		log.Printf("%+v", err)
+		return err
+	}
+
+	// start message loop
+	csvDlg.Run()
+
+	return nil
+}
+
+// exportCSV drives the user thru exporting qsos to a CSV file
+func exportCSV(parent walk.Form, qsos []qso.QSO) error {
+	var csvDlg *walk.Dialog
+
+	var leMappingFile *walk.LineEdit
+
+	err := declarative.Dialog{
+		AssignTo:  &csvDlg,
+		Title:     appName + " Export CSV",
+		Icon:      appIcon,
+		FixedSize: true,
+		MinSize:   declarative.Size{Width: 600},
+		Font: declarative.Font{
+			Family:    "MS Shell Dlg 2",
+			PointSize: 10,
+		},
+		Layout: declarative.VBox{Alignment: declarative.AlignHNearVNear},
+		Children: []declarative.Widget{
+			filePickerRow(parent, &csvDlg, &leMappingFile, "Column Mapping (optional)", "Select column mapping", csvMappingFileFilter),
+			declarative.Composite{
+				Layout: declarative.HBox{},
+				Children: []declarative.Widget{
+					declarative.HSpacer{},
+					declarative.PushButton{
+						Text: "OK",
+						OnClicked: func() {
+							m, err := readCSVMapping(leMappingFile.Text())
+							if err != nil {
+								MsgError(csvDlg, err)
+								log.Printf("%+v", err)
+								return
+							}
+
+							fname, err := SaveFilePicker(parent, "Select file to export QSOs", csvFileFilter)
+							if err != nil {
+								MsgError(csvDlg, err)
+								log.Printf("%+v", err)
+								return
+							}
+							if fname == nil {
+								return
+							}
+
+							err = csvlog.WriteToFile(qsos, *fname, m)
+							if err != nil {
+								MsgError(csvDlg, err)
+								log.Printf("%+v", err)
+								return
+							}
+
+							csvDlg.Accept()
+						},
+					},
+					declarative.PushButton{
+						Text: "Cancel",
+						OnClicked: func() {
+							csvDlg.Cancel()
+						},
+					},
+				},
+			},
+		},
+	}.Create(parent)
+	if err != nil {
+		MsgError(parent, err)
+		log.Printf("%+v", err)
+		return err
+	}
+
+	// start message loop
+	csvDlg.Run()
+
+	return nil
+}
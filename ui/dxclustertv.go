@@ -34,8 +34,10 @@ func NewDXClusterModel() *DXClusterModel {
 	m.ResetRows()
 
 	// register event handler for any spot changes
-	spot.Attach(func() {
-		dxclustermodel.ResetRows()
+	spot.Attach(func(e spot.SpotEvent) {
+		synchronize(func() {
+			dxclustermodel.ResetRows()
+		})
 	})
 
 	return m
@@ -71,6 +73,9 @@ func (m *DXClusterModel) Value(row, col int) interface{} {
 
 	case 6:
 		return item.Comments
+
+	case 7:
+		return item.Country
 	}
 
 	return ""
@@ -213,6 +218,7 @@ func dxClusterTableView() declarative.TableView {
 			{Title: "Frequency", Alignment: declarative.AlignFar},
 			{Title: "Spotter"},
 			{Title: "Comments", Width: 250},
+			{Title: "Country"},
 			{Title: ""},
 		},
 		Model: dxclustermodel,
@@ -226,7 +232,7 @@ func dxClusterTableView() declarative.TableView {
 					Band: dxclustermodel.items[idx].Band,
 					Call: dxclustermodel.items[idx].Call,
 					Date: n.Format("2006-01-02"),
-					Time: n.Format("15:04"),
+					Time: n.Format("15:04:05"),
 				}
 
 				// refresh
@@ -1,7 +1,10 @@
 package ui
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -29,6 +32,9 @@ var (
 	qsomodel       *QSOModel
 	selectedQSO    = &qso.QSO{}
 	bndSelectedQSO *walk.DataBinder
+
+	// where qsos are found & changed
+	store qso.ServiceStore
 )
 
 func init() {
@@ -114,10 +120,21 @@ func (m *ModeListModel) Value(index int) interface{} {
 	return m.items[index]
 }
 
-// gologWindow creates the main window and begins processing of user input
-func GoLogWindow() error {
+// synchronize runs fn on the ui thread
+// events that come before the main window is created are dropped, the models load everything as it's created
+func synchronize(fn func()) {
+	if mainWin == nil {
+		return
+	}
+	mainWin.Synchronize(fn)
+}
+
+// gologWindow creates the main window and begins processing of user input, using s for the qsos
+func GoLogWindow(s qso.ServiceStore) error {
 	var err error
 
+	store = s
+
 	var leDate *walk.LineEdit
 	var leTime *walk.LineEdit
 	var leCall *walk.LineEdit
@@ -125,6 +142,10 @@ func GoLogWindow() error {
 	var cbMode *walk.ComboBox
 	var leRSTRcvd *walk.LineEdit
 	var leRSTSent *walk.LineEdit
+	var neFreq *walk.NumberEdit
+	var neFreqRx *walk.NumberEdit
+
+	var leQuery *walk.LineEdit
 
 	var pbQRZ *walk.PushButton
 	var pbCurrentTime *walk.PushButton
@@ -150,6 +171,17 @@ func GoLogWindow() error {
 			AssignTo:       &bndSelectedQSO,
 			DataSource:     selectedQSO,
 			ErrorPresenter: declarative.ToolTipErrorPresenter{},
+			OnReset: func() {
+				// frequencies are entered in kHz but kept in Hz, so aren't bound
+				err := neFreq.SetValue(float64(selectedQSO.Frequency) / 1000)
+				if err != nil {
+					log.Printf("%+v", err)
+				}
+				err = neFreqRx.SetValue(float64(selectedQSO.FrequencyRx) / 1000)
+				if err != nil {
+					log.Printf("%+v", err)
+				}
+			},
 		},
 		MenuItems: []declarative.MenuItem{
 			declarative.Menu{
@@ -174,6 +206,31 @@ func GoLogWindow() error {
 					},
 				},
 			},
+			declarative.Menu{
+				Text: "&Edit",
+				Items: []declarative.MenuItem{
+					declarative.Action{
+						Text: "&Undo",
+						OnTriggered: func() {
+							err := undoManual(true)
+							if err != nil {
+								MsgError(mainWin, err)
+								log.Printf("%+v", err)
+							}
+						},
+					},
+					declarative.Action{
+						Text: "&Redo",
+						OnTriggered: func() {
+							err := undoManual(false)
+							if err != nil {
+								MsgError(mainWin, err)
+								log.Printf("%+v", err)
+							}
+						},
+					},
+				},
+			},
 			declarative.Menu{
 				Text: "&ADIF",
 				Items: []declarative.MenuItem{
@@ -204,6 +261,100 @@ func GoLogWindow() error {
 					},
 				},
 			},
+			declarative.Menu{
+				Text: "C&SV",
+				Items: []declarative.MenuItem{
+					declarative.Action{
+						Text: "&Import...",
+						OnTriggered: func() {
+							err := importCSV(mainWin)
+							if err != nil {
+								MsgError(mainWin, err)
+								log.Printf("%+v", err)
+								return
+							}
+
+							qsomodel.ResetRows()
+						},
+					},
+					declarative.Action{
+						Text: "&Export...",
+						OnTriggered: func() {
+							err := exportCSV(mainWin, qsomodel.QSOs())
+							if err != nil {
+								MsgError(mainWin, err)
+								log.Printf("%+v", err)
+								return
+							}
+						},
+					},
+					declarative.Action{
+						Text: "&Export All...",
+						OnTriggered: func() {
+							qs, err := store.All(context.Background())
+							if err != nil {
+								MsgError(mainWin, err)
+								log.Printf("%+v", err)
+								return
+							}
+
+							err = exportCSV(mainWin, qs)
+							if err != nil {
+								MsgError(mainWin, err)
+								log.Printf("%+v", err)
+								return
+							}
+						},
+					},
+				},
+			},
+			declarative.Menu{
+				Text: "&Contest",
+				Items: []declarative.MenuItem{
+					declarative.Action{
+						Text: "&Import Cabrillo...",
+						OnTriggered: func() {
+							err := importCabrillo(mainWin)
+							if err != nil {
+								MsgError(mainWin, err)
+								log.Printf("%+v", err)
+								return
+							}
+
+							qsomodel.ResetRows()
+						},
+					},
+					declarative.Action{
+						Text: "Export &Cabrillo...",
+						OnTriggered: func() {
+							err := exportCabrillo(mainWin)
+							if err != nil {
+								MsgError(mainWin, err)
+								log.Printf("%+v", err)
+								return
+							}
+						},
+					},
+				},
+			},
+			declarative.Menu{
+				Text: "&Logbook",
+				Items: []declarative.MenuItem{
+					declarative.Action{
+						Text: "Sync with &QRZ.com...",
+						OnTriggered: func() {
+							err := syncQRZ(mainWin)
+							if err != nil {
+								MsgError(mainWin, err)
+								log.Printf("%+v", err)
+								return
+							}
+
+							qsomodel.ResetRows()
+						},
+					},
+				},
+			},
 		},
 		Children: []declarative.Widget{
 			declarative.Composite{
@@ -283,6 +434,11 @@ func GoLogWindow() error {
 										},
 										OnEditingFinished: func() {
 											t := strings.TrimSpace(leTime.Text())
+
+											// seconds are optional when entering by hand
+											if ht, err := time.Parse("15:04", t); err == nil {
+												t = ht.Format("15:04:05")
+											}
 											selectedQSO.Time = t
 											err := leTime.SetText(t)
 											if err != nil {
@@ -308,7 +464,7 @@ func GoLogWindow() error {
 										},
 										OnClicked: func() {
 											n := time.Now().UTC()
-											selectedQSO.Time = n.Format("15:04")
+											selectedQSO.Time = n.Format("15:04:05")
 
 											// refresh
 											err := bndSelectedQSO.Reset()
@@ -377,6 +533,39 @@ func GoLogWindow() error {
 							},
 						},
 					},
+					declarative.Composite{
+						Layout: declarative.VBox{},
+						Children: []declarative.Widget{
+							declarative.Label{
+								Text: "Freq (kHz)",
+							},
+							declarative.NumberEdit{
+								AssignTo: &neFreq,
+								Decimals: 3,
+								MinSize: declarative.Size{
+									Width: 90,
+								},
+								OnValueChanged: func() {
+									khz := neFreq.Value()
+									selectedQSO.Frequency = int64(math.Round(khz * 1000))
+
+									// band follows the frequency
+									b := config.LookupBand(int(khz))
+									if b != "" && b != selectedQSO.Band {
+										for i := 0; i < bands.ItemCount(); i++ {
+											if bands.Value(i).(string) == b {
+												err := cbBand.SetCurrentIndex(i)
+												if err != nil {
+													log.Printf("%+v", err)
+												}
+												break
+											}
+										}
+									}
+								},
+							},
+						},
+					},
 					declarative.Composite{
 						Layout: declarative.VBox{},
 						Children: []declarative.Widget{
@@ -469,6 +658,39 @@ func GoLogWindow() error {
 							},
 						},
 					},
+					declarative.Composite{
+						Layout: declarative.VBox{},
+						Children: []declarative.Widget{
+							declarative.Label{
+								Text: "RX Freq (kHz)",
+							},
+							declarative.NumberEdit{
+								AssignTo: &neFreqRx,
+								Decimals: 3,
+								MinSize: declarative.Size{
+									Width: 90,
+								},
+								OnValueChanged: func() {
+									selectedQSO.FrequencyRx = int64(math.Round(neFreqRx.Value() * 1000))
+								},
+							},
+						},
+					},
+					declarative.Composite{
+						Layout: declarative.VBox{},
+						Children: []declarative.Widget{
+							declarative.Label{
+								Text: "Power (W)",
+							},
+							declarative.NumberEdit{
+								Value:    declarative.Bind("TxPower"),
+								Decimals: 0,
+								MinSize: declarative.Size{
+									Width: 60,
+								},
+							},
+						},
+					},
 				},
 			},
 			declarative.Composite{
@@ -487,7 +709,7 @@ func GoLogWindow() error {
 							n := time.Now().UTC()
 							*selectedQSO = qso.QSO{
 								Date: n.Format("2006-01-02"),
-								Time: n.Format("15:04"),
+								Time: n.Format("15:04:05"),
 							}
 
 							// refresh
@@ -509,10 +731,18 @@ func GoLogWindow() error {
 							Width: 50,
 						},
 						OnClicked: func() {
+							// power is only bound, so needs the form written to the qso
+							err := bndSelectedQSO.Submit()
+							if err != nil {
+								MsgError(mainWin, err)
+								log.Printf("%+v", err)
+								return
+							}
+
 							// log under current station callsign
 							selectedQSO.StationCallsign = config.Station.Callsign
 
-							err := selectedQSO.Add()
+							err = store.Add(qso.WithSource(context.Background(), qso.SourceManual), selectedQSO)
 							if err != nil {
 								MsgError(mainWin, err)
 								log.Printf("%+v", err)
@@ -541,7 +771,15 @@ func GoLogWindow() error {
 							Width: 50,
 						},
 						OnClicked: func() {
-							err := selectedQSO.UpdateOnlyQSO()
+							// power is only bound, so needs the form written to the qso
+							err := bndSelectedQSO.Submit()
+							if err != nil {
+								MsgError(mainWin, err)
+								log.Printf("%+v", err)
+								return
+							}
+
+							err = store.Update(qso.WithSource(context.Background(), qso.SourceManual), selectedQSO)
 							if err != nil {
 								MsgError(mainWin, err)
 								log.Printf("%+v", err)
@@ -570,7 +808,7 @@ func GoLogWindow() error {
 							Width: 50,
 						},
 						OnClicked: func() {
-							err := selectedQSO.Delete()
+							err := store.Delete(qso.WithSource(context.Background(), qso.SourceManual), selectedQSO)
 							if err != nil {
 								MsgError(mainWin, err)
 								log.Printf("%+v", err)
@@ -599,6 +837,16 @@ func GoLogWindow() error {
 							Width: 50,
 						},
 						OnClicked: func() {
+							// a query takes the place of the qso fields
+							if strings.TrimSpace(leQuery.Text()) != "" {
+								err := qsomodel.Query(leQuery.Text())
+								if err != nil {
+									MsgError(mainWin, err)
+									log.Printf("%+v", err)
+								}
+								return
+							}
+
 							qsomodel.Search(
 								leDate.Text(),
 								leTime.Text(),
@@ -644,9 +892,31 @@ func GoLogWindow() error {
 								return
 							}
 
+							err = leQuery.SetText("")
+							if err != nil {
+								MsgError(mainWin, err)
+								log.Printf("%+v", err)
+								return
+							}
+
 							qsomodel.ClearSearch()
 						},
 					},
+					declarative.LineEdit{
+						AssignTo:    &leQuery,
+						ToolTipText: "search query, e.g. band:20m,40m mode:FT8 date>=2024-01-01 !lotw:confirmed",
+						OnKeyPress: func(key walk.Key) {
+							if key != walk.KeyReturn || strings.TrimSpace(leQuery.Text()) == "" {
+								return
+							}
+
+							err := qsomodel.Query(leQuery.Text())
+							if err != nil {
+								MsgError(mainWin, err)
+								log.Printf("%+v", err)
+							}
+						},
+					},
 				},
 			},
 			qsoTableView(),
@@ -725,9 +995,12 @@ func updateConfig() {
 		return
 	}
 
+	// database was reopened
+	store = qso.NewSQLiteStore(db.QSODb)
+
 	// restart background tasks
 	go func() {
-		tasks.Start()
+		tasks.Start(store)
 	}()
 
 	// reload so new changes are in effect
@@ -740,3 +1013,34 @@ func updateConfig() {
 		return
 	}
 }
+
+// undoManual undoes (or redoes) the last change made in the ui
+func undoManual(undo bool) error {
+	u, ok := store.(qso.UndoStore)
+	if !ok {
+		return fmt.Errorf("undo isn't supported by this log")
+	}
+
+	ctx := qso.WithSource(context.Background(), qso.SourceManual)
+
+	var entries []qso.AuditEntry
+	var err error
+	if undo {
+		entries, err = u.Undo(ctx, 1)
+	} else {
+		entries, err = u.Redo(ctx, 1)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		if undo {
+			MsgInformation(mainWin, "There is nothing to undo")
+		} else {
+			MsgInformation(mainWin, "There is nothing to redo")
+		}
+	}
+
+	return nil
+}
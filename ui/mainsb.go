@@ -16,6 +16,10 @@ var (
 	icLoTW        *walk.ImageView
 	icQRZ         *walk.ImageView
 	icClubLog     *walk.ImageView
+	icEqsl        *walk.ImageView
+	icLoTWConfirm *walk.ImageView
+	icQRZSync     *walk.ImageView
+	icEqslInbox   *walk.ImageView
 	icHamAlert    *walk.ImageView
 
 	imgOK         walk.Image
@@ -67,6 +71,38 @@ func updateStatuses(statuses []tasks.GoLogTaskStatus) {
 		}
 	}
 
+	if icEqsl != nil {
+		err := icEqsl.SetImage(statusImage(statuses[tasks.TaskQSLEqsl]))
+		if err != nil {
+			log.Printf("%+v", err)
+			return
+		}
+	}
+
+	if icLoTWConfirm != nil {
+		err := icLoTWConfirm.SetImage(statusImage(statuses[tasks.TaskLoTWConfirmations]))
+		if err != nil {
+			log.Printf("%+v", err)
+			return
+		}
+	}
+
+	if icQRZSync != nil {
+		err := icQRZSync.SetImage(statusImage(statuses[tasks.TaskQRZSync]))
+		if err != nil {
+			log.Printf("%+v", err)
+			return
+		}
+	}
+
+	if icEqslInbox != nil {
+		err := icEqslInbox.SetImage(statusImage(statuses[tasks.TaskEqslInbox]))
+		if err != nil {
+			log.Printf("%+v", err)
+			return
+		}
+	}
+
 	if icHamAlert != nil {
 		err := icHamAlert.SetImage(statusImage(statuses[tasks.TaskHamAlert]))
 		if err != nil {
@@ -117,6 +153,26 @@ func mainStatusBar() declarative.Composite {
 				AssignTo:    &icClubLog,
 				ToolTipText: "Club Log",
 			},
+			declarative.ImageView{
+				Image:       imgNotRunning,
+				AssignTo:    &icEqsl,
+				ToolTipText: "eQSL",
+			},
+			declarative.ImageView{
+				Image:       imgNotRunning,
+				AssignTo:    &icLoTWConfirm,
+				ToolTipText: "LoTW Confirmations",
+			},
+			declarative.ImageView{
+				Image:       imgNotRunning,
+				AssignTo:    &icQRZSync,
+				ToolTipText: "QRZ Sync",
+			},
+			declarative.ImageView{
+				Image:       imgNotRunning,
+				AssignTo:    &icEqslInbox,
+				ToolTipText: "eQSL Inbox",
+			},
 			declarative.ImageView{
 				Image:       imgNotRunning,
 				AssignTo:    &icHamAlert,
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bbathe/golog/config"
+	"github.com/bbathe/golog/models/qso"
+	"github.com/bbathe/golog/tasks"
+	"github.com/lxn/walk"
+)
+
+// maximum number of missing QSOs listed in the sync summary
+const maxSummaryMissing = 10
+
+// missingSummary lists the first missing QSOs
+func missingSummary(title string, qsos []qso.QSO) string {
+	if len(qsos) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\n\n%s:", title)
+	for i, q := range qsos {
+		if i == maxSummaryMissing {
+			fmt.Fprintf(&sb, "\n  and %d more", len(qsos)-maxSummaryMissing)
+			break
+		}
+		fmt.Fprintf(&sb, "\n  %s %s %s %s %s", q.Date, q.Time, q.Call, q.Band, q.Mode)
+	}
+
+	return sb.String()
+}
+
+// syncQRZ compares the log with the QRZ.com logbook and offers to import the QSOs only on QRZ.com
+func syncQRZ(parent walk.Form) error {
+	err := config.LogbookServices.QRZ.Validate()
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	result, err := tasks.SyncQRZ(store, false)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
+	}
+
+	MsgInformation(parent, result.String()+
+		missingSummary("Missing locally", result.MissingLocal)+
+		missingSummary("Missing on QRZ.com", result.MissingRemote))
+
+	if len(result.MissingLocal) > 0 {
+		msg := fmt.Sprintf("Import the %d QSOs that are only on QRZ.com?", len(result.MissingLocal))
+		if walk.MsgBox(parent, appName, msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) == walk.DlgCmdYes {
+			n, err := tasks.ImportQRZQSOs(store, result.MissingLocal)
+			if err != nil {
+				log.Printf("%+v", err)
+				return err
+			}
+
+			MsgInformation(parent, fmt.Sprintf("%d QSOs imported", n))
+		}
+	}
+
+	return nil
+}
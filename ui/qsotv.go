@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"log"
 	"sort"
 	"strings"
@@ -17,10 +18,10 @@ import (
 type QSOModel struct {
 	walk.TableModelBase
 	walk.SorterBase
-	sortColumn     int
-	sortOrder      walk.SortOrder
-	searchCriteria *qso.QSO
-	items          []*qso.QSO
+	sortColumn int
+	sortOrder  walk.SortOrder
+	query      *qso.Query
+	items      []*qso.QSO
 }
 
 func NewQSOModel() *QSOModel {
@@ -30,8 +31,10 @@ func NewQSOModel() *QSOModel {
 	m.ResetRows()
 
 	// register event handler for any qso changes
-	qso.Attach(func() {
-		qsomodel.ResetRows()
+	qso.Attach(func(e qso.QSOEvent) {
+		synchronize(func() {
+			qsomodel.QSOsChanged(e)
+		})
 	})
 
 	return m
@@ -69,37 +72,35 @@ func (m *QSOModel) Value(row, col int) interface{} {
 		return item.RSTSent
 
 	case 7:
-		if item.QSLLotw == qso.Sent {
-			return "\u2713"
-		} else {
-			return ""
-		}
+		return qslMark(item.QSLLotw, item.QSLLotwRcvd)
 
 	case 8:
-		if item.QSLQrz == qso.Sent {
-			return "\u2713"
-		} else {
-			return ""
-		}
+		return qslMark(item.QSLQrz, item.QSLQrzRcvd)
 
 	case 9:
-		if item.QSLClublog == qso.Sent {
-			return "\u2713"
-		} else {
-			return ""
-		}
+		return qslMark(item.QSLClublog, item.QSLClublogRcvd)
 
 	case 10:
-		if item.QSLCard == qso.Sent {
-			return "\u2713"
-		} else {
-			return ""
-		}
+		return qslMark(item.QSLEqsl, item.QSLEqslRcvd)
+
+	case 11:
+		return qslMark(item.QSLCard, item.QSLCardRcvd)
 	}
 
 	return ""
 }
 
+// qslMark returns what to show for a qsl, a check when sent and two when confirmed
+func qslMark(sent qso.QSLSent, rcvd qso.QSLRcvd) string {
+	if rcvd.Confirmed() {
+		return "\u2713\u2713"
+	}
+	if sent == qso.Sent {
+		return "\u2713"
+	}
+	return ""
+}
+
 // Sort is called by the TableView to sort the model
 func (m *QSOModel) Sort(col int, order walk.SortOrder) error {
 	m.sortColumn, m.sortOrder = col, order
@@ -142,16 +143,19 @@ func (m *QSOModel) Sort(col int, order walk.SortOrder) error {
 			return c(strings.Compare(a.RSTSent, b.RSTSent) < 0)
 
 		case 7:
-			return c(a.QSLLotw > b.QSLLotw)
+			return c(qslMark(a.QSLLotw, a.QSLLotwRcvd) > qslMark(b.QSLLotw, b.QSLLotwRcvd))
 
 		case 8:
-			return c(a.QSLQrz > b.QSLQrz)
+			return c(qslMark(a.QSLQrz, a.QSLQrzRcvd) > qslMark(b.QSLQrz, b.QSLQrzRcvd))
 
 		case 9:
-			return c(a.QSLClublog > b.QSLClublog)
+			return c(qslMark(a.QSLClublog, a.QSLClublogRcvd) > qslMark(b.QSLClublog, b.QSLClublogRcvd))
 
 		case 10:
-			return c(a.QSLCard > b.QSLCard)
+			return c(qslMark(a.QSLEqsl, a.QSLEqslRcvd) > qslMark(b.QSLEqsl, b.QSLEqslRcvd))
+
+		case 11:
+			return c(qslMark(a.QSLCard, a.QSLCardRcvd) > qslMark(b.QSLCard, b.QSLCardRcvd))
 		}
 
 		return false
@@ -165,11 +169,15 @@ func (m *QSOModel) ResetRows() {
 	var r []qso.QSO
 	var err error
 
-	// search or not based on if criteria is set in model
-	if m.searchCriteria != nil {
-		r, err = qso.Search(*m.searchCriteria, config.QSOTableview.Limit)
+	// search or not based on if a query is set in model
+	if m.query != nil {
+		q := *m.query
+		if q.Limit == 0 {
+			q.Limit = config.QSOTableview.Limit
+		}
+		r, err = store.Find(context.Background(), q)
 	} else {
-		r, err = qso.History(config.QSOTableview.History, config.QSOTableview.Limit)
+		r, err = store.History(context.Background(), config.QSOTableview.History, config.QSOTableview.Limit)
 	}
 	if err != nil {
 		log.Printf("%+v", err)
@@ -194,9 +202,30 @@ func (m *QSOModel) ResetRows() {
 	}
 }
 
+// QSOsChanged refreshes the model after the qsos in e changed
+// qsl changes are made in place when they can't change which qsos are shown, anything else reloads
+func (m *QSOModel) QSOsChanged(e qso.QSOEvent) {
+	if e.Type != qso.QSLChanged || m.query != nil {
+		m.ResetRows()
+		return
+	}
+
+	after := make(map[int64]qso.QSO, len(e.After))
+	for _, q := range e.After {
+		after[q.ID] = q
+	}
+
+	for i, item := range m.items {
+		if q, ok := after[item.ID]; ok {
+			*item = q
+			m.PublishRowChanged(i)
+		}
+	}
+}
+
 // Search establishes the selection criteria in the model
 func (m *QSOModel) Search(date, time, call, band, mode, rstrcvd, rstsent string) {
-	m.searchCriteria = &qso.QSO{
+	q := qso.CriteriaQuery(qso.QSO{
 		Date:    strings.TrimSpace(date),
 		Time:    strings.TrimSpace(time),
 		Call:    strings.TrimSpace(call),
@@ -204,22 +233,46 @@ func (m *QSOModel) Search(date, time, call, band, mode, rstrcvd, rstsent string)
 		Mode:    strings.TrimSpace(mode),
 		RSTRcvd: strings.TrimSpace(rstrcvd),
 		RSTSent: strings.TrimSpace(rstsent),
+	}, 0)
+	m.query = &q
+
+	m.ResetRows()
+}
+
+// Query establishes the selection criteria in the model from the textual query syntax
+func (m *QSOModel) Query(text string) error {
+	q, err := qso.ParseQuery(text)
+	if err != nil {
+		log.Printf("%+v", err)
+		return err
 	}
+	m.query = &q
 
 	m.ResetRows()
+	return nil
 }
 
 // ClearSearch clears the selection criteria in the model
 func (m *QSOModel) ClearSearch() {
-	m.searchCriteria = nil
+	m.query = nil
 
 	m.ResetRows()
 }
 
+// QSOs returns the qsos in the model
+func (m *QSOModel) QSOs() []qso.QSO {
+	qs := make([]qso.QSO, 0, len(m.items))
+	for _, item := range m.items {
+		qs = append(qs, *item)
+	}
+
+	return qs
+}
+
 // Export generates an adif with the items in the model
 func (m *QSOModel) Export() {
 	// ask where to export to
-	fname, err := SaveFilePicker(mainWin, "Select file to export QSOs", "ADIF Files (*.adi;*.adif)|*.adi;*.adif|All Files (*.*)|*.*")
+	fname, err := SaveFilePicker(mainWin, "Select file to export QSOs", adifFileFilter)
 	if err != nil {
 		MsgError(nil, err)
 		log.Printf("%+v", err)
@@ -227,14 +280,8 @@ func (m *QSOModel) Export() {
 	}
 
 	if fname != nil {
-		// get qsos ready for WriteToFile
-		qs := make([]qso.QSO, 0, len(m.items))
-		for _, item := range m.items {
-			qs = append(qs, *item)
-		}
-
 		// write to file
-		err := adif.WriteToFile(qs, *fname)
+		err := adif.WriteToFile(store, m.QSOs(), *fname, adif.WriteOptions{})
 		if err != nil {
 			log.Printf("%+v", err)
 			MsgError(nil, err)
@@ -310,7 +357,25 @@ func qsoTableView() declarative.TableView {
 						if qsomodel.items[idx].QSLCard == qso.Sent {
 							sent = qso.NotSent
 						}
-						err := qso.UpdateQSL([]qso.QSO{*qsomodel.items[idx]}, qso.QSLCard, sent)
+						err := store.UpdateQSL(qso.WithSource(context.Background(), qso.SourceManual), []qso.QSO{*qsomodel.items[idx]}, qso.QSLCard, sent)
+						if err != nil {
+							MsgError(mainWin, err)
+							log.Printf("%+v", err)
+							return
+						}
+					}
+				},
+			},
+			declarative.Action{
+				Text: "QSL card received",
+				OnTriggered: func() {
+					idx := tv.CurrentIndex()
+					if idx >= 0 {
+						rcvd := qso.Received
+						if qsomodel.items[idx].QSLCardRcvd.Confirmed() {
+							rcvd = qso.NotReceived
+						}
+						err := store.UpdateQSLReceived(qso.WithSource(context.Background(), qso.SourceManual), []qso.QSO{*qsomodel.items[idx]}, qso.QSLCard, rcvd, "")
 						if err != nil {
 							MsgError(mainWin, err)
 							log.Printf("%+v", err)
@@ -345,6 +410,7 @@ func qsoTableView() declarative.TableView {
 			{Title: "QSL LoTW", Width: 85},
 			{Title: "QSL QRZ", Width: 85},
 			{Title: "QSL Club Log", Width: 125},
+			{Title: "QSL eQSL", Width: 85},
 			{Title: "QSL Card", Width: 85},
 			{Title: ""},
 		},
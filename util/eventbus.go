@@ -0,0 +1,87 @@
+package util
+
+import (
+	"sync"
+)
+
+// how many events can be waiting for a subscriber before publishing waits for it
+const eventBufferSize = 64
+
+// subscriber receives events on its own goroutine until done is closed
+type subscriber[T any] struct {
+	events chan T
+	done   chan struct{}
+}
+
+// EventBus delivers events of type T to the attached handlers asynchronously
+// each handler gets every event in the order they were published, on a goroutine of its own
+// the zero value is ready to use
+type EventBus[T any] struct {
+	mutex       sync.Mutex
+	subscribers map[int]*subscriber[T]
+	lastHandle  int
+}
+
+// Attach starts delivering events to handler, returns the handle to detach it with
+func (b *EventBus[T]) Attach(handler func(T)) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.subscribers == nil {
+		b.subscribers = map[int]*subscriber[T]{}
+	}
+
+	s := &subscriber[T]{
+		events: make(chan T, eventBufferSize),
+		done:   make(chan struct{}),
+	}
+
+	b.lastHandle++
+	b.subscribers[b.lastHandle] = s
+
+	go func() {
+		for {
+			select {
+			case e := <-s.events:
+				handler(e)
+			case <-s.done:
+				return
+			}
+		}
+	}()
+
+	return b.lastHandle
+}
+
+// Detach stops delivering events to the handler, events it hasn't been given yet are dropped
+// safe to call from the handler itself
+func (b *EventBus[T]) Detach(handle int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	s, ok := b.subscribers[handle]
+	if !ok {
+		return
+	}
+	delete(b.subscribers, handle)
+	close(s.done)
+}
+
+// Publish queues the event for all the attached handlers
+// only waits when a handler has fallen eventBufferSize events behind
+func (b *EventBus[T]) Publish(e T) {
+	b.mutex.Lock()
+	subscribers := make([]*subscriber[T], 0, len(b.subscribers))
+	for _, s := range b.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	b.mutex.Unlock()
+
+	for _, s := range subscribers {
+		select {
+		case s.events <- e:
+		case <-s.done:
+			// detached while we were waiting
+		}
+	}
+}